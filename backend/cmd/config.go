@@ -0,0 +1,287 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelbwah/huffmin/backend/internal/routes"
+)
+
+const (
+	envAddr        = "HUFFMIN_ADDR"
+	envCORSOrigins = "HUFFMIN_CORS_ORIGINS"
+	envCORSMethods = "HUFFMIN_CORS_METHODS"
+	envRateLimit   = "HUFFMIN_RATE_LIMIT"
+	envRateBurst   = "HUFFMIN_RATE_BURST"
+	envPprof       = "HUFFMIN_PPROF"
+	envPprofAddr   = "HUFFMIN_PPROF_ADDR"
+	envExtension   = "HUFFMIN_EXTENSION"
+	envConcurrency = "HUFFMIN_MAX_CONCURRENT"
+	envQueueWait   = "HUFFMIN_QUEUE_TIMEOUT"
+	envTLSCert     = "HUFFMIN_TLS_CERT"
+	envTLSKey      = "HUFFMIN_TLS_KEY"
+	envProxies     = "HUFFMIN_TRUSTED_PROXIES"
+)
+
+// defaultAddr is the listen address used when HUFFMIN_ADDR is unset.
+const defaultAddr = ":6969"
+
+// defaultPprofAddr is the listen address of the pprof endpoints when
+// HUFFMIN_PPROF_ADDR is unset. It is on loopback, so only the host itself
+// can fetch profiles.
+const defaultPprofAddr = "localhost:6060"
+
+// Default per-client budget for the compression endpoints.
+const (
+	defaultRateLimit = 10
+	defaultRateBurst = 20
+)
+
+// Default server-wide bound on simultaneous compression requests.
+const (
+	defaultConcurrency = 16
+	defaultQueueWait   = 5 * time.Second
+)
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodPost}
+
+// parseList splits a comma-separated value, trimming whitespace and dropping
+// empty entries.
+func parseList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseOrigins returns the allowed CORS origins from a comma-separated value,
+// falling back to "*" when none are given.
+func parseOrigins(value string) []string {
+	origins := parseList(value)
+	if len(origins) == 0 {
+		return []string{"*"}
+	}
+	return origins
+}
+
+// parseMethods returns the allowed CORS methods from a comma-separated value,
+// falling back to GET and POST when none are given.
+func parseMethods(value string) []string {
+	methods := parseList(value)
+	if len(methods) == 0 {
+		return append([]string(nil), defaultCORSMethods...)
+	}
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(m)
+	}
+	return methods
+}
+
+func corsOriginsFromEnv() []string {
+	return parseOrigins(os.Getenv(envCORSOrigins))
+}
+
+func corsMethodsFromEnv() []string {
+	return parseMethods(os.Getenv(envCORSMethods))
+}
+
+func addrFromEnv() string {
+	if addr := strings.TrimSpace(os.Getenv(envAddr)); addr != "" {
+		return addr
+	}
+	return defaultAddr
+}
+
+// rateLimit is the per-client request budget for /compress and /decompress.
+// A perSecond of 0 disables limiting.
+type rateLimit struct {
+	perSecond float64
+	burst     int
+}
+
+// parseRateLimit parses a requests-per-second rate and a burst size, each
+// falling back to its default when empty.
+func parseRateLimit(perSecond, burst string) (rateLimit, error) {
+	limit := rateLimit{perSecond: defaultRateLimit, burst: defaultRateBurst}
+	if v := strings.TrimSpace(perSecond); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return rateLimit{}, fmt.Errorf("%s must be a non-negative number, got %q", envRateLimit, perSecond)
+		}
+		limit.perSecond = f
+	}
+	if v := strings.TrimSpace(burst); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return rateLimit{}, fmt.Errorf("%s must be a positive integer, got %q", envRateBurst, burst)
+		}
+		limit.burst = n
+	}
+	return limit, nil
+}
+
+func rateLimitFromEnv() (rateLimit, error) {
+	return parseRateLimit(os.Getenv(envRateLimit), os.Getenv(envRateBurst))
+}
+
+// parseProxies parses a comma-separated list of proxy addresses or CIDR
+// ranges whose X-Forwarded-For the server believes. A bare address is a
+// range of one.
+func parseProxies(value string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range parseList(value) {
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("%s must list addresses or CIDR ranges, got %q", envProxies, part)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("%s must list addresses or CIDR ranges, got %q", envProxies, part)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func proxiesFromEnv() ([]*net.IPNet, error) {
+	return parseProxies(os.Getenv(envProxies))
+}
+
+// concurrency bounds the /compress and /decompress requests running at once
+// across all clients. A limit of 0 disables the bound.
+type concurrency struct {
+	limit int
+	wait  time.Duration
+}
+
+// parseConcurrency parses a maximum number of simultaneous requests and how
+// long excess requests may queue, each falling back to its default when
+// empty.
+func parseConcurrency(limit, wait string) (concurrency, error) {
+	conc := concurrency{limit: defaultConcurrency, wait: defaultQueueWait}
+	if v := strings.TrimSpace(limit); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return concurrency{}, fmt.Errorf("%s must be a non-negative integer, got %q", envConcurrency, limit)
+		}
+		conc.limit = n
+	}
+	if v := strings.TrimSpace(wait); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return concurrency{}, fmt.Errorf("%s must be a non-negative duration, got %q", envQueueWait, wait)
+		}
+		conc.wait = d
+	}
+	return conc, nil
+}
+
+func concurrencyFromEnv() (concurrency, error) {
+	return parseConcurrency(os.Getenv(envConcurrency), os.Getenv(envQueueWait))
+}
+
+// tlsFiles holds the PEM certificate and key paths the server uses for
+// HTTPS. Both are empty when it serves plain HTTP.
+type tlsFiles struct {
+	cert string
+	key  string
+}
+
+// enabled reports whether the server should serve HTTPS.
+func (t tlsFiles) enabled() bool {
+	return t.cert != ""
+}
+
+// parseTLS parses the certificate and key paths for HTTPS. Setting neither
+// serves plain HTTP; setting only one is an error, since a certificate is
+// useless without its key.
+func parseTLS(cert, key string) (tlsFiles, error) {
+	files := tlsFiles{cert: strings.TrimSpace(cert), key: strings.TrimSpace(key)}
+	if (files.cert == "") != (files.key == "") {
+		return tlsFiles{}, fmt.Errorf("%s and %s must be set together", envTLSCert, envTLSKey)
+	}
+	return files, nil
+}
+
+func tlsFromEnv() (tlsFiles, error) {
+	return parseTLS(os.Getenv(envTLSCert), os.Getenv(envTLSKey))
+}
+
+// parsePprof reports whether the pprof endpoints should be served: when the
+// --pprof flag is set, or when value is a true boolean. They are off by
+// default, since profiles expose internals of the running server.
+func parsePprof(flagSet bool, value string) (bool, error) {
+	if flagSet {
+		return true, nil
+	}
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got %q", envPprof, value)
+	}
+	return enabled, nil
+}
+
+func pprofFromEnv(flagSet bool) (bool, error) {
+	return parsePprof(flagSet, os.Getenv(envPprof))
+}
+
+func pprofAddrFromEnv() string {
+	if addr := strings.TrimSpace(os.Getenv(envPprofAddr)); addr != "" {
+		return addr
+	}
+	return defaultPprofAddr
+}
+
+// parseFlags parses the command-line arguments args, without the program
+// name, and reports whether --pprof was given.
+func parseFlags(args []string) (bool, error) {
+	fs := flag.NewFlagSet("huffmin", flag.ContinueOnError)
+	pprof := fs.Bool("pprof", false, "serve net/http/pprof profiles on "+envPprofAddr+" (also "+envPprof+")")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	return *pprof, nil
+}
+
+// parseExtension parses the extension given to compressed downloads,
+// falling back to routes.DefaultExtension when empty. It must be a dot
+// followed by characters that are safe in a quoted Content-Disposition
+// filename.
+func parseExtension(value string) (string, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return routes.DefaultExtension, nil
+	}
+	if len(v) < 2 || v[0] != '.' || strings.ContainsFunc(v, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"\/;`, r)
+	}) {
+		return "", fmt.Errorf("%s must be a dot followed by printable characters other than \"\\/;, got %q", envExtension, value)
+	}
+	return v, nil
+}
+
+func extensionFromEnv() (string, error) {
+	return parseExtension(os.Getenv(envExtension))
+}
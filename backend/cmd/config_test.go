@@ -0,0 +1,326 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOrigins(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "Unset", value: "", want: []string{"*"}},
+		{name: "Only separators", value: " , ,", want: []string{"*"}},
+		{name: "Single origin", value: "https://huffmin.dev", want: []string{"https://huffmin.dev"}},
+		{
+			name:  "Multiple with whitespace",
+			value: " https://a.example ,https://b.example,, ",
+			want:  []string{"https://a.example", "https://b.example"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseOrigins(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOrigins(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "Unset", value: "", want: []string{"GET", "POST"}},
+		{name: "Lowercase", value: "get, put", want: []string{"GET", "PUT"}},
+		{name: "Single", value: "POST", want: []string{"POST"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMethods(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMethods(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSFromEnv(t *testing.T) {
+	t.Setenv(envCORSOrigins, "https://a.example,https://b.example")
+	t.Setenv(envCORSMethods, "post")
+
+	if got, want := corsOriginsFromEnv(), []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("corsOriginsFromEnv() = %v, want %v", got, want)
+	}
+	if got, want := corsMethodsFromEnv(), []string{"POST"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("corsMethodsFromEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestAddrFromEnv(t *testing.T) {
+	t.Setenv(envAddr, "")
+	if got := addrFromEnv(); got != defaultAddr {
+		t.Errorf("addrFromEnv() = %q, want %q", got, defaultAddr)
+	}
+	t.Setenv(envAddr, " 127.0.0.1:8080 ")
+	if got := addrFromEnv(); got != "127.0.0.1:8080" {
+		t.Errorf("addrFromEnv() = %q, want %q", got, "127.0.0.1:8080")
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond string
+		burst     string
+		want      rateLimit
+		wantErr   bool
+	}{
+		{name: "Unset", want: rateLimit{perSecond: defaultRateLimit, burst: defaultRateBurst}},
+		{name: "Fractional rate", perSecond: " 0.5 ", burst: "3", want: rateLimit{perSecond: 0.5, burst: 3}},
+		{name: "Disabled", perSecond: "0", want: rateLimit{perSecond: 0, burst: defaultRateBurst}},
+		{name: "Negative rate", perSecond: "-1", wantErr: true},
+		{name: "Bad rate", perSecond: "fast", wantErr: true},
+		{name: "Zero burst", burst: "0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRateLimit(tt.perSecond, tt.burst)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseRateLimit(%q, %q): expected error but got nil", tt.perSecond, tt.burst)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRateLimit(%q, %q): unexpected error: %v", tt.perSecond, tt.burst, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseRateLimit(%q, %q) = %+v, want %+v", tt.perSecond, tt.burst, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Unset", value: ""},
+		{name: "Ranges", value: " 10.0.0.0/8, 2001:db8::/32 ", want: []string{"10.0.0.0/8", "2001:db8::/32"}},
+		{name: "Bare addresses", value: "192.0.2.1,2001:db8::1", want: []string{"192.0.2.1/32", "2001:db8::1/128"}},
+		{name: "Bad address", value: "proxy.local", wantErr: true},
+		{name: "Bad range", value: "10.0.0.0/33", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nets, err := parseProxies(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseProxies(%q): expected error but got nil", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProxies(%q): unexpected error: %v", tt.value, err)
+			}
+			var got []string
+			for _, n := range nets {
+				got = append(got, n.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProxies(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   string
+		wait    string
+		want    concurrency
+		wantErr bool
+	}{
+		{name: "Unset", want: concurrency{limit: defaultConcurrency, wait: defaultQueueWait}},
+		{name: "Custom", limit: " 4 ", wait: "250ms", want: concurrency{limit: 4, wait: 250 * time.Millisecond}},
+		{name: "Disabled", limit: "0", want: concurrency{limit: 0, wait: defaultQueueWait}},
+		{name: "No queue", wait: "0s", want: concurrency{limit: defaultConcurrency, wait: 0}},
+		{name: "Negative limit", limit: "-1", wantErr: true},
+		{name: "Bad limit", limit: "lots", wantErr: true},
+		{name: "Bad wait", wait: "5", wantErr: true},
+		{name: "Negative wait", wait: "-1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConcurrency(tt.limit, tt.wait)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseConcurrency(%q, %q): expected error but got nil", tt.limit, tt.wait)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConcurrency(%q, %q): unexpected error: %v", tt.limit, tt.wait, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseConcurrency(%q, %q) = %+v, want %+v", tt.limit, tt.wait, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		cert    string
+		key     string
+		want    tlsFiles
+		wantErr bool
+	}{
+		{name: "Unset", want: tlsFiles{}},
+		{name: "Both", cert: " /etc/huffmin/cert.pem ", key: "/etc/huffmin/key.pem", want: tlsFiles{cert: "/etc/huffmin/cert.pem", key: "/etc/huffmin/key.pem"}},
+		{name: "Blank", cert: " ", key: " ", want: tlsFiles{}},
+		{name: "Cert only", cert: "cert.pem", wantErr: true},
+		{name: "Key only", key: "key.pem", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTLS(tt.cert, tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTLS(%q, %q): expected error but got nil", tt.cert, tt.key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTLS(%q, %q): unexpected error: %v", tt.cert, tt.key, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseTLS(%q, %q) = %+v, want %+v", tt.cert, tt.key, got, tt.want)
+			}
+			if got.enabled() != (tt.want.cert != "") {
+				t.Errorf("parseTLS(%q, %q).enabled() = %v", tt.cert, tt.key, got.enabled())
+			}
+		})
+	}
+}
+
+func TestParsePprof(t *testing.T) {
+	tests := []struct {
+		name    string
+		flagSet bool
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{name: "Off by default", want: false},
+		{name: "Flag", flagSet: true, want: true},
+		{name: "Env true", value: " true ", want: true},
+		{name: "Env 1", value: "1", want: true},
+		{name: "Env false", value: "false", want: false},
+		{name: "Flag overrides env", flagSet: true, value: "false", want: true},
+		{name: "Bad value", value: "yes please", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePprof(tt.flagSet, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePprof(%v, %q): expected error but got nil", tt.flagSet, tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePprof(%v, %q): unexpected error: %v", tt.flagSet, tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parsePprof(%v, %q) = %v, want %v", tt.flagSet, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    bool
+		wantErr bool
+	}{
+		{name: "None", want: false},
+		{name: "Pprof", args: []string{"--pprof"}, want: true},
+		{name: "Pprof single dash", args: []string{"-pprof=true"}, want: true},
+		{name: "Unknown flag", args: []string{"--nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFlags(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFlags(%q): expected error but got nil", tt.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFlags(%q): unexpected error: %v", tt.args, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseFlags(%q) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExtension(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Default", value: "", want: ".huff"},
+		{name: "Custom", value: " .hm ", want: ".hm"},
+		{name: "Several dots", value: ".tar.huff", want: ".tar.huff"},
+		{name: "No dot", value: "huff", wantErr: true},
+		{name: "Dot only", value: ".", wantErr: true},
+		{name: "Quote", value: `.hu"ff`, wantErr: true},
+		{name: "Slash", value: ".a/b", wantErr: true},
+		{name: "Space", value: ".a b", wantErr: true},
+		{name: "Non-ASCII", value: ".hüff", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExtension(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseExtension(%q): expected error but got nil", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseExtension(%q): unexpected error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseExtension(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kelbwah/huffmin/backend/internal/huffman"
+)
+
+const usage = `usage: huffmin <compress|decompress> [flags] <in> <out>
+       huffmin validate <in>...
+
+Paths may be "-" to read from stdin or write to stdout. validate checks
+that each input survives a compress, decompress and recompress unchanged.
+
+Flags:
+`
+
+const (
+	modeHuffman = "huffman"
+	modeStore   = "store"
+)
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "huffmin: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run executes the CLI with the given arguments, excluding the program name.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("huffmin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	mode := fs.String("mode", modeHuffman, "codec to compress with: huffman or store (store wraps bytes verbatim); decompress detects it")
+	stats := fs.Bool("stats", false, "print input and output sizes to stderr")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	command := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+	if command == "validate" {
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		return validate(fs.Args(), stdin, stdout)
+	}
+	if command != "compress" && command != "decompress" {
+		fmt.Fprintf(stderr, "unknown command %q\n", command)
+		fs.Usage()
+		return errUsage
+	}
+	if *mode != modeHuffman && *mode != modeStore {
+		fmt.Fprintf(stderr, "unknown mode %q\n", *mode)
+		fs.Usage()
+		return errUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	inPath, outPath := fs.Arg(0), fs.Arg(1)
+
+	// File-to-file runs go straight through the library's atomic file APIs
+	// where one exists; everything else is handled in memory.
+	if inPath != "-" && outPath != "-" && !*stats {
+		if command == "decompress" {
+			return huffman.HuffmanDecompressFile(inPath, outPath)
+		}
+		if *mode == modeHuffman {
+			return huffman.HuffmanCompressFile(inPath, outPath)
+		}
+	}
+
+	input, err := readInput(inPath, stdin)
+	if err != nil {
+		return err
+	}
+	var output []byte
+	switch {
+	case command == "decompress":
+		output, err = huffman.Decompress(input)
+	case *mode == modeStore:
+		output, err = huffman.CompressStore(input)
+	default:
+		output, err = huffman.HuffmanCompressBytes(input)
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(outPath, output, stdout); err != nil {
+		return err
+	}
+
+	if *stats {
+		ratio := 0.0
+		if len(input) > 0 {
+			ratio = float64(len(output)) / float64(len(input))
+		}
+		fmt.Fprintf(stderr, "%s: %d bytes in, %d bytes out, ratio %.3f\n", command, len(input), len(output), ratio)
+	}
+	return nil
+}
+
+// validate runs huffman.ValidateRoundTrip over each path, reporting every
+// result on stdout, and fails if any input does not round-trip.
+func validate(paths []string, stdin io.Reader, stdout io.Writer) error {
+	failed := 0
+	for _, path := range paths {
+		input, err := readInput(path, stdin)
+		if err == nil {
+			err = huffman.ValidateRoundTrip(input)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(stdout, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d inputs failed validation", failed, len(paths))
+	}
+	return nil
+}
+
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func writeOutput(path string, data []byte, stdout io.Writer) error {
+	if path == "-" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
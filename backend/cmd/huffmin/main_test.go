@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFileRoundTrip(t *testing.T) {
+	content := []byte("hello world! hello world! hello world! hello world!")
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "input.txt")
+	huffPath := filepath.Join(dir, "input.txt.huff")
+	outPath := filepath.Join(dir, "output.txt")
+	if err := os.WriteFile(inPath, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	for _, mode := range []string{modeHuffman, modeStore} {
+		t.Run(mode, func(t *testing.T) {
+			var stderr bytes.Buffer
+			if err := run([]string{"compress", "-mode", mode, inPath, huffPath}, nil, nil, &stderr); err != nil {
+				t.Fatalf("compress failed: %v (%s)", err, stderr.String())
+			}
+			if err := run([]string{"decompress", huffPath, outPath}, nil, nil, &stderr); err != nil {
+				t.Fatalf("decompress failed: %v (%s)", err, stderr.String())
+			}
+
+			got, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("round trip mismatch.\nGot: %v\nWant: %v", got, content)
+			}
+		})
+	}
+}
+
+func TestRunStdio(t *testing.T) {
+	content := []byte("aaaaabbbbcccdde")
+
+	var compressed, stderr bytes.Buffer
+	if err := run([]string{"compress", "-stats", "-", "-"}, bytes.NewReader(content), &compressed, &stderr); err != nil {
+		t.Fatalf("compress failed: %v (%s)", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "15 bytes in") {
+		t.Errorf("expected stats on stderr, got %q", stderr.String())
+	}
+
+	var decompressed bytes.Buffer
+	if err := run([]string{"decompress", "-", "-"}, &compressed, &decompressed, &stderr); err != nil {
+		t.Fatalf("decompress failed: %v (%s)", err, stderr.String())
+	}
+	if !bytes.Equal(decompressed.Bytes(), content) {
+		t.Errorf("round trip mismatch.\nGot: %v\nWant: %v", decompressed.Bytes(), content)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(good, []byte("hello world! hello world!"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"validate", good, "-"}, strings.NewReader("aaaaabbbbcccdde"), &stdout, &stderr); err != nil {
+		t.Fatalf("validate failed: %v (%s)", err, stdout.String())
+	}
+	if want := good + ": ok\n-: ok\n"; stdout.String() != want {
+		t.Errorf("expected output %q, got %q", want, stdout.String())
+	}
+
+	stdout.Reset()
+	err := run([]string{"validate", good, empty}, nil, &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 inputs") {
+		t.Errorf("expected one failed input, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), empty+": cannot compress empty file") {
+		t.Errorf("expected the empty input reported, got %q", stdout.String())
+	}
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "No args", args: nil},
+		{name: "Unknown command", args: []string{"squash", "a", "b"}},
+		{name: "Unknown mode", args: []string{"compress", "-mode", "zip", "a", "b"}},
+		{name: "Missing output", args: []string{"compress", "a"}},
+		{name: "Validate without inputs", args: []string{"validate"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			if err := run(tt.args, nil, nil, &stderr); err != errUsage {
+				t.Errorf("expected usage error, got %v", err)
+			}
+			if !strings.Contains(stderr.String(), "usage: huffmin") {
+				t.Errorf("expected usage text on stderr, got %q", stderr.String())
+			}
+		})
+	}
+}
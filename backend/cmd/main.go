@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/kelbwah/huffmin/backend/internal/routes"
 	"github.com/labstack/echo/v4"
@@ -10,23 +16,133 @@ import (
 )
 
 func main() {
+	pprofFlag, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	e := echo.New()
+	proxies, err := proxiesFromEnv()
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+	// Clients are identified by their connection, not by forwarding headers
+	// they could set themselves, so the rate limit cannot be dodged.
+	e.IPExtractor = routes.ClientIP(proxies)
+	e.Use(routes.RequestID())
 	e.Use(echoware.Logger())
 	e.Use(echoware.Recover())
 	e.Use(echoware.CORSWithConfig(echoware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost},
+		AllowOrigins: corsOriginsFromEnv(),
+		AllowMethods: corsMethodsFromEnv(),
+		ExposeHeaders: []string{
+			routes.HeaderHuffminMode,
+			routes.HeaderHuffminOriginalSize,
+			routes.HeaderHuffminSkipped,
+			routes.HeaderHuffminWarning,
+		},
 	}))
 
+	ext, err := extensionFromEnv()
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+	e.Use(routes.Extension(ext))
+
+	metrics := routes.NewMetrics()
+
+	limit, err := rateLimitFromEnv()
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+	// Compress and decompress share one limiter, so a client's budget
+	// covers both.
+	var limited []echo.MiddlewareFunc
+	if limit.perSecond > 0 {
+		limited = append(limited, routes.RateLimit(limit.perSecond, limit.burst))
+	}
+
+	conc, err := concurrencyFromEnv()
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+	// Rate-limited requests are turned away before they take a slot.
+	if conc.limit > 0 {
+		limited = append(limited, routes.ConcurrencyLimit(conc.limit, conc.wait))
+	}
+
+	e.GET("/health", func(c echo.Context) error {
+		return routes.Health(c)
+	})
+
+	e.GET("/version", func(c echo.Context) error {
+		return routes.Version(c)
+	})
+
+	e.GET("/metrics", func(c echo.Context) error {
+		return metrics.Serve(c)
+	})
+
 	e.POST("/compress", func(c echo.Context) error {
 		return routes.CompressFile(c)
+	}, append(limited, routes.Instrument(metrics, "compress"))...)
+
+	e.POST("/compress/batch", func(c echo.Context) error {
+		return routes.CompressBatch(c)
+	})
+
+	e.POST("/decompress/info", func(c echo.Context) error {
+		return routes.DecompressInfo(c)
+	})
+
+	e.POST("/estimate", func(c echo.Context) error {
+		return routes.Estimate(c)
+	})
+
+	e.POST("/analyze", func(c echo.Context) error {
+		return routes.Analyze(c)
+	})
+
+	e.POST("/compare", func(c echo.Context) error {
+		return routes.Compare(c)
+	})
+
+	e.POST("/frequencies", func(c echo.Context) error {
+		return routes.Frequencies(c)
+	})
+
+	e.POST("/tree", func(c echo.Context) error {
+		return routes.Tree(c)
 	})
 
 	e.POST("/decompress", func(c echo.Context) error {
 		return routes.DecompressFile(c)
-	})
+	}, append(limited, routes.Instrument(metrics, "decompress"))...)
 
-	if err := e.Start(":6969"); err != nil {
+	pprof, err := pprofServer(pprofFlag)
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+
+	tls, err := tlsFromEnv()
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if pprof != nil {
+		go func() {
+			if err := serve(ctx, pprof, pprofAddrFromEnv(), tlsFiles{}, shutdownTimeout); err != nil {
+				log.Printf("pprof server error: %v\n", err)
+			}
+		}()
+	}
+	if err := serve(ctx, e, addrFromEnv(), tls, shutdownTimeout); err != nil {
 		log.Fatalf("Server error: %v\n", err)
 	}
 }
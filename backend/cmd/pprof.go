@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/kelbwah/huffmin/backend/internal/routes"
+	"github.com/labstack/echo/v4"
+)
+
+// pprofServer returns a server holding only the pprof endpoints, or nil when
+// neither the --pprof flag, given as flagSet, nor HUFFMIN_PPROF turns them
+// on. It is run on its own listener rather than the public one, since
+// profiles expose the server's internals and carry no authentication.
+func pprofServer(flagSet bool) (*echo.Echo, error) {
+	enabled, err := pprofFromEnv(flagSet)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	e := echo.New()
+	e.HideBanner = true
+	routes.RegisterPprof(e)
+	return e, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPprofServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		enabled bool
+	}{
+		{name: "Absent by default", enabled: false},
+		{name: "Flag", args: []string{"--pprof"}, enabled: true},
+		{name: "Env", env: "true", enabled: true},
+		{name: "Env false", env: "false", enabled: false},
+		{name: "Flag false", args: []string{"--pprof=false"}, enabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envPprof, tt.env)
+			flagSet, err := parseFlags(tt.args)
+			if err != nil {
+				t.Fatalf("parseFlags(%q): unexpected error: %v", tt.args, err)
+			}
+			e, err := pprofServer(flagSet)
+			if err != nil {
+				t.Fatalf("unexpected config error: %v", err)
+			}
+			if !tt.enabled {
+				if e != nil {
+					t.Fatal("expected no pprof server but got one")
+				}
+				return
+			}
+			if e == nil {
+				t.Fatal("expected a pprof server but got nil")
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET /debug/pprof/heap: expected status 200, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestPprofServerBadEnv(t *testing.T) {
+	t.Setenv(envPprof, "yes please")
+	if _, err := pprofServer(false); err == nil {
+		t.Error("expected error for a non-boolean " + envPprof + " but got nil")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish once a
+// shutdown signal arrives.
+const shutdownTimeout = 30 * time.Second
+
+// serve runs e on addr until ctx is cancelled, then stops accepting
+// connections and waits up to timeout for outstanding requests to finish.
+// It serves HTTPS when tls names a certificate and key, plain HTTP
+// otherwise. It returns nil after a clean shutdown.
+func serve(ctx context.Context, e *echo.Echo, addr string, tls tlsFiles, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		if tls.enabled() {
+			errc <- e.StartTLS(addr, tls.cert, tls.key)
+			return
+		}
+		errc <- e.Start(addr)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package huffman
+
+import "fmt"
+
+// adaptiveRescaleTotal is the count total at which an adaptive model halves
+// its counts. Halving keeps codes short, since a total this small bounds the
+// tree depth well under 64 bits, and lets the model follow a drifting
+// distribution instead of averaging over the whole stream.
+const adaptiveRescaleTotal = 1 << 13
+
+// adaptiveModel is the symbol model shared by AdaptiveEncoder and
+// AdaptiveDecoder: a count per byte value, every one starting at 1 so any
+// byte can be coded, and the Huffman tree of those counts.
+type adaptiveModel struct {
+	counts [256]int
+	total  int
+	tb     treeBuilder
+	root   *Node
+	codes  codeTable
+	stale  bool
+}
+
+func newAdaptiveModel() adaptiveModel {
+	m := adaptiveModel{total: 256, stale: true}
+	for i := range m.counts {
+		m.counts[i] = 1
+	}
+	return m
+}
+
+// update counts one more occurrence of b, halving every count (keeping each
+// at least 1) once the total reaches adaptiveRescaleTotal. The tree is
+// rebuilt the next time it is needed.
+// Time Complexity: O(1) amortized, Space Complexity: O(1)
+func (m *adaptiveModel) update(b byte) {
+	m.counts[b]++
+	m.total++
+	if m.total >= adaptiveRescaleTotal {
+		m.total = 0
+		for i, c := range m.counts {
+			m.counts[i] = max(c/2, 1)
+			m.total += m.counts[i]
+		}
+	}
+	m.stale = true
+}
+
+// tree returns the Huffman tree of the current counts, rebuilding it and the
+// code table if an update has happened since the last call.
+// Time Complexity: O(m log m) after an update, O(1) otherwise, Space
+// Complexity: O(m)
+func (m *adaptiveModel) tree() *Node {
+	if m.stale {
+		symbols := make([]byte, 256)
+		for i := range symbols {
+			symbols[i] = byte(i)
+		}
+		m.root = m.tb.build(symbols, &m.counts)
+		m.codes = codeTable{}
+		buildCodeTable(m.root, 0, 0, &m.codes)
+		m.stale = false
+	}
+	return m.root
+}
+
+// frequencies returns a copy of the current counts.
+func (m *adaptiveModel) frequencies() FrequencyTable {
+	t := make(FrequencyTable, len(m.counts))
+	for b, c := range m.counts {
+		t[byte(b)] = c
+	}
+	return t
+}
+
+// AdaptiveEncoder codes bytes one at a time with a model that adapts as it
+// goes, for streams such as network messages whose distribution drifts and
+// which cannot wait for a whole input to build a frequency table. It starts
+// from a count of 1 for every byte value; after each symbol the caller calls
+// Update, and an AdaptiveDecoder fed the same Updates in the same order
+// reproduces the model exactly, so no table is ever transmitted.
+type AdaptiveEncoder struct {
+	model adaptiveModel
+}
+
+// NewAdaptiveEncoder returns an encoder with the initial, uniform model.
+func NewAdaptiveEncoder() *AdaptiveEncoder {
+	return &AdaptiveEncoder{model: newAdaptiveModel()}
+}
+
+// Encode returns the code for b under the current model: its n low bits,
+// most significant first. It does not change the model; call Update to
+// count b.
+// Time Complexity: O(m log m) after an Update, O(1) otherwise, Space
+// Complexity: O(m)
+func (e *AdaptiveEncoder) Encode(b byte) (bits uint64, n int) {
+	e.model.tree()
+	c := e.model.codes[b]
+	return c.bits, int(c.length)
+}
+
+// Update counts one occurrence of b in the model.
+// Time Complexity: O(1) amortized, Space Complexity: O(1)
+func (e *AdaptiveEncoder) Update(b byte) {
+	e.model.update(b)
+}
+
+// Frequencies returns a snapshot of the model's current counts.
+// Time Complexity: O(m), Space Complexity: O(m)
+func (e *AdaptiveEncoder) Frequencies() FrequencyTable {
+	return e.model.frequencies()
+}
+
+// AdaptiveDecoder mirrors an AdaptiveEncoder.
+type AdaptiveDecoder struct {
+	model adaptiveModel
+}
+
+// NewAdaptiveDecoder returns a decoder with the initial, uniform model.
+func NewAdaptiveDecoder() *AdaptiveDecoder {
+	return &AdaptiveDecoder{model: newAdaptiveModel()}
+}
+
+// Decode reads one symbol from the n low bits of bits, most significant
+// first, and returns it with the number of bits its code took. It fails if
+// those bits end inside a code. Like Encode it leaves the model alone; call
+// Update with the decoded symbol before decoding the next.
+// Time Complexity: O(m log m) after an Update, O(L) otherwise for code
+// length L, Space Complexity: O(m)
+func (d *AdaptiveDecoder) Decode(bits uint64, n int) (byte, int, error) {
+	if n < 0 || n > 64 {
+		return 0, 0, fmt.Errorf("invalid bit count %d", n)
+	}
+	node := d.model.tree()
+	used := 0
+	for node.Left != nil {
+		if used == n {
+			return 0, 0, corruptf("%d bits end inside a code", n)
+		}
+		if bits>>(n-1-used)&1 == 0 {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+		used++
+	}
+	return node.Char, used, nil
+}
+
+// Update counts one occurrence of b in the model.
+// Time Complexity: O(1) amortized, Space Complexity: O(1)
+func (d *AdaptiveDecoder) Update(b byte) {
+	d.model.update(b)
+}
+
+// Frequencies returns a snapshot of the model's current counts.
+// Time Complexity: O(m), Space Complexity: O(m)
+func (d *AdaptiveDecoder) Frequencies() FrequencyTable {
+	return d.model.frequencies()
+}
@@ -0,0 +1,113 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAdaptiveSymbolBySymbol(t *testing.T) {
+	// The messages drift from lowercase text to binary-looking bytes.
+	rng := rand.New(rand.NewSource(357))
+	binary := make([]byte, 3000)
+	for i := range binary {
+		binary[i] = byte(rng.Intn(16)) | 0xF0
+	}
+	messages := [][]byte{
+		[]byte(strings.Repeat("hello world! ", 40)),
+		binary,
+		bytes.Repeat([]byte{0x00}, adaptiveRescaleTotal), // past the rescale total
+		[]byte("back to text"),
+	}
+
+	enc := NewAdaptiveEncoder()
+	dec := NewAdaptiveDecoder()
+	for m, msg := range messages {
+		for i, b := range msg {
+			bits, n := enc.Encode(b)
+			if n < 1 || n > 64 {
+				t.Fatalf("message %d, symbol %d: invalid code length %d", m, i, n)
+			}
+			got, used, err := dec.Decode(bits, n)
+			if err != nil {
+				t.Fatalf("message %d, symbol %d: unexpected decode error: %v", m, i, err)
+			}
+			if got != b || used != n {
+				t.Fatalf("message %d, symbol %d: decoded 0x%02x in %d bits, want 0x%02x in %d", m, i, got, used, b, n)
+			}
+			enc.Update(b)
+			dec.Update(got)
+		}
+		if !reflect.DeepEqual(enc.Frequencies(), dec.Frequencies()) {
+			t.Fatalf("message %d: encoder and decoder models diverged", m)
+		}
+	}
+	total := 0
+	for _, c := range enc.Frequencies() {
+		total += c
+	}
+	if total >= adaptiveRescaleTotal {
+		t.Errorf("expected the counts to have been rescaled, total is %d", total)
+	}
+}
+
+func TestAdaptiveConcatenatedStream(t *testing.T) {
+	data := []byte(strings.Repeat("abracadabra ", 200))
+
+	enc := NewAdaptiveEncoder()
+	var stream strings.Builder
+	for _, b := range data {
+		bits, n := enc.Encode(b)
+		for i := n - 1; i >= 0; i-- {
+			stream.WriteByte('0' + byte(bits>>i&1))
+		}
+		enc.Update(b)
+	}
+
+	// The decoder sees the bits without code boundaries, a 64-bit window at
+	// a time.
+	bitString := stream.String()
+	dec := NewAdaptiveDecoder()
+	var out []byte
+	for pos := 0; pos < len(bitString); {
+		n := min(64, len(bitString)-pos)
+		var window uint64
+		for _, c := range bitString[pos : pos+n] {
+			window = window<<1 | uint64(c-'0')
+		}
+		b, used, err := dec.Decode(window, n)
+		if err != nil {
+			t.Fatalf("bit %d: unexpected decode error: %v", pos, err)
+		}
+		dec.Update(b)
+		out = append(out, b)
+		pos += used
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("decoded stream does not match original")
+	}
+
+	// The model adapts: frequent symbols end up with shorter codes.
+	_, nA := enc.Encode('a')
+	_, nZ := enc.Encode('z')
+	if nA >= nZ {
+		t.Errorf("expected 'a' to code shorter than 'z', got %d and %d bits", nA, nZ)
+	}
+	if fresh, _ := NewAdaptiveEncoder().Encode('a'); fresh != 0x61 {
+		t.Errorf("expected the uniform model to give 'a' the 8-bit code 0x61, got 0x%x", fresh)
+	}
+}
+
+func TestAdaptiveDecodeErrors(t *testing.T) {
+	dec := NewAdaptiveDecoder()
+	// Every code is 8 bits under the uniform model.
+	if _, _, err := dec.Decode(0x3, 7); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a cut-off code, got %v", err)
+	}
+	if _, _, err := dec.Decode(0, 65); err == nil {
+		t.Error("expected error for more than 64 bits but got nil")
+	}
+}
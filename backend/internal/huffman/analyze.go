@@ -0,0 +1,85 @@
+package huffman
+
+import "math"
+
+// AnalyzeResult sets the output of HuffmanCompressBytes against the entropy
+// bound, the fewest bits per byte any code over single bytes can average.
+type AnalyzeResult struct {
+	OriginalSize int `json:"originalSize"`
+	// Entropy is the order-0 Shannon entropy of the input in bits per byte.
+	Entropy float64 `json:"entropy"`
+	// MinimumSize is Entropy * OriginalSize / 8, the payload size in bytes
+	// below which no code over single bytes can go.
+	MinimumSize float64 `json:"minimumSize"`
+	// HuffmanSize is the size of the ModeHuffman blob, header included.
+	HuffmanSize int `json:"huffmanSize"`
+	// BitsPerByte is the mean length of the Huffman codes, weighted by
+	// frequency. It is never below Entropy and at most Entropy + 1.
+	BitsPerByte float64 `json:"bitsPerByte"`
+	// Efficiency is Entropy over BitsPerByte: 1 when the payload meets the
+	// bound, and lower the more bits Huffman coding wastes. It is 0 for a
+	// single-symbol input, whose entropy is 0 but whose codes take one bit.
+	Efficiency float64 `json:"efficiency"`
+}
+
+// Entropy returns the order-0 Shannon entropy of data in bits per byte,
+// from 0 for a single repeated byte to 8 for uniformly spread bytes. It is
+// 0 for empty input.
+// Time Complexity: O(n), Space Complexity: O(1)
+func Entropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	return entropyOf(&counts, len(data))
+}
+
+// entropyOf returns the entropy in bits per byte of n bytes with counts.
+// Time Complexity: O(1), Space Complexity: O(1)
+func entropyOf(counts *[256]int, n int) float64 {
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Analyze measures how close HuffmanCompressBytes comes to the entropy
+// bound on data, without encoding the payload.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func Analyze(data []byte) (AnalyzeResult, error) {
+	if len(data) == 0 {
+		return AnalyzeResult{}, ErrEmptyInput
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	freq := make(map[byte]int, 256)
+	for b, c := range counts {
+		if c > 0 {
+			freq[byte(b)] = c
+		}
+	}
+	lengths := make(map[byte]int, len(freq))
+	codeLengths(buildHuffmanTree(freq), 0, lengths)
+	totalBits := 0
+	for b, f := range freq {
+		totalBits += f * lengths[b]
+	}
+
+	entropy := entropyOf(&counts, len(data))
+	bitsPerByte := float64(totalBits) / float64(len(data))
+	return AnalyzeResult{
+		OriginalSize: len(data),
+		Entropy:      entropy,
+		MinimumSize:  entropy * float64(len(data)) / 8,
+		HuffmanSize:  huffmanBlobSize(len(freq), totalBits),
+		BitsPerByte:  bitsPerByte,
+		Efficiency:   entropy / bitsPerByte,
+	}, nil
+}
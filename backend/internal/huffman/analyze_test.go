@@ -0,0 +1,91 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want float64
+	}{
+		{name: "Empty", data: nil, want: 0},
+		{name: "Single symbol", data: []byte("aaaa"), want: 0},
+		{name: "Two even symbols", data: []byte("abab"), want: 1},
+		{name: "Dyadic", data: []byte("aaaabbcd"), want: 1.75},
+		{name: "All bytes once", data: func() []byte {
+			data := make([]byte, 256)
+			for i := range data {
+				data[i] = byte(i)
+			}
+			return data
+		}(), want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Entropy(tt.data); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected entropy %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	rng := rand.New(rand.NewSource(381))
+	random := make([]byte, 1<<16)
+	rng.Read(random)
+	skewed := append(bytes.Repeat([]byte("a"), 900), bytes.Repeat([]byte("bc"), 50)...)
+
+	tests := []struct {
+		name                   string
+		data                   []byte
+		minEntropy, maxEntropy float64
+		minEff, maxEff         float64
+	}{
+		// Uniform bytes sit at the 8-bit bound, which Huffman codes of 8
+		// bits meet almost exactly.
+		{name: "Uniform random", data: random, minEntropy: 7.99, maxEntropy: 8, minEff: 0.99, maxEff: 1},
+		// p(a) = 0.9: the entropy is about 0.57 bits, but no code is
+		// shorter than one bit, so Huffman coding wastes nearly half.
+		{name: "Skewed", data: skewed, minEntropy: 0.56, maxEntropy: 0.58, minEff: 0.5, maxEff: 0.6},
+		{name: "Dyadic", data: bytes.Repeat([]byte("aaaabbcd"), 100), minEntropy: 1.75, maxEntropy: 1.75, minEff: 1, maxEff: 1},
+		{name: "Single symbol", data: []byte("zzzz"), minEntropy: 0, maxEntropy: 0, minEff: 0, maxEff: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Analyze(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected analyze error: %v", err)
+			}
+			if got.OriginalSize != len(tt.data) {
+				t.Errorf("expected original size %d, got %d", len(tt.data), got.OriginalSize)
+			}
+			if got.Entropy < tt.minEntropy-1e-9 || got.Entropy > tt.maxEntropy+1e-9 {
+				t.Errorf("expected entropy in [%v, %v], got %v", tt.minEntropy, tt.maxEntropy, got.Entropy)
+			}
+			if got.Efficiency < tt.minEff-1e-9 || got.Efficiency > tt.maxEff+1e-9 {
+				t.Errorf("expected efficiency in [%v, %v], got %v", tt.minEff, tt.maxEff, got.Efficiency)
+			}
+			if want := got.Entropy * float64(len(tt.data)) / 8; math.Abs(got.MinimumSize-want) > 1e-6 {
+				t.Errorf("expected minimum size %v, got %v", want, got.MinimumSize)
+			}
+			if got.BitsPerByte < got.Entropy-1e-9 || got.BitsPerByte > got.Entropy+1+1e-9 {
+				t.Errorf("expected %v bits per byte within one bit above entropy %v", got.BitsPerByte, got.Entropy)
+			}
+			if want := len(mustCompress(t, tt.data)); got.HuffmanSize != want {
+				t.Errorf("expected huffman size %d, got %d", want, got.HuffmanSize)
+			}
+		})
+	}
+
+	if _, err := Analyze(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package huffman
+
+import (
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// ArchiveMember is one named file in an archive. Mode and ModTime are
+// optional: a zero Mode or ModTime records that the value is unknown, and
+// members of archives written before they existed read back with both zero.
+type ArchiveMember struct {
+	Name    string
+	Data    []byte
+	Mode    fs.FileMode // permission bits only
+	ModTime time.Time
+}
+
+// archiveEntry locates one member blob inside a ModeArchive body.
+type archiveEntry struct {
+	name   string
+	offset uint64
+	length uint64
+	mode   uint32
+	mtime  int64 // Unix nanoseconds, 0 if unknown
+}
+
+// archiveEntryFields is the size of the fields every index entry carries
+// besides the name: u16 name length, u64 offset, u64 length.
+const archiveEntryFields = 2 + 8 + 8
+
+// archiveMetaFields is the size of the metadata that follows the required
+// fields in newer entries: u32 permission bits and i64 mtime.
+const archiveMetaFields = 4 + 8
+
+// maxArchiveName bounds member names, leaving room in the u16 entry size for
+// fields added later.
+const maxArchiveName = 4096
+
+// HuffmanArchive builds a ModeArchive blob holding members in order. The
+// body is laid out as:
+//
+//	members        each member is a complete blob, back to back
+//	u32            number of index entries n
+//	n x entry      u16 entry size, then the u16-prefixed name, the u64
+//	               offset and length of the member within the body, the
+//	               u32 permission bits and the i64 mtime in Unix nanoseconds
+//	u64            offset of the index within the body
+//
+// Readers skip entry bytes they do not know, so new per-member fields can be
+// added to the end of an entry without breaking older archives. Entries
+// written before the permission bits and mtime were added end after the
+// length, and read back with both zero.
+// Time Complexity: O(n + k·m log m) for k members, Space Complexity: O(n)
+func HuffmanArchive(members []ArchiveMember) ([]byte, error) {
+	return appendMembers(nil, nil, members)
+}
+
+// HuffmanArchiveAppend compresses data as a new member called name and adds
+// it to existing, which may be empty to start a new archive. Existing members
+// are copied as-is; only the index is rewritten.
+// Time Complexity: O(n + m log m), Space Complexity: O(n)
+func HuffmanArchiveAppend(existing []byte, name string, data []byte) ([]byte, error) {
+	var members []byte
+	var entries []archiveEntry
+	if len(existing) > 0 {
+		body, err := archiveBody(existing)
+		if err != nil {
+			return nil, err
+		}
+		if entries, members, err = readArchiveIndex(body); err != nil {
+			return nil, err
+		}
+	}
+	return appendMembers(members, entries, []ArchiveMember{{Name: name, Data: data}})
+}
+
+// appendMembers compresses add after the existing member region described
+// by entries and returns the resulting archive blob.
+// Time Complexity: O(n + k·m log m) for k members, Space Complexity: O(n)
+func appendMembers(members []byte, entries []archiveEntry, add []ArchiveMember) ([]byte, error) {
+	names := make(map[string]bool, len(entries)+len(add))
+	for _, e := range entries {
+		names[e.name] = true
+	}
+	body := append([]byte(nil), members...)
+	for _, m := range add {
+		if m.Name == "" || len(m.Name) > maxArchiveName {
+			return nil, fmt.Errorf("invalid archive member name of %d bytes (want 1-%d)", len(m.Name), maxArchiveName)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("archive already contains %q", m.Name)
+		}
+		names[m.Name] = true
+		member, err := compressMember(m.Data)
+		if err != nil {
+			return nil, err
+		}
+		e := archiveEntry{
+			name:   m.Name,
+			offset: uint64(len(body)),
+			length: uint64(len(member)),
+			mode:   uint32(m.Mode.Perm()),
+		}
+		if !m.ModTime.IsZero() {
+			e.mtime = m.ModTime.UnixNano()
+		}
+		entries = append(entries, e)
+		body = append(body, member...)
+	}
+	return wrap(ModeArchive, appendArchiveIndex(body, entries)), nil
+}
+
+// HuffmanArchiveExtract decompresses every member of a ModeArchive blob, in
+// the order they were added. Members must be ModeStore or ModeHuffman blobs,
+// as compressMember writes them. Their combined output is capped at
+// DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(n + k·m log m) for k members, Space Complexity: O(n)
+func HuffmanArchiveExtract(blob []byte) ([]ArchiveMember, error) {
+	body, err := archiveBody(blob)
+	if err != nil {
+		return nil, err
+	}
+	entries, members, err := readArchiveIndex(body)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ArchiveMember, 0, len(entries))
+	remaining := DefaultMaxDecompressedSize
+	for _, e := range entries {
+		member := members[e.offset : e.offset+e.length]
+		if err := checkLeafBlob(member); err != nil {
+			return nil, fmt.Errorf("archive member %q: %w", e.name, err)
+		}
+		data, err := decompress(member, remaining, true)
+		if err != nil {
+			return nil, fmt.Errorf("archive member %q: %w", e.name, err)
+		}
+		remaining -= len(data)
+		m := ArchiveMember{Name: e.name, Data: data, Mode: fs.FileMode(e.mode)}
+		if e.mtime != 0 {
+			m.ModTime = time.Unix(0, e.mtime)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// compressMember encodes one archive member. Empty files are legal members,
+// so they are stored rather than rejected.
+func compressMember(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return wrap(ModeStore, nil), nil
+	}
+	return HuffmanCompressBytes(data)
+}
+
+// archiveBody returns the body of blob after checking it is an archive.
+func archiveBody(blob []byte) ([]byte, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return nil, err
+	}
+	if mode != ModeArchive {
+		return nil, fmt.Errorf("%w: expected %s blob, got %s", ErrWrongMode, ModeArchive, mode)
+	}
+	return body, nil
+}
+
+// appendArchiveIndex appends the index and trailer for entries to body,
+// whose current length is taken as the index offset.
+// Time Complexity: O(k), Space Complexity: O(k)
+func appendArchiveIndex(body []byte, entries []archiveEntry) []byte {
+	indexOffset := uint64(len(body))
+	body = byteOrder.AppendUint32(body, uint32(len(entries)))
+	for _, e := range entries {
+		body = byteOrder.AppendUint16(body, uint16(archiveEntryFields+len(e.name)+archiveMetaFields))
+		body = byteOrder.AppendUint16(body, uint16(len(e.name)))
+		body = append(body, e.name...)
+		body = byteOrder.AppendUint64(body, e.offset)
+		body = byteOrder.AppendUint64(body, e.length)
+		body = byteOrder.AppendUint32(body, e.mode)
+		body = byteOrder.AppendUint64(body, uint64(e.mtime))
+	}
+	return byteOrder.AppendUint64(body, indexOffset)
+}
+
+// readArchiveIndex parses the index of a ModeArchive body, returning its
+// entries and the region holding the member blobs. Members must lie back to
+// back in index order, covering the region, and have distinct names, as
+// appendMembers writes them, so no two entries can share a member.
+// Time Complexity: O(k), Space Complexity: O(k)
+func readArchiveIndex(body []byte) ([]archiveEntry, []byte, error) {
+	if len(body) < 4+8 {
+		return nil, nil, corruptf("archive of %d bytes is too short", len(body))
+	}
+	indexOffset := byteOrder.Uint64(body[len(body)-8:])
+	if indexOffset > uint64(len(body)-8-4) {
+		return nil, nil, corruptf("archive index offset %d out of range", indexOffset)
+	}
+	members := body[:indexOffset]
+	index := body[indexOffset : len(body)-8]
+
+	count := byteOrder.Uint32(index)
+	index = index[4:]
+	var entries []archiveEntry
+	names := make(map[string]bool)
+	var offset uint64
+	for i := uint32(0); i < count; i++ {
+		if len(index) < 2 {
+			return nil, nil, corruptf("archive index truncated at entry %d", i)
+		}
+		size := int(byteOrder.Uint16(index))
+		index = index[2:]
+		if size < archiveEntryFields || size > len(index) {
+			return nil, nil, corruptf("archive index entry %d has invalid size %d", i, size)
+		}
+		entry := index[:size]
+		index = index[size:]
+
+		nameLen := int(byteOrder.Uint16(entry))
+		if archiveEntryFields+nameLen > size {
+			return nil, nil, corruptf("archive index entry %d name overflows entry", i)
+		}
+		e := archiveEntry{
+			name:   string(entry[2 : 2+nameLen]),
+			offset: byteOrder.Uint64(entry[2+nameLen:]),
+			length: byteOrder.Uint64(entry[2+nameLen+8:]),
+		}
+		if meta := entry[archiveEntryFields+nameLen:]; len(meta) >= archiveMetaFields {
+			e.mode = byteOrder.Uint32(meta) & uint32(fs.ModePerm)
+			e.mtime = int64(byteOrder.Uint64(meta[4:]))
+		}
+		if e.offset != offset {
+			return nil, nil, corruptf("archive member %q starts at %d, want %d after the member before it", e.name, e.offset, offset)
+		}
+		if e.length > uint64(len(members))-offset {
+			return nil, nil, corruptf("archive member %q lies outside the archive", e.name)
+		}
+		if names[e.name] {
+			return nil, nil, corruptf("archive lists %q more than once", e.name)
+		}
+		names[e.name] = true
+		entries = append(entries, e)
+		offset += e.length
+	}
+	if len(index) != 0 {
+		return nil, nil, corruptf("archive index has %d trailing bytes", len(index))
+	}
+	if offset != uint64(len(members)) {
+		return nil, nil, corruptf("archive member region has %d bytes no member accounts for", uint64(len(members))-offset)
+	}
+	return entries, members, nil
+}
@@ -0,0 +1,245 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+var archiveFixtures = []ArchiveMember{
+	{Name: "notes.txt", Data: []byte("hello world! hello world! hello world!")},
+	{Name: "data.bin", Data: []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}},
+	{Name: "empty", Data: nil},
+	{Name: "runs.txt", Data: bytes.Repeat([]byte("z"), 1000)},
+}
+
+func TestHuffmanArchiveAppend(t *testing.T) {
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}}
+
+	for _, order := range orders {
+		var archive []byte
+		for i, idx := range order {
+			m := archiveFixtures[idx]
+			next, err := HuffmanArchiveAppend(archive, m.Name, m.Data)
+			if err != nil {
+				t.Fatalf("order %v: unexpected append error: %v", order, err)
+			}
+			// Earlier members are carried over byte for byte.
+			if i > 0 {
+				prev, err := archiveBody(archive)
+				if err != nil {
+					t.Fatalf("order %v: %v", order, err)
+				}
+				_, members, err := readArchiveIndex(prev)
+				if err != nil {
+					t.Fatalf("order %v: %v", order, err)
+				}
+				if !bytes.HasPrefix(next[containerHeaderSize:], members) {
+					t.Errorf("order %v: append rewrote existing members", order)
+				}
+			}
+			archive = next
+		}
+
+		got, err := HuffmanArchiveExtract(archive)
+		if err != nil {
+			t.Fatalf("order %v: unexpected extract error: %v", order, err)
+		}
+		if len(got) != len(order) {
+			t.Fatalf("order %v: expected %d members, got %d", order, len(order), len(got))
+		}
+		for i, idx := range order {
+			want := archiveFixtures[idx]
+			if got[i].Name != want.Name || !bytes.Equal(got[i].Data, want.Data) {
+				t.Errorf("order %v: member %d = %q (%d bytes), want %q (%d bytes)", order, i, got[i].Name, len(got[i].Data), want.Name, len(want.Data))
+			}
+		}
+		if err := HuffmanVerify(archive); err != nil {
+			t.Errorf("order %v: unexpected verify error: %v", order, err)
+		}
+	}
+}
+
+func TestHuffmanArchive(t *testing.T) {
+	archive, err := HuffmanArchive(archiveFixtures)
+	if err != nil {
+		t.Fatalf("unexpected archive error: %v", err)
+	}
+	got, err := HuffmanArchiveExtract(archive)
+	if err != nil {
+		t.Fatalf("unexpected extract error: %v", err)
+	}
+	for i, want := range archiveFixtures {
+		if got[i].Name != want.Name || !bytes.Equal(got[i].Data, want.Data) {
+			t.Errorf("member %d = %q, want %q", i, got[i].Name, want.Name)
+		}
+	}
+
+	empty, err := HuffmanArchive(nil)
+	if err != nil {
+		t.Fatalf("unexpected archive error: %v", err)
+	}
+	if got, err := HuffmanArchiveExtract(empty); err != nil || len(got) != 0 {
+		t.Errorf("expected empty archive to extract to nothing, got %d members, %v", len(got), err)
+	}
+}
+
+func TestHuffmanArchiveErrors(t *testing.T) {
+	archive, err := HuffmanArchive(archiveFixtures[:1])
+	if err != nil {
+		t.Fatalf("unexpected archive error: %v", err)
+	}
+	if _, err := HuffmanArchiveAppend(archive, archiveFixtures[0].Name, []byte("again")); err == nil {
+		t.Error("expected error for duplicate member name but got nil")
+	}
+	if _, err := HuffmanArchiveAppend(archive, "", []byte("x")); err == nil {
+		t.Error("expected error for empty member name but got nil")
+	}
+	if _, err := HuffmanArchiveAppend(archive, strings.Repeat("n", maxArchiveName+1), []byte("x")); err == nil {
+		t.Error("expected error for overlong member name but got nil")
+	}
+
+	plain, err := HuffmanCompressBytes([]byte("abc"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := HuffmanArchiveAppend(plain, "x", []byte("x")); !errors.Is(err, ErrWrongMode) {
+		t.Errorf("expected ErrWrongMode appending to a non-archive blob, got %v", err)
+	}
+	if _, err := Decompress(archive); err == nil {
+		t.Error("expected Decompress to reject an archive but got nil")
+	}
+
+	badOffset := bytes.Clone(archive)
+	badOffset[len(badOffset)-8] ^= 0xFF
+	if _, err := HuffmanArchiveExtract(badOffset); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a bad index offset, got %v", err)
+	}
+}
+
+func TestHuffmanArchiveForgedIndex(t *testing.T) {
+	a := mustCompress(t, []byte("aaaa"))
+	b := mustCompress(t, []byte("bbbb"))
+	region := append(bytes.Clone(a), b...)
+	n := uint64(len(a))
+
+	tests := []struct {
+		name    string
+		members []byte
+		entries []archiveEntry
+	}{
+		{
+			// Two entries claiming the same member would decode it twice.
+			name:    "Overlapping",
+			members: a,
+			entries: []archiveEntry{{name: "x", length: n}, {name: "y", length: n}},
+		},
+		{
+			name:    "Out of order",
+			members: region,
+			entries: []archiveEntry{{name: "x", offset: n, length: n}, {name: "y", length: n}},
+		},
+		{
+			name:    "Gap",
+			members: region,
+			entries: []archiveEntry{{name: "x", offset: n, length: n}},
+		},
+		{
+			name:    "Region not covered",
+			members: region,
+			entries: []archiveEntry{{name: "x", length: n}},
+		},
+		{
+			name:    "Duplicate name",
+			members: region,
+			entries: []archiveEntry{{name: "x", length: n}, {name: "x", offset: n, length: n}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob := wrap(ModeArchive, appendArchiveIndex(bytes.Clone(tt.members), tt.entries))
+			if _, err := HuffmanArchiveExtract(blob); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("HuffmanArchiveExtract: expected ErrCorruptStream, got %v", err)
+			}
+			if _, err := HuffmanArchiveAppend(blob, "z", []byte("z")); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("HuffmanArchiveAppend: expected ErrCorruptStream, got %v", err)
+			}
+		})
+	}
+}
+
+// legacyArchive builds an archive whose index entries end after the member
+// length, as written before entries carried permissions and mtimes.
+func legacyArchive(t *testing.T, members []ArchiveMember) []byte {
+	t.Helper()
+	var body []byte
+	type span struct{ offset, length int }
+	var spans []span
+	for _, m := range members {
+		blob, err := compressMember(m.Data)
+		if err != nil {
+			t.Fatalf("unexpected compress error: %v", err)
+		}
+		spans = append(spans, span{len(body), len(blob)})
+		body = append(body, blob...)
+	}
+	indexOffset := len(body)
+	body = byteOrder.AppendUint32(body, uint32(len(members)))
+	for i, m := range members {
+		body = byteOrder.AppendUint16(body, uint16(archiveEntryFields+len(m.Name)))
+		body = byteOrder.AppendUint16(body, uint16(len(m.Name)))
+		body = append(body, m.Name...)
+		body = byteOrder.AppendUint64(body, uint64(spans[i].offset))
+		body = byteOrder.AppendUint64(body, uint64(spans[i].length))
+	}
+	return wrap(ModeArchive, byteOrder.AppendUint64(body, uint64(indexOffset)))
+}
+
+func TestHuffmanArchiveMetadata(t *testing.T) {
+	mtime := time.Date(2021, 3, 4, 5, 6, 7, 890, time.UTC)
+	members := []ArchiveMember{
+		{Name: "script.sh", Data: []byte("#!/bin/sh\necho hi\n"), Mode: 0755, ModTime: mtime},
+		// Only permission bits are kept.
+		{Name: "secret", Data: []byte("shh"), Mode: fs.ModeSetuid | 0600},
+		{Name: "plain", Data: []byte("no metadata")},
+	}
+	archive, err := HuffmanArchive(members)
+	if err != nil {
+		t.Fatalf("unexpected archive error: %v", err)
+	}
+	got, err := HuffmanArchiveExtract(archive)
+	if err != nil {
+		t.Fatalf("unexpected extract error: %v", err)
+	}
+	wantModes := []fs.FileMode{0755, 0600, 0}
+	wantTimes := []time.Time{mtime, {}, {}}
+	for i := range members {
+		if got[i].Mode != wantModes[i] {
+			t.Errorf("member %q: expected mode %v, got %v", got[i].Name, wantModes[i], got[i].Mode)
+		}
+		if !got[i].ModTime.Equal(wantTimes[i]) {
+			t.Errorf("member %q: expected mtime %v, got %v", got[i].Name, wantTimes[i], got[i].ModTime)
+		}
+	}
+
+	legacy := legacyArchive(t, members[:1])
+	got, err = HuffmanArchiveExtract(legacy)
+	if err != nil {
+		t.Fatalf("unexpected legacy extract error: %v", err)
+	}
+	if got[0].Mode != 0 || !got[0].ModTime.IsZero() || !bytes.Equal(got[0].Data, members[0].Data) {
+		t.Errorf("legacy member read back as mode %v, mtime %v", got[0].Mode, got[0].ModTime)
+	}
+	// Appending to a legacy archive keeps its entry readable.
+	appended, err := HuffmanArchiveAppend(legacy, "new", []byte("new member"))
+	if err != nil {
+		t.Fatalf("unexpected append error: %v", err)
+	}
+	if got, err := HuffmanArchiveExtract(appended); err != nil || len(got) != 2 {
+		t.Errorf("expected 2 members after appending to a legacy archive, got %d (%v)", len(got), err)
+	}
+}
@@ -0,0 +1,93 @@
+package huffman
+
+import "fmt"
+
+// asciiAlphabet is the alphabet ModeASCII codes: tab, line feed, carriage
+// return and the printable characters from space to tilde, 98 in all.
+const asciiAlphabet = "\t\n\r !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+
+// asciiBitmapSize is the size of the ModeASCII presence bitmap, one bit per
+// alphabet symbol.
+const asciiBitmapSize = (len(asciiAlphabet) + 7) / 8
+
+// asciiIndex maps a byte to its position in asciiAlphabet, or -1.
+var asciiIndex = func() (index [256]int) {
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(asciiAlphabet); i++ {
+		index[asciiAlphabet[i]] = i
+	}
+	return index
+}()
+
+// HuffmanCompressASCII codes text whose every byte is in the printable ASCII
+// alphabet (tab, line feed, carriage return and space to tilde) into a
+// ModeASCII blob. The payload is the ModeHuffman one, but knowing the
+// alphabet lets the frequency table drop its symbol bytes and fixed-width
+// counts, so it costs 13 bytes plus one or two per symbol for most inputs
+// rather than 2 plus five per symbol. A byte outside the alphabet fails with
+// ErrNotASCII. The body is:
+//
+//	[13]u8         bitmap over the alphabet in the order above, bit i&7 of
+//	               byte i>>3 set if symbol i occurs
+//	k x uvarint    frequency of each symbol present, in alphabet order
+//	u64            number of meaningful payload bits
+//	payload        as for ModeHuffman
+//
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressASCII(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	for i, b := range data {
+		if asciiIndex[b] < 0 {
+			return nil, fmt.Errorf("%w: byte 0x%02x at offset %d", ErrNotASCII, b, i)
+		}
+	}
+	return compressVarintTable(data, ModeASCII, appendASCIIHeader)
+}
+
+// appendASCIIHeader appends the bitmap and counts of freq, whose symbols
+// must all be in asciiAlphabet, to buf.
+// Time Complexity: O(m), Space Complexity: O(m)
+func appendASCIIHeader(buf []byte, freq map[byte]int) []byte {
+	var bitmap [asciiBitmapSize]byte
+	for b := range freq {
+		i := asciiIndex[b]
+		bitmap[i>>3] |= 1 << (i & 7)
+	}
+	// The alphabet is in ascending byte order, so its counts are too.
+	return appendVarintCounts(append(buf, bitmap[:]...), freq)
+}
+
+// readASCIIBody parses the header and bit length of a ModeASCII body,
+// returning the frequency table, its tree, the bit count and the payload.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readASCIIBody(body []byte) (map[byte]int, *Node, uint64, []byte, error) {
+	if len(body) < asciiBitmapSize {
+		return nil, nil, 0, nil, corruptf("read alphabet bitmap failed: body of %d bytes is too short", len(body))
+	}
+	bitmap, rest := body[:asciiBitmapSize], body[asciiBitmapSize:]
+	if bitmap[asciiBitmapSize-1]>>(len(asciiAlphabet)&7) != 0 {
+		return nil, nil, 0, nil, corruptf("invalid header: alphabet bitmap sets bits past symbol %d", len(asciiAlphabet)-1)
+	}
+	var symbols []byte
+	for i := 0; i < len(asciiAlphabet); i++ {
+		if bitmap[i>>3]&(1<<(i&7)) != 0 {
+			symbols = append(symbols, asciiAlphabet[i])
+		}
+	}
+	return readVarintTable(rest, ModeASCII, symbols)
+}
+
+// decodeASCIIBody reverses HuffmanCompressASCII for a ModeASCII body. strict
+// is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeASCIIBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	freq, root, totalBits, bitData, err := readASCIIBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHuffmanPayload(nil, freq, root, totalBits, bitData, maxSize, strict)
+}
@@ -0,0 +1,173 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// asciiSource is a stretch of Go source, the kind of text ModeASCII is for.
+const asciiSource = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfor i := 0; i < 10; i++ {\n\t\tfmt.Printf(\"%d: %q\\n\", i, \"~hello, world!~\")\n\t}\n}\n"
+
+func TestHuffmanCompressASCII(t *testing.T) {
+	// The bitmap has a fixed cost, so inputs of only a symbol or two keep
+	// the larger header.
+	tests := []struct {
+		name    string
+		content []byte
+		smaller bool
+	}{
+		{name: "Source", content: []byte(strings.Repeat(asciiSource, 20)), smaller: true},
+		{name: "Log lines", content: []byte(strings.Repeat("2024-01-02T03:04:05Z INFO request served in 12ms\r\n", 50)), smaller: true},
+		{name: "Whole alphabet", content: []byte(asciiAlphabet), smaller: true},
+		{name: "Single symbol", content: []byte("~~~~~~")},
+		{name: "Single byte", content: []byte("\t")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := HuffmanCompressASCII(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if mode, _ := ModeOf(blob); mode != ModeASCII {
+				t.Errorf("expected a %s blob, got %s", ModeASCII, mode)
+			}
+			got, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, tt.content) {
+				t.Error("decompressed output does not match original")
+			}
+			if err := HuffmanVerify(blob); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+			if _, err := DecodeBitString(blob); err != nil {
+				t.Errorf("unexpected bit string error: %v", err)
+			}
+
+			// The codes are those of ModeHuffman; only the header differs.
+			plain := mustCompress(t, tt.content)
+			_, _, asciiPayload, err := splitPayload(blob)
+			if err != nil {
+				t.Fatalf("unexpected split error: %v", err)
+			}
+			_, _, plainPayload, err := splitPayload(plain)
+			if err != nil {
+				t.Fatalf("unexpected split error: %v", err)
+			}
+			if !bytes.Equal(asciiPayload, plainPayload) {
+				t.Error("payload differs from the ModeHuffman one")
+			}
+			if tt.smaller && len(blob) >= len(plain) {
+				t.Errorf("expected a header smaller than ModeHuffman's, got %d bytes against %d", len(blob), len(plain))
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressASCIIHeaderSize(t *testing.T) {
+	content := []byte(strings.Repeat(asciiSource, 20))
+	freq := buildFrequencyTable(content)
+	blob, err := HuffmanCompressASCII(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	bitsAt, _, _, err := splitPayload(blob)
+	if err != nil {
+		t.Fatalf("unexpected split error: %v", err)
+	}
+	header := bitsAt - containerHeaderSize
+	if header > asciiBitmapSize+2*len(freq) {
+		t.Errorf("expected at most %d header bytes for %d symbols, got %d", asciiBitmapSize+2*len(freq), len(freq), header)
+	}
+	if plain := headerSize(len(freq)); header >= plain/2 {
+		t.Errorf("expected under half of the %d byte ModeHuffman header, got %d", plain, header)
+	}
+}
+
+func TestHuffmanCompressASCIIRejectsBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr string
+	}{
+		{name: "NUL", content: []byte("text\x00more"), wantErr: "byte 0x00 at offset 4"},
+		{name: "DEL", content: []byte("\x7f"), wantErr: "byte 0x7f at offset 0"},
+		{name: "UTF-8", content: []byte("café"), wantErr: "byte 0xc3 at offset 3"},
+		{name: "Vertical tab", content: []byte("a\vb"), wantErr: "byte 0x0b at offset 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := HuffmanCompressASCII(tt.content)
+			if !errors.Is(err, ErrNotASCII) {
+				t.Fatalf("expected ErrNotASCII, got %v", err)
+			}
+			if blob != nil {
+				t.Errorf("expected no blob, got %d bytes", len(blob))
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+	if _, err := HuffmanCompressASCII(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
+
+func TestDecompressASCIICorrupt(t *testing.T) {
+	blob, err := HuffmanCompressASCII([]byte("abracadabra"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	bitmapAt := containerHeaderSize
+	countsAt := bitmapAt + asciiBitmapSize
+	tests := []struct {
+		name   string
+		mutate func(b []byte) []byte
+	}{
+		{name: "Short bitmap", mutate: func(b []byte) []byte { return b[:countsAt-1] }},
+		{name: "Bit past the alphabet", mutate: func(b []byte) []byte { b[countsAt-1] |= 0x80; return b }},
+		{name: "Empty alphabet", mutate: func(b []byte) []byte {
+			return append(b[:bitmapAt], make([]byte, asciiBitmapSize+8)...)
+		}},
+		{name: "Zero frequency", mutate: func(b []byte) []byte { b[countsAt] = 0; return b }},
+		{name: "Unterminated frequency", mutate: func(b []byte) []byte { return append(b[:countsAt], 0x80) }},
+		{name: "Missing bit length", mutate: func(b []byte) []byte { return b[:countsAt+5+4] }},
+		{name: "Truncated payload", mutate: func(b []byte) []byte { return b[:len(b)-1] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := tt.mutate(bytes.Clone(blob))
+			if _, err := Decompress(corrupt); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("expected ErrCorruptStream, got %v", err)
+			}
+			if err := HuffmanVerify(corrupt); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("expected verify to report ErrCorruptStream, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecompressMultiASCII(t *testing.T) {
+	first, err := HuffmanCompressASCII([]byte("first member\n"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	second, err := HuffmanCompressASCII([]byte("second member\n"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	got, err := HuffmanDecompressMulti(append(first, second...))
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if want := "first member\nsecond member\n"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,117 @@
+package huffman
+
+// rleMinMeanRun is the mean run length from which CompressAuto tries run
+// length encoding; below it most runs are single bytes and RLE only doubles
+// the token count.
+const rleMinMeanRun = 2
+
+// AutoStats describes the input analysis behind a CompressAuto call and the
+// mode it settled on.
+type AutoStats struct {
+	// Entropy is the order-0 Shannon entropy of the input in bits per byte,
+	// for display; CompressAuto does not decide on it.
+	Entropy float64 `json:"entropy"`
+	// MeanRun is the input length divided by its number of byte runs.
+	MeanRun float64 `json:"meanRun"`
+	// Mode is the container mode of the returned blob.
+	Mode Mode `json:"mode"`
+}
+
+// CompressAuto picks a codec for data from the size its Huffman coding would
+// have and its mean run length, and returns a blob no larger than storing it
+// would be. Decompress reads the choice back from the container mode.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func CompressAuto(data []byte) ([]byte, error) {
+	blob, _, err := CompressAutoStats(data)
+	return blob, err
+}
+
+// CompressAutoStats is CompressAuto, also returning the analysis it used.
+// Input whose predicted Huffman blob is no smaller than storing it, such as
+// random bytes, is stored; input whose mean run is at least rleMinMeanRun
+// goes through RLE unless Huffman coding or DEFLATE comes out smaller;
+// anything else is Huffman coded, falling back to DEFLATE when that is
+// smaller. The entropy is reported but plays no part in the choice.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func CompressAutoStats(data []byte) ([]byte, AutoStats, error) {
+	if len(data) == 0 {
+		return nil, AutoStats{}, ErrEmptyInput
+	}
+	stats, huffmanSize := analyzeInput(data)
+	storeSize := containerHeaderSize + len(data)
+
+	var blob []byte
+	var err error
+	switch {
+	case huffmanSize >= storeSize:
+		blob, err = CompressStore(data)
+	case stats.MeanRun >= rleMinMeanRun:
+		// Runs favour RLE but do not guarantee it wins: long repeats of a
+		// runny pattern suit DEFLATE better.
+		blob, err = smallestOf(data, HuffmanCompressRLE, CompressBest)
+	default:
+		blob, err = CompressBest(data)
+	}
+	if err != nil {
+		return nil, AutoStats{}, err
+	}
+	if len(blob) > storeSize {
+		// RLE and DEFLATE can still lose to storing on awkward input.
+		if blob, err = CompressStore(data); err != nil {
+			return nil, AutoStats{}, err
+		}
+	}
+	stats.Mode = Mode(blob[len(magic)+1])
+	return blob, stats, nil
+}
+
+// smallestOf returns the smallest of the blobs the compressors make of
+// data, the first on ties.
+// Time Complexity: O(k·(n + m log m)) for k compressors, Space Complexity:
+// O(n + m)
+func smallestOf(data []byte, compressors ...func([]byte) ([]byte, error)) ([]byte, error) {
+	var best []byte
+	for _, compress := range compressors {
+		blob, err := compress(data)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || len(blob) < len(best) {
+			best = blob
+		}
+	}
+	return best, nil
+}
+
+// analyzeInput measures the entropy and mean run length of data and predicts
+// the size of its ModeHuffman blob.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func analyzeInput(data []byte) (AutoStats, int) {
+	var counts [256]int
+	runs := 0
+	for i, b := range data {
+		counts[b]++
+		if i == 0 || data[i-1] != b {
+			runs++
+		}
+	}
+	freq := make(map[byte]int, 256)
+	for b, c := range counts {
+		if c > 0 {
+			freq[byte(b)] = c
+		}
+	}
+	entropy := entropyOf(&counts, len(data))
+
+	lengths := make(map[byte]int, len(freq))
+	codeLengths(buildHuffmanTree(freq), 0, lengths)
+	totalBits := 0
+	for b, f := range freq {
+		totalBits += f * lengths[b]
+	}
+	size := huffmanBlobSize(len(freq), totalBits)
+	return AutoStats{
+		Entropy: entropy,
+		MeanRun: float64(len(data)) / float64(runs),
+	}, size
+}
@@ -0,0 +1,90 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompressAuto(t *testing.T) {
+	rng := rand.New(rand.NewSource(27))
+	random := make([]byte, 8192)
+	rng.Read(random)
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 100))
+	// Short runs over a few symbols, with no longer repeats for DEFLATE to
+	// find, are where RLE beats the other codecs.
+	var runny []byte
+	for i := 0; i < 400; i++ {
+		runny = append(runny, bytes.Repeat([]byte{byte(rng.Intn(16))}, 3+rng.Intn(5))...)
+	}
+
+	tests := []struct {
+		name      string
+		content   []byte
+		wantModes []Mode
+	}{
+		{name: "Random", content: random, wantModes: []Mode{ModeStore}},
+		{name: "Text", content: text, wantModes: []Mode{ModeHuffman, ModeFlate}},
+		{name: "Runny", content: runny, wantModes: []Mode{ModeRLE}},
+		// Runny, but one short pattern over and over, which DEFLATE codes
+		// far smaller than RLE.
+		{name: "Repeated runs", content: []byte(strings.Repeat("aaaabbbbccccddddeeeeffffgggghhhh", 200)), wantModes: []Mode{ModeFlate}},
+		{name: "Single byte", content: []byte("x"), wantModes: []Mode{ModeStore}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, stats, err := CompressAutoStats(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			mode, err := ModeOf(blob)
+			if err != nil {
+				t.Fatalf("unexpected container error: %v", err)
+			}
+			if mode != stats.Mode {
+				t.Errorf("stats report %s, blob is %s", stats.Mode, mode)
+			}
+			found := false
+			for _, m := range tt.wantModes {
+				found = found || m == mode
+			}
+			if !found {
+				t.Errorf("expected one of %v, got %s (entropy %.2f, mean run %.2f)", tt.wantModes, mode, stats.Entropy, stats.MeanRun)
+			}
+			if len(blob) > containerHeaderSize+len(tt.content) {
+				t.Errorf("blob of %d bytes is larger than storing %d bytes", len(blob), len(tt.content))
+			}
+
+			decompressed, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Error("decompressed output does not match original")
+			}
+			auto, err := CompressAuto(tt.content)
+			if err != nil || !bytes.Equal(auto, blob) {
+				t.Errorf("CompressAuto differs from CompressAutoStats (err %v)", err)
+			}
+		})
+	}
+}
+
+func TestCompressAutoStatsAnalysis(t *testing.T) {
+	_, stats, err := CompressAutoStats([]byte("aabbbbcd"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	// p = 1/4, 1/2, 1/8, 1/8 gives 1.75 bits per byte over 4 runs.
+	if stats.Entropy != 1.75 {
+		t.Errorf("expected entropy 1.75, got %v", stats.Entropy)
+	}
+	if stats.MeanRun != 2 {
+		t.Errorf("expected mean run 2, got %v", stats.MeanRun)
+	}
+	if _, err := CompressAuto(nil); err == nil {
+		t.Error("expected error for empty input but got nil")
+	}
+}
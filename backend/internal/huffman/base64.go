@@ -0,0 +1,54 @@
+package huffman
+
+import "encoding/base64"
+
+// HuffmanCompressBase64 compresses data like HuffmanCompressBytes and
+// returns the blob in standard base64 (RFC 4648 section 4: A-Z, a-z, 0-9,
+// '+' and '/', padded with '='), for embedding in JSON or other text.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressBase64(data []byte) (string, error) {
+	return compressBase64(data, base64.StdEncoding)
+}
+
+// HuffmanCompressBase64URL is like HuffmanCompressBase64 but uses the
+// URL-safe alphabet ('-' and '_' in place of '+' and '/') without padding,
+// so the result can go in a URL path or query unescaped.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressBase64URL(data []byte) (string, error) {
+	return compressBase64(data, base64.RawURLEncoding)
+}
+
+// HuffmanDecompressBase64 reverses HuffmanCompressBase64. The text may hold
+// any blob Decompress accepts; text that is not valid standard base64 is
+// reported as ErrCorruptStream.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompressBase64(s string) ([]byte, error) {
+	return decompressBase64(s, base64.StdEncoding)
+}
+
+// HuffmanDecompressBase64URL reverses HuffmanCompressBase64URL, expecting
+// unpadded URL-safe base64.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompressBase64URL(s string) ([]byte, error) {
+	return decompressBase64(s, base64.RawURLEncoding)
+}
+
+// compressBase64 compresses data and encodes the blob with enc.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func compressBase64(data []byte, enc *base64.Encoding) (string, error) {
+	blob, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return "", err
+	}
+	return enc.EncodeToString(blob), nil
+}
+
+// decompressBase64 decodes s with enc and decompresses the blob.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decompressBase64(s string, enc *base64.Encoding) ([]byte, error) {
+	blob, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, corruptf("invalid base64: %v", err)
+	}
+	return Decompress(blob)
+}
@@ -0,0 +1,85 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanBase64RoundTrip(t *testing.T) {
+	binary := make([]byte, 2048)
+	for i := range binary {
+		binary[i] = byte(i*7) | 0xC0
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Text", content: []byte(strings.Repeat("hello world! ", 40))},
+		{name: "Binary", content: binary},
+		{name: "SingleSymbol", content: bytes.Repeat([]byte{0xFF}, 100)},
+		{name: "OneByte", content: []byte{0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			std, err := HuffmanCompressBase64(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if _, err := base64.StdEncoding.DecodeString(std); err != nil {
+				t.Errorf("expected standard base64, got %v", err)
+			}
+			got, err := HuffmanDecompressBase64(std)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, tt.content) {
+				t.Error("standard base64 round trip does not match original")
+			}
+
+			url, err := HuffmanCompressBase64URL(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if strings.ContainsAny(url, "+/=") {
+				t.Errorf("expected URL-safe unpadded base64, got %q", url)
+			}
+			got, err = HuffmanDecompressBase64URL(url)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, tt.content) {
+				t.Error("URL-safe base64 round trip does not match original")
+			}
+		})
+	}
+}
+
+func TestHuffmanBase64Errors(t *testing.T) {
+	if _, err := HuffmanCompressBase64(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput for empty input, got %v", err)
+	}
+	if _, err := HuffmanCompressBase64URL([]byte{}); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput for empty input, got %v", err)
+	}
+	if _, err := HuffmanDecompressBase64("not base64!"); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for invalid base64, got %v", err)
+	}
+
+	// The URL-safe decoder rejects the standard alphabet and padding.
+	data := []byte{0xFB, 0xFF, 0xFE, 0xFB, 0xEF, 0xBE}
+	std, err := HuffmanCompressBase64(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := HuffmanDecompressBase64URL(std); err == nil {
+		t.Error("expected URL-safe decoding of padded standard base64 to fail but got nil")
+	}
+	if _, err := HuffmanDecompressBase64(""); err == nil {
+		t.Error("expected error for empty text but got nil")
+	}
+}
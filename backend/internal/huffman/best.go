@@ -0,0 +1,59 @@
+package huffman
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// flateCompress encodes data as a raw DEFLATE stream at best compression.
+// Time Complexity: O(n), Space Complexity: O(n)
+func flateCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flateDecompress decodes a raw DEFLATE stream, failing once the output
+// would exceed maxSize bytes.
+// Time Complexity: O(n), Space Complexity: O(n)
+func flateDecompress(payload []byte, maxSize int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(payload))
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, corruptf("flate decode failed: %w", err)
+	}
+	if len(out) > maxSize {
+		return nil, sizeLimitError(maxSize)
+	}
+	return out, nil
+}
+
+// CompressBest compresses data with both Huffman coding and DEFLATE and keeps
+// whichever is smaller as a ModeHuffman or ModeFlate blob. Huffman is
+// preferred on ties.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func CompressBest(data []byte) ([]byte, error) {
+	huff, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	deflated, err := flateCompress(data)
+	if err != nil {
+		return nil, err
+	}
+	if containerHeaderSize+len(deflated) < len(huff) {
+		return wrap(ModeFlate, deflated), nil
+	}
+	return huff, nil
+}
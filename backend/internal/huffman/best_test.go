@@ -0,0 +1,55 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompressBest(t *testing.T) {
+	// Independent draws from a dyadic distribution are exactly what Huffman
+	// coding is optimal for, and leave DEFLATE no matches to exploit.
+	rng := rand.New(rand.NewSource(7))
+	dyadic := make([]byte, 4096)
+	for i := range dyadic {
+		dyadic[i] = "aaaabbcd"[rng.Intn(8)]
+	}
+
+	structured := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 200))
+
+	tests := []struct {
+		name     string
+		content  []byte
+		wantMode Mode
+	}{
+		{name: "Dyadic symbols", content: dyadic, wantMode: ModeHuffman},
+		{name: "Structured text", content: structured, wantMode: ModeFlate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := CompressBest(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if mode, _, _ := unwrap(compressed); mode != tt.wantMode {
+				t.Errorf("expected mode %v, got %v", tt.wantMode, mode)
+			}
+
+			decompressed, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestDecompressFlateInvalid(t *testing.T) {
+	if _, err := Decompress(wrap(ModeFlate, []byte{0xFF, 0xFF})); err == nil {
+		t.Error("expected error for corrupt flate payload but got nil")
+	}
+}
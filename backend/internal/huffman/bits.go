@@ -0,0 +1,114 @@
+package huffman
+
+import (
+	"fmt"
+	"io"
+)
+
+// bitWriter packs bits most significant first into w. Bits are buffered until
+// a whole byte is available; Flush pads the final partial byte with zeros.
+type bitWriter struct {
+	w       io.ByteWriter
+	acc     uint64
+	pending uint // buffered bits in the low end of acc, always < 8 between calls
+	written int
+}
+
+func newBitWriter(w io.ByteWriter) *bitWriter {
+	return &bitWriter{w: w}
+}
+
+// WriteBits writes the low n bits of value, most significant first. n must be
+// between 0 and 32.
+func (bw *bitWriter) WriteBits(value uint32, n int) error {
+	if n < 0 || n > 32 {
+		return fmt.Errorf("bit writer: cannot write %d bits", n)
+	}
+	bw.acc = bw.acc<<uint(n) | uint64(value)&(1<<uint(n)-1)
+	bw.pending += uint(n)
+	bw.written += n
+	for bw.pending >= 8 {
+		if err := bw.w.WriteByte(byte(bw.acc >> (bw.pending - 8))); err != nil {
+			return err
+		}
+		bw.pending -= 8
+	}
+	return nil
+}
+
+// writeCode writes a packed code, splitting codes longer than 32 bits.
+func (bw *bitWriter) writeCode(c code) error {
+	if c.length > 32 {
+		if err := bw.WriteBits(uint32(c.bits>>32), int(c.length)-32); err != nil {
+			return err
+		}
+		return bw.WriteBits(uint32(c.bits), 32)
+	}
+	return bw.WriteBits(uint32(c.bits), int(c.length))
+}
+
+// Flush writes any buffered bits, padding the final byte with zero bits.
+func (bw *bitWriter) Flush() error {
+	if bw.pending == 0 {
+		return nil
+	}
+	err := bw.w.WriteByte(byte(bw.acc << (8 - bw.pending)))
+	bw.pending = 0
+	return err
+}
+
+// Written reports the number of bits written so far, excluding padding.
+func (bw *bitWriter) Written() int {
+	return bw.written
+}
+
+// bitReader reads up to limit bits, most significant first, from data.
+type bitReader struct {
+	data  []byte
+	pos   uint64
+	limit uint64
+}
+
+func newBitReader(data []byte, limit uint64) *bitReader {
+	return &bitReader{data: data, limit: limit}
+}
+
+// ReadBit returns the next bit. It returns io.EOF once limit bits have been
+// read and io.ErrUnexpectedEOF if data ends first.
+func (br *bitReader) ReadBit() (int, error) {
+	if br.pos >= br.limit {
+		return 0, io.EOF
+	}
+	i := br.pos / 8
+	if i >= uint64(len(br.data)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	bit := int(br.data[i]>>(7-br.pos%8)) & 1
+	br.pos++
+	return bit, nil
+}
+
+// ReadBits reads n bits (0 to 32) as an unsigned value, most significant
+// first. Running out of bits part way through returns io.ErrUnexpectedEOF.
+func (br *bitReader) ReadBits(n int) (uint32, error) {
+	if n < 0 || n > 32 {
+		return 0, fmt.Errorf("bit reader: cannot read %d bits", n)
+	}
+	var v uint32
+	for i := 0; i < n; i++ {
+		bit, err := br.ReadBit()
+		if err != nil {
+			if err == io.EOF && i > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v = v<<1 | uint32(bit)
+	}
+	return v, nil
+}
+
+// BitsRead reports the number of bits consumed so far.
+func (br *bitReader) BitsRead() uint64 {
+	return br.pos
+}
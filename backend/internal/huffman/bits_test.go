@@ -0,0 +1,126 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBitWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes [][2]int // value, n
+		want   []byte
+		bits   int
+	}{
+		{name: "Nothing", writes: nil, want: nil, bits: 0},
+		{name: "Single bit", writes: [][2]int{{1, 1}}, want: []byte{0x80}, bits: 1},
+		{name: "Exact byte", writes: [][2]int{{0xA5, 8}}, want: []byte{0xA5}, bits: 8},
+		{name: "Cross byte boundary", writes: [][2]int{{0x5, 3}, {0x3F, 6}}, want: []byte{0xBF, 0x80}, bits: 9},
+		{name: "Zero length write", writes: [][2]int{{0x1, 0}, {0x3, 2}}, want: []byte{0xC0}, bits: 2},
+		{name: "Full 32 bits unaligned", writes: [][2]int{{0x1, 1}, {0xDEADBEEF, 32}}, want: []byte{0xEF, 0x56, 0xDF, 0x77, 0x80}, bits: 33},
+		{name: "High bits ignored", writes: [][2]int{{0xFF, 2}}, want: []byte{0xC0}, bits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			bw := newBitWriter(&buf)
+			for _, w := range tt.writes {
+				if err := bw.WriteBits(uint32(w[0]), w[1]); err != nil {
+					t.Fatalf("unexpected write error: %v", err)
+				}
+			}
+			if err := bw.Flush(); err != nil {
+				t.Fatalf("unexpected flush error: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("got % x, want % x", buf.Bytes(), tt.want)
+			}
+			if bw.Written() != tt.bits {
+				t.Errorf("expected %d bits written, got %d", tt.bits, bw.Written())
+			}
+		})
+	}
+}
+
+func TestBitWriterInvalidWidth(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newBitWriter(&buf).WriteBits(0, 33); err == nil {
+		t.Error("expected error writing 33 bits but got nil")
+	}
+}
+
+func TestBitWriterLongCode(t *testing.T) {
+	var buf bytes.Buffer
+	bw := newBitWriter(&buf)
+	if err := bw.writeCode(code{bits: 0x1_0000_0001, length: 33}); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	bw.Flush()
+
+	br := newBitReader(buf.Bytes(), 33)
+	if bit, _ := br.ReadBit(); bit != 1 {
+		t.Errorf("expected leading 1 bit, got %d", bit)
+	}
+	if v, err := br.ReadBits(32); err != nil || v != 1 {
+		t.Errorf("expected low 32 bits to be 1, got %d (%v)", v, err)
+	}
+}
+
+func TestBitReader(t *testing.T) {
+	data := []byte{0xBF, 0x80}
+	br := newBitReader(data, 9)
+
+	if v, err := br.ReadBits(3); err != nil || v != 0x5 {
+		t.Fatalf("ReadBits(3) = %#x, %v; want 0x5", v, err)
+	}
+	// Spans the byte boundary.
+	if v, err := br.ReadBits(6); err != nil || v != 0x3F {
+		t.Fatalf("ReadBits(6) = %#x, %v; want 0x3f", v, err)
+	}
+	if br.BitsRead() != 9 {
+		t.Errorf("expected 9 bits read, got %d", br.BitsRead())
+	}
+	if _, err := br.ReadBit(); err != io.EOF {
+		t.Errorf("expected io.EOF at limit, got %v", err)
+	}
+}
+
+func TestBitReaderTruncated(t *testing.T) {
+	br := newBitReader([]byte{0xFF}, 12)
+	if _, err := br.ReadBits(8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := br.ReadBit(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF past data, got %v", err)
+	}
+
+	br = newBitReader([]byte{0xFF}, 4)
+	if _, err := br.ReadBits(6); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF reading past limit mid-value, got %v", err)
+	}
+}
+
+func TestBitWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	bw := newBitWriter(&buf)
+	var widths []int
+	for n := 0; n <= 32; n++ {
+		widths = append(widths, n)
+		if err := bw.WriteBits(uint32(n*2654435761), n); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	bw.Flush()
+
+	br := newBitReader(buf.Bytes(), uint64(bw.Written()))
+	for _, n := range widths {
+		want := uint32(n*2654435761) & uint32(uint64(1)<<uint(n)-1)
+		got, err := br.ReadBits(n)
+		if err != nil || got != want {
+			t.Fatalf("ReadBits(%d) = %#x, %v; want %#x", n, got, err, want)
+		}
+	}
+}
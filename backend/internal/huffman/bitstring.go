@@ -0,0 +1,131 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// splitPayload locates the bit length and packed codes that end the body of
+// blob, returning the offset of the bit length within blob, the bit count
+// and the payload. The bit length is a u64, or a uvarint for ModeCompact.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func splitPayload(blob []byte) (int, uint64, []byte, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	var totalBits uint64
+	var payload []byte
+	switch mode {
+	case ModeHuffman, ModeRLE, ModeDelta:
+		_, _, totalBits, payload, err = readHuffmanBody(body)
+	case ModeASCII:
+		_, _, totalBits, payload, err = readASCIIBody(body)
+	case ModeCompact:
+		_, _, totalBits, payload, err = readCompactBody(body)
+	case ModeWords:
+		var wb wordsBody
+		wb, err = readWordsBody(body)
+		totalBits, payload = wb.totalBits, wb.payload
+	case ModeCanonical:
+		_, totalBits, payload, err = readCanonicalBody(body)
+	case ModeEscape:
+		var eb escapeBody
+		eb, err = readEscapeBody(body)
+		totalBits, payload = eb.totalBits, eb.payload
+	case ModeModel:
+		if len(body) < 8 {
+			return 0, 0, nil, corruptf("read bit length failed: body of %d bytes is too short", len(body))
+		}
+		totalBits, payload = byteOrder.Uint64(body), body[8:]
+	default:
+		return 0, 0, nil, fmt.Errorf("%s blob has no packed code payload", mode)
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return len(blob) - len(payload) - len(appendBitLength(nil, mode, totalBits)), totalBits, payload, nil
+}
+
+// appendBitLength appends a payload bit length to buf in the encoding mode
+// uses for it.
+func appendBitLength(buf []byte, mode Mode, totalBits uint64) []byte {
+	if mode == ModeCompact {
+		return binary.AppendUvarint(buf, totalBits)
+	}
+	return byteOrder.AppendUint64(buf, totalBits)
+}
+
+// readBitLength reads a payload bit length written by appendBitLength for
+// mode from the front of buf, returning it and the bytes after it.
+func readBitLength(buf []byte, mode Mode) (uint64, []byte, error) {
+	if mode == ModeCompact {
+		totalBits, n := readUvarint(buf)
+		if n <= 0 {
+			return 0, nil, corruptf("read bit length failed: invalid uvarint")
+		}
+		return totalBits, buf[n:], nil
+	}
+	if len(buf) < 8 {
+		return 0, nil, corruptf("read bit length failed: %d bytes left", len(buf))
+	}
+	return byteOrder.Uint64(buf), buf[8:], nil
+}
+
+// DecodeBitString returns the payload of blob as a string of '0' and '1',
+// one character per meaningful bit in stream order, leaving out the padding
+// of the final byte. It is a debugging aid for reading the packed codes of
+// any Huffman-coded mode directly.
+// Time Complexity: O(n + m log m), Space Complexity: O(n)
+func DecodeBitString(blob []byte) (string, error) {
+	_, totalBits, payload, err := splitPayload(blob)
+	if err != nil {
+		return "", err
+	}
+	if err := checkPayloadLength(payload, totalBits); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.Grow(int(totalBits))
+	br := newBitReader(payload, totalBits)
+	for i := uint64(0); i < totalBits; i++ {
+		bit, err := br.ReadBit()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte('0' + byte(bit))
+	}
+	return sb.String(), nil
+}
+
+// EncodeFromBitString is the inverse of DecodeBitString: it returns a copy
+// of blob with its bit length and payload replaced by bits, a string of '0'
+// and '1', packed the way the encoder packs codes. The header is kept as-is,
+// so bits that do not spell out whole codes, or that disagree with the
+// header's frequencies, give a blob that fails to decode.
+// Time Complexity: O(n + m log m), Space Complexity: O(n)
+func EncodeFromBitString(blob []byte, bits string) ([]byte, error) {
+	bitsAt, _, _, err := splitPayload(blob)
+	if err != nil {
+		return nil, err
+	}
+	mode, _ := ModeOf(blob)
+	out := bytes.NewBuffer(make([]byte, 0, bitsAt+8+(len(bits)+7)/8))
+	out.Write(blob[:bitsAt])
+	out.Write(appendBitLength(nil, mode, uint64(len(bits))))
+	bw := newBitWriter(out)
+	for i := 0; i < len(bits); i++ {
+		if bits[i] != '0' && bits[i] != '1' {
+			return nil, fmt.Errorf("invalid bit %q at offset %d", bits[i], i)
+		}
+		if err := bw.WriteBits(uint32(bits[i]-'0'), 1); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBitString(t *testing.T) {
+	content := []byte("abracadabra")
+	blob, codes, err := HuffmanCompressVerbose(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	var want strings.Builder
+	for _, b := range content {
+		want.WriteString(codes[b])
+	}
+	got, err := DecodeBitString(blob)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if got != want.String() {
+		t.Fatalf("expected bits %s, got %s", want.String(), got)
+	}
+
+	// Packing the string again reproduces the encoder's output exactly.
+	repacked, err := EncodeFromBitString(blob, got)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	if !bytes.Equal(repacked, blob) {
+		t.Errorf("repacked blob does not match the encoder's output")
+	}
+
+	// Spelling out a permutation under the same header decodes to it.
+	permuted := []byte("aaaaabbrrcd")
+	var bits strings.Builder
+	for _, b := range permuted {
+		bits.WriteString(codes[b])
+	}
+	crafted, err := EncodeFromBitString(blob, bits.String())
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	decompressed, err := Decompress(crafted)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(decompressed, permuted) {
+		t.Errorf("expected %q, got %q", permuted, decompressed)
+	}
+}
+
+func TestEncodeFromBitStringRoundTrip(t *testing.T) {
+	limited, err := HuffmanCompressLimited([]byte("hello world"), MaxCodeLength)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	compact, err := HuffmanCompressOpts([]byte("hello world"), CompressOptions{CompactHeader: true})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	blobs := map[string][]byte{
+		"huffman":   mustCompress(t, []byte("hello world")),
+		"canonical": limited,
+		"compact":   compact,
+	}
+	for name, blob := range blobs {
+		// The 200-bit string needs a wider varint bit length than compact's own.
+		for _, bits := range []string{"", "1", "10110011", "101100111", "0000000011111111", strings.Repeat("10", 100)} {
+			crafted, err := EncodeFromBitString(blob, bits)
+			if err != nil {
+				t.Fatalf("%s %q: unexpected encode error: %v", name, bits, err)
+			}
+			got, err := DecodeBitString(crafted)
+			if err != nil {
+				t.Fatalf("%s %q: unexpected decode error: %v", name, bits, err)
+			}
+			if got != bits {
+				t.Errorf("%s: expected %q, got %q", name, bits, got)
+			}
+		}
+	}
+}
+
+func TestBitStringErrors(t *testing.T) {
+	blob := mustCompress(t, []byte("hello world"))
+	if _, err := EncodeFromBitString(blob, "0102"); err == nil || !strings.Contains(err.Error(), "invalid bit '2' at offset 3") {
+		t.Errorf("expected invalid bit error, got %v", err)
+	}
+	stored, err := CompressStore([]byte("hello"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := DecodeBitString(stored); err == nil {
+		t.Error("expected error for a stored blob but got nil")
+	}
+	if _, err := DecodeBitString(blob[:len(blob)-1]); err == nil {
+		t.Error("expected error for a truncated payload but got nil")
+	}
+}
@@ -0,0 +1,333 @@
+package huffman
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBlockSize is the block size HuffmanCompressBlocks uses when given
+// zero.
+const DefaultBlockSize = 64 << 10
+
+// blockEntry locates one block inside a ModeBlocks body, and the span of the
+// original input it decodes to.
+type blockEntry struct {
+	rawOffset uint64
+	rawLength uint64
+	offset    uint64
+	length    uint64
+}
+
+// blockEntrySize is the size of an index entry: u64 original length and u64
+// blob length.
+const blockEntrySize = 8 + 8
+
+// HuffmanCompressBlocks splits data into blocks of blockSize bytes, the last
+// possibly shorter, and codes each one independently into a ModeBlocks blob.
+// Every block has its own frequency table, which costs some ratio over a
+// single ModeHuffman blob but lets HuffmanDecompressRange decode part of
+// the input without the rest, and lets blocks be coded in parallel, one
+// goroutine per available CPU. Blocks that coding would expand are stored.
+// The body is laid out as:
+//
+//	blocks         each block is a complete blob, back to back
+//	u32            number of blocks n
+//	n x entry      u64 length of the block's original data, u64 length of
+//	               its blob
+//	u64            offset of the index within the body
+//
+// Time Complexity: O(n + k·m log m) for k blocks, Space Complexity: O(n)
+func HuffmanCompressBlocks(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if blockSize < 0 {
+		return nil, fmt.Errorf("invalid block size %d", blockSize)
+	}
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+	return compressBlocks(data, blockSize, blockWorkers())
+}
+
+// compressBlocks is HuffmanCompressBlocks with up to workers blocks coded at
+// once.
+// Time Complexity: O(n + k·m log m) for k blocks, Space Complexity: O(n)
+func compressBlocks(data []byte, blockSize, workers int) ([]byte, error) {
+	coded := make([][]byte, (len(data)+blockSize-1)/blockSize)
+	err := forEachBlock(len(coded), workers, func(i int) error {
+		var err error
+		coded[i], err = encodeBlock(data[i*blockSize : min((i+1)*blockSize, len(data))])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	entries := make([]blockEntry, len(coded))
+	for i, block := range coded {
+		entries[i] = blockEntry{
+			rawLength: uint64(min(blockSize, len(data)-i*blockSize)),
+			offset:    uint64(len(body)),
+			length:    uint64(len(block)),
+		}
+		body = append(body, block...)
+	}
+	return wrap(ModeBlocks, appendBlockIndex(body, entries, uint64(len(body)))), nil
+}
+
+// blockWorkers returns how many blocks to code or decode at once: one per
+// CPU the scheduler may use. Under GOMAXPROCS=1 it is 1, so forEachBlock
+// stays on the calling goroutine.
+func blockWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// forEachBlock calls fn for each block index below n, on up to workers
+// goroutines. With one worker, or one block, it is a plain loop on the
+// calling goroutine, as goroutines there would only add overhead. It
+// returns the error of the lowest failing index, so the result does not
+// depend on scheduling.
+// Time Complexity: O(n) calls of fn, Space Complexity: O(n)
+func forEachBlock(n, workers int, fn func(i int) error) error {
+	if workers <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make([]error, n)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n {
+					return
+				}
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeBlock codes data as one block, storing it when coding would not
+// make it smaller.
+// Time Complexity: O(n + m log m), Space Complexity: O(n)
+func encodeBlock(data []byte) ([]byte, error) {
+	block, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(block) >= containerHeaderSize+len(data) {
+		block = wrap(ModeStore, data)
+	}
+	return block, nil
+}
+
+// appendBlockIndex appends to dst the index and trailer for entries, whose
+// blocks end indexOffset bytes into the body.
+// Time Complexity: O(k), Space Complexity: O(k)
+func appendBlockIndex(dst []byte, entries []blockEntry, indexOffset uint64) []byte {
+	dst = byteOrder.AppendUint32(dst, uint32(len(entries)))
+	for _, e := range entries {
+		dst = byteOrder.AppendUint64(dst, e.rawLength)
+		dst = byteOrder.AppendUint64(dst, e.length)
+	}
+	return byteOrder.AppendUint64(dst, indexOffset)
+}
+
+// readBlockIndex parses the index of a ModeBlocks body, returning its
+// entries, the region holding the block blobs and the original input size.
+// Time Complexity: O(k), Space Complexity: O(k)
+func readBlockIndex(body []byte) ([]blockEntry, []byte, uint64, error) {
+	if len(body) < 4+8 {
+		return nil, nil, 0, corruptf("block body of %d bytes is too short", len(body))
+	}
+	indexOffset := byteOrder.Uint64(body[len(body)-8:])
+	if indexOffset > uint64(len(body)-8-4) {
+		return nil, nil, 0, corruptf("block index offset %d out of range", indexOffset)
+	}
+	blocks := body[:indexOffset]
+	index := body[indexOffset : len(body)-8]
+
+	count := uint64(byteOrder.Uint32(index))
+	index = index[4:]
+	if count*blockEntrySize != uint64(len(index)) {
+		return nil, nil, 0, corruptf("block index of %d bytes does not hold %d entries", len(index), count)
+	}
+	entries := make([]blockEntry, count)
+	var rawOffset, offset uint64
+	for i := range entries {
+		e := blockEntry{
+			rawOffset: rawOffset,
+			rawLength: byteOrder.Uint64(index[i*blockEntrySize:]),
+			offset:    offset,
+			length:    byteOrder.Uint64(index[i*blockEntrySize+8:]),
+		}
+		if e.rawLength == 0 || e.rawLength > math.MaxInt64-rawOffset {
+			return nil, nil, 0, corruptf("block %d has invalid length %d", i, e.rawLength)
+		}
+		if e.length > uint64(len(blocks))-offset {
+			return nil, nil, 0, corruptf("block %d lies outside the body", i)
+		}
+		entries[i] = e
+		rawOffset += e.rawLength
+		offset += e.length
+	}
+	if offset != uint64(len(blocks)) {
+		return nil, nil, 0, corruptf("block region has %d bytes no block accounts for", uint64(len(blocks))-offset)
+	}
+	return entries, blocks, rawOffset, nil
+}
+
+// decodeBlock decodes the block e describes, checking it is a ModeStore or
+// ModeHuffman blob, as encodeBlock writes, and holds as many bytes as the
+// index says. strict is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeBlock(blocks []byte, e blockEntry, i int, strict bool) ([]byte, error) {
+	block := blocks[e.offset : e.offset+e.length]
+	if err := checkLeafBlob(block); err != nil {
+		return nil, fmt.Errorf("block %d: %w", i, err)
+	}
+	data, err := decompress(block, int(min(e.rawLength, DefaultMaxDecompressedSize)), strict)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", i, err)
+	}
+	if uint64(len(data)) != e.rawLength {
+		return nil, corruptf("block %d decoded to %d bytes, index says %d", i, len(data), e.rawLength)
+	}
+	return data, nil
+}
+
+// decodeBlocksBody decodes every block of a ModeBlocks body, several at once
+// when more than one CPU is available.
+// Time Complexity: O(n + k·m log m) for k blocks, Space Complexity: O(n)
+func decodeBlocksBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	return decodeBlocks(body, maxSize, strict, blockWorkers())
+}
+
+// decodeBlocks is decodeBlocksBody with up to workers blocks decoded at
+// once. Each block lands at its own offset of the output, so the order they
+// finish in does not matter.
+//
+// The size the index claims is not checked against the blocks until they
+// decode, so the output is allocated up front only up to sizedPreallocLimit.
+// Beyond that the blocks are decoded on their own and joined once each has
+// produced the length the index says, so a few bytes of forged index cannot
+// claim memory no block will fill.
+// Time Complexity: O(n + k·m log m) for k blocks, Space Complexity: O(n)
+func decodeBlocks(body []byte, maxSize int, strict bool, workers int) ([]byte, error) {
+	entries, blocks, size, err := readBlockIndex(body)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(maxSize) {
+		return nil, sizeLimitError(maxSize)
+	}
+	if size > sizedPreallocLimit {
+		return joinBlocks(blocks, entries, size, strict, workers)
+	}
+	out := make([]byte, size)
+	err = forEachBlock(len(entries), workers, func(i int) error {
+		e := entries[i]
+		data, err := decodeBlock(blocks, e, i, strict)
+		if err != nil {
+			return err
+		}
+		copy(out[e.rawOffset:], data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// joinBlocks decodes every block into its own buffer and concatenates them
+// into the size-byte output once all have decoded.
+// Time Complexity: O(n + k·m log m) for k blocks, Space Complexity: O(n)
+func joinBlocks(blocks []byte, entries []blockEntry, size uint64, strict bool, workers int) ([]byte, error) {
+	parts := make([][]byte, len(entries))
+	err := forEachBlock(len(entries), workers, func(i int) error {
+		var err error
+		parts[i], err = decodeBlock(blocks, entries[i], i, strict)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, size)
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out, nil
+}
+
+// HuffmanDecompressRange returns length bytes of the original input of a
+// ModeBlocks blob, starting at byte start, decoding only the blocks that
+// overlap the range. A range running past the end of the input is cut short
+// there; a start past the end is an error. The range is capped at
+// DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(k + r + b·m log m) for k blocks, b of them overlapping
+// an r-byte range, Space Complexity: O(r + block size)
+func HuffmanDecompressRange(blob []byte, start, length int64) ([]byte, error) {
+	if start < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range of %d bytes at %d", length, start)
+	}
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return nil, err
+	}
+	if mode != ModeBlocks {
+		return nil, fmt.Errorf("%w: expected %s blob, got %s", ErrWrongMode, ModeBlocks, mode)
+	}
+	entries, blocks, size, err := readBlockIndex(body)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(start) > size {
+		return nil, fmt.Errorf("range start %d is past the end of %d bytes", start, size)
+	}
+	end := size
+	if uint64(length) < size-uint64(start) {
+		end = uint64(start + length)
+	}
+	if end-uint64(start) > DefaultMaxDecompressedSize {
+		return nil, sizeLimitError(DefaultMaxDecompressedSize)
+	}
+
+	// The range is only as long as the index says until its blocks decode,
+	// so the up-front allocation is capped as in decodeBlocks.
+	out := make([]byte, 0, min(end-uint64(start), sizedPreallocLimit))
+	first := sort.Search(len(entries), func(i int) bool {
+		return entries[i].rawOffset+entries[i].rawLength > uint64(start)
+	})
+	for i := first; i < len(entries) && entries[i].rawOffset < end; i++ {
+		e := entries[i]
+		data, err := decodeBlock(blocks, e, i, true)
+		if err != nil {
+			return nil, err
+		}
+		lo := max(uint64(start), e.rawOffset) - e.rawOffset
+		hi := min(end, e.rawOffset+e.rawLength) - e.rawOffset
+		out = append(out, data[lo:hi]...)
+	}
+	return out, nil
+}
@@ -0,0 +1,291 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+func blockFixture() []byte {
+	rng := rand.New(rand.NewSource(41))
+	data := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 20)
+	// A run of noise makes at least one block worth storing.
+	for i := 300; i < 400; i++ {
+		data[i] = byte(rng.Intn(256))
+	}
+	return data
+}
+
+func TestHuffmanCompressBlocks(t *testing.T) {
+	data := blockFixture()
+	tests := []struct {
+		name       string
+		blockSize  int
+		wantBlocks int
+	}{
+		{name: "Default block size", blockSize: 0, wantBlocks: 1},
+		{name: "Even split", blockSize: 100, wantBlocks: 9},
+		{name: "Short last block", blockSize: 256, wantBlocks: 4},
+		{name: "One byte blocks", blockSize: 1, wantBlocks: len(data)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := HuffmanCompressBlocks(data, tt.blockSize)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			_, body, _ := unwrap(blob)
+			entries, _, size, err := readBlockIndex(body)
+			if err != nil {
+				t.Fatalf("unexpected index error: %v", err)
+			}
+			if len(entries) != tt.wantBlocks || size != uint64(len(data)) {
+				t.Errorf("expected %d blocks over %d bytes, got %d over %d", tt.wantBlocks, len(data), len(entries), size)
+			}
+			decompressed, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Errorf("decompressed output does not match original")
+			}
+			if err := HuffmanVerify(blob); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHuffmanDecompressRange(t *testing.T) {
+	data := blockFixture()
+	blob, err := HuffmanCompressBlocks(data, 100)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		start, length int64
+		want          []byte
+	}{
+		{name: "Inside one block", start: 10, length: 50, want: data[10:60]},
+		{name: "Whole block", start: 200, length: 100, want: data[200:300]},
+		{name: "Across a boundary", start: 95, length: 10, want: data[95:105]},
+		{name: "Across several blocks", start: 150, length: 520, want: data[150:670]},
+		{name: "Last byte", start: int64(len(data)) - 1, length: 1, want: data[len(data)-1:]},
+		{name: "Past the end", start: 880, length: 1000, want: data[880:]},
+		{name: "Empty at the end", start: int64(len(data)), length: 5, want: []byte{}},
+		{name: "Empty", start: 42, length: 0, want: []byte{}},
+		{name: "Everything", start: 0, length: int64(len(data)), want: data},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HuffmanDecompressRange(blob, tt.start, tt.length)
+			if err != nil {
+				t.Fatalf("unexpected range error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("range %d+%d: got %d bytes that do not match the original slice of %d", tt.start, tt.length, len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestHuffmanDecompressRangeSkipsOtherBlocks(t *testing.T) {
+	data := blockFixture()
+	blob, err := HuffmanCompressBlocks(data, 100)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	// Break the magic of the first block; ranges that avoid it still decode.
+	blob[containerHeaderSize] ^= 0xFF
+	got, err := HuffmanDecompressRange(blob, 150, 100)
+	if err != nil {
+		t.Fatalf("unexpected range error: %v", err)
+	}
+	if !bytes.Equal(got, data[150:250]) {
+		t.Error("range does not match the original slice")
+	}
+	if _, err := HuffmanDecompressRange(blob, 50, 100); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("expected ErrBadMagic from the damaged block, got %v", err)
+	}
+}
+
+func TestHuffmanDecompressRangeErrors(t *testing.T) {
+	data := blockFixture()
+	blob, err := HuffmanCompressBlocks(data, 100)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := HuffmanDecompressRange(blob, -1, 10); err == nil {
+		t.Error("expected error for a negative start but got nil")
+	}
+	if _, err := HuffmanDecompressRange(blob, 0, -1); err == nil {
+		t.Error("expected error for a negative length but got nil")
+	}
+	if _, err := HuffmanDecompressRange(blob, int64(len(data))+1, 1); err == nil {
+		t.Error("expected error for a start past the end but got nil")
+	}
+	plain := mustCompress(t, data)
+	if _, err := HuffmanDecompressRange(plain, 0, 1); !errors.Is(err, ErrWrongMode) {
+		t.Errorf("expected ErrWrongMode for a non-block blob, got %v", err)
+	}
+	if _, err := HuffmanCompressBlocks(data, -1); err == nil {
+		t.Error("expected error for a negative block size but got nil")
+	}
+	if _, err := HuffmanCompressBlocks(nil, 100); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+
+	// An index that claims more bytes than a block decodes to is corrupt.
+	lying := bytes.Clone(blob)
+	_, body, _ := unwrap(lying)
+	indexOffset := byteOrder.Uint64(body[len(body)-8:])
+	byteOrder.PutUint64(body[indexOffset+4:], 101)
+	if _, err := Decompress(lying); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a wrong block length, got %v", err)
+	}
+	if _, err := DecompressWithLimit(blob, len(data)-1); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestBlocksForgedIndexSize(t *testing.T) {
+	// A one-byte stored block whose index entry claims 1 GiB of output.
+	body := wrap(ModeStore, []byte("a"))
+	body = appendBlockIndex(body, []blockEntry{{rawLength: 1 << 30, length: uint64(len(body))}}, uint64(len(body)))
+	blob := wrap(ModeBlocks, body)
+
+	allocated := func(fn func() error) uint64 {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if err := fn(); !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("expected ErrCorruptStream for a forged block length, got %v", err)
+		}
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+	if n := allocated(func() error { _, err := Decompress(blob); return err }); n > 1<<20 {
+		t.Errorf("decompressing a %d-byte blob allocated %d bytes", len(blob), n)
+	}
+	if n := allocated(func() error { _, err := HuffmanDecompressRange(blob, 0, 1<<30); return err }); n > sizedPreallocLimit+1<<20 {
+		t.Errorf("decompressing a range of a %d-byte blob allocated %d bytes", len(blob), n)
+	}
+}
+
+func TestBlocksNested(t *testing.T) {
+	// nest wraps inner as the only block of a ModeBlocks blob.
+	nest := func(inner []byte, rawLength int) []byte {
+		body := appendBlockIndex(bytes.Clone(inner), []blockEntry{{rawLength: uint64(rawLength), length: uint64(len(inner))}}, uint64(len(inner)))
+		return wrap(ModeBlocks, body)
+	}
+	leaf := wrap(ModeStore, []byte("a"))
+	archive, err := HuffmanArchive([]ArchiveMember{{Name: "a", Data: []byte("a")}})
+	if err != nil {
+		t.Fatalf("unexpected archive error: %v", err)
+	}
+	commented, err := AddComment(nest(leaf, 1), "nested")
+	if err != nil {
+		t.Fatalf("unexpected comment error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{name: "Blocks in a block", blob: nest(nest(leaf, 1), 1)},
+		{name: "Comment in a block", blob: nest(commented, 1)},
+		{name: "Archive in a block", blob: nest(archive, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decompress(tt.blob); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("Decompress: expected ErrCorruptStream, got %v", err)
+			}
+			if _, err := HuffmanDecompressRange(tt.blob, 0, 1); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("HuffmanDecompressRange: expected ErrCorruptStream, got %v", err)
+			}
+			if err := HuffmanVerify(tt.blob); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("HuffmanVerify: expected ErrCorruptStream, got %v", err)
+			}
+		})
+	}
+}
+
+func TestForEachBlockSingleCore(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	if got := blockWorkers(); got != 1 {
+		t.Fatalf("expected 1 worker under GOMAXPROCS=1, got %d", got)
+	}
+
+	// The sequential path runs fn on the calling goroutine, in order, so
+	// the goroutine count never rises while it runs.
+	base := runtime.NumGoroutine()
+	var order []int
+	err := forEachBlock(8, blockWorkers(), func(i int) error {
+		if n := runtime.NumGoroutine(); n > base {
+			t.Errorf("block %d: %d goroutines running, expected at most %d", i, n, base)
+		}
+		order = append(order, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("expected blocks in order, got %v", order)
+		}
+	}
+
+	// With several workers the lowest failing block's error wins.
+	errBlock := func(i int) error { return fmt.Errorf("block %d", i) }
+	err = forEachBlock(8, 4, func(i int) error {
+		if i >= 3 {
+			return errBlock(i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "block 3" {
+		t.Errorf("expected the error of block 3, got %v", err)
+	}
+}
+
+func TestBlocksWorkersMatch(t *testing.T) {
+	data := blockFixture()
+	want, err := compressBlocks(data, 512, 1)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	for _, workers := range []int{1, 2, 8} {
+		got, err := compressBlocks(data, 512, workers)
+		if err != nil {
+			t.Fatalf("%d workers: unexpected compress error: %v", workers, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%d workers: output differs from the sequential path", workers)
+		}
+		out, err := decodeBlocks(want[containerHeaderSize:], DefaultMaxDecompressedSize, true, workers)
+		if err != nil {
+			t.Fatalf("%d workers: unexpected decode error: %v", workers, err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("%d workers: decoded output does not match original", workers)
+		}
+	}
+
+	// A damaged block is reported the same way however many workers run.
+	bad := bytes.Clone(want)
+	bad[containerHeaderSize+4] ^= 0xFF
+	_, seqErr := decodeBlocks(bad[containerHeaderSize:], DefaultMaxDecompressedSize, true, 1)
+	_, parErr := decodeBlocks(bad[containerHeaderSize:], DefaultMaxDecompressedSize, true, 8)
+	if seqErr == nil || parErr == nil || seqErr.Error() != parErr.Error() {
+		t.Errorf("expected matching errors, got %v and %v", seqErr, parErr)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !js && !wasip1
+
+package huffman
+
+import (
+	"go/build"
+	"slices"
+	"testing"
+)
+
+// TestCoreHasNoOSDependency checks that the package, as seen by a js/wasm
+// build, does not import os.
+func TestCoreHasNoOSDependency(t *testing.T) {
+	for _, target := range []struct{ goos, goarch string }{
+		{"js", "wasm"},
+		{"wasip1", "wasm"},
+	} {
+		ctx := build.Default
+		ctx.GOOS = target.goos
+		ctx.GOARCH = target.goarch
+		ctx.CgoEnabled = false
+
+		pkg, err := ctx.ImportDir(".", 0)
+		if err != nil {
+			t.Fatalf("%s/%s: import failed: %v", target.goos, target.goarch, err)
+		}
+		if slices.Contains(pkg.Imports, "os") {
+			t.Errorf("%s/%s: core package imports os via %v", target.goos, target.goarch, pkg.GoFiles)
+		}
+		if slices.Contains(pkg.GoFiles, "file.go") {
+			t.Errorf("%s/%s: file.go should be excluded by its build constraint", target.goos, target.goarch)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package huffman
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// DecompressCache remembers the output of recently decompressed blobs, so a
+// service decoding the same popular blobs repeatedly decodes each only once.
+// Entries are keyed by the SHA-256 of the blob and evicted least recently
+// used first once their outputs add up to more than the cache's size limit.
+// A DecompressCache is safe for concurrent use.
+type DecompressCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	order    *list.List // of *cacheEntry, most recently used first
+	entries  map[[sha256.Size]byte]*list.Element
+	hits     int64
+	misses   int64
+}
+
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	data []byte
+}
+
+// CacheStats reports the state of a DecompressCache.
+type CacheStats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+	Bytes   int // total size of the cached outputs
+}
+
+// NewDecompressCache returns a cache holding at most maxBytes of
+// decompressed output. Outputs larger than maxBytes are never cached, so a
+// cache with maxBytes <= 0 stores nothing.
+func NewDecompressCache(maxBytes int) *DecompressCache {
+	return &DecompressCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Decompress returns HuffmanDecompress(blob), from the cache if an identical
+// blob was decompressed recently. Callers get their own copy of the output
+// and may modify it. Errors are not cached.
+// Time Complexity: O(n) on a hit, O(n + m log m) on a miss, Space Complexity: O(n)
+func (c *DecompressCache) Decompress(blob []byte) ([]byte, error) {
+	key := sha256.Sum256(blob)
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		c.hits++
+		data := bytes.Clone(el.Value.(*cacheEntry).data)
+		c.mu.Unlock()
+		return data, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Decode without holding the lock, so a slow miss does not stall hits.
+	data, err := HuffmanDecompress(blob)
+	if err != nil {
+		return nil, err
+	}
+	c.add(key, data)
+	return data, nil
+}
+
+// add caches a copy of data under key, evicting old entries to stay within
+// the size limit.
+func (c *DecompressCache) add(key [sha256.Size]byte, data []byte) {
+	if c.maxBytes <= 0 || len(data) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		// A concurrent miss on the same blob got here first.
+		return
+	}
+	for c.size+len(data) > c.maxBytes {
+		oldest := c.order.Back()
+		e := c.order.Remove(oldest).(*cacheEntry)
+		delete(c.entries, e.key)
+		c.size -= len(e.data)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, data: bytes.Clone(data)})
+	c.size += len(data)
+}
+
+// Stats reports the cache's hit and miss counts and current contents.
+func (c *DecompressCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len(), Bytes: c.size}
+}
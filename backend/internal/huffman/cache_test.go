@@ -0,0 +1,116 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestDecompressCache(t *testing.T) {
+	content := bytes.Repeat([]byte("popular archive contents. "), 10)
+	blob := mustCompress(t, content)
+	cache := NewDecompressCache(1 << 20)
+
+	first, err := cache.Decompress(blob)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	// Modifying a returned slice must not reach later hits.
+	first[0] ^= 0xFF
+	second, err := cache.Decompress(bytes.Clone(blob))
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(second, content) {
+		t.Errorf("cached output does not match original")
+	}
+	if got, want := cache.Stats(), (CacheStats{Hits: 1, Misses: 1, Entries: 1, Bytes: len(content)}); got != want {
+		t.Errorf("expected stats %+v, got %+v", want, got)
+	}
+
+	if _, err := cache.Decompress(blob[:len(blob)-1]); err == nil {
+		t.Error("expected error for a truncated blob but got nil")
+	}
+	if got := cache.Stats().Entries; got != 1 {
+		t.Errorf("expected the failed blob not to be cached, got %d entries", got)
+	}
+}
+
+func TestDecompressCacheEviction(t *testing.T) {
+	blobs := make([][]byte, 4)
+	for i := range blobs {
+		blobs[i] = mustCompress(t, bytes.Repeat([]byte{byte('a' + i), 'z'}, 50)) // 100 bytes each
+	}
+	cache := NewDecompressCache(300)
+	for _, i := range []int{0, 1, 2} {
+		if _, err := cache.Decompress(blobs[i]); err != nil {
+			t.Fatalf("unexpected decompress error: %v", err)
+		}
+	}
+	// Touch blob 0, so blob 1 is now the least recently used.
+	if _, err := cache.Decompress(blobs[0]); err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if _, err := cache.Decompress(blobs[3]); err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if stats := cache.Stats(); stats.Entries != 3 || stats.Bytes != 300 {
+		t.Errorf("expected 3 entries of 300 bytes past the limit, got %+v", stats)
+	}
+
+	for _, tt := range []struct {
+		blob    int
+		wantHit bool
+	}{{0, true}, {2, true}, {3, true}, {1, false}} {
+		before := cache.Stats().Hits
+		if _, err := cache.Decompress(blobs[tt.blob]); err != nil {
+			t.Fatalf("unexpected decompress error: %v", err)
+		}
+		if hit := cache.Stats().Hits > before; hit != tt.wantHit {
+			t.Errorf("blob %d: expected hit = %v", tt.blob, tt.wantHit)
+		}
+	}
+
+	tiny := NewDecompressCache(50)
+	if _, err := tiny.Decompress(blobs[0]); err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if got := tiny.Stats().Entries; got != 0 {
+		t.Errorf("expected an output over the limit not to be cached, got %d entries", got)
+	}
+}
+
+func TestDecompressCacheConcurrent(t *testing.T) {
+	cache := NewDecompressCache(1 << 20)
+	var blobs, contents [][]byte
+	for i := 0; i < 8; i++ {
+		content := []byte(fmt.Sprintf("blob %d: %s", i, bytes.Repeat([]byte("x"), i*10)))
+		contents = append(contents, content)
+		blobs = append(blobs, mustCompress(t, content))
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				n := (g + i) % len(blobs)
+				got, err := cache.Decompress(blobs[n])
+				if err != nil {
+					t.Errorf("unexpected decompress error: %v", err)
+					return
+				}
+				if !bytes.Equal(got, contents[n]) {
+					t.Errorf("blob %d: decompressed output does not match original", n)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if stats := cache.Stats(); stats.Entries != len(blobs) || stats.Hits+stats.Misses != 800 {
+		t.Errorf("unexpected stats after concurrent use: %+v", stats)
+	}
+}
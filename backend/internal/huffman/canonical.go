@@ -0,0 +1,298 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// MaxCodeLength is the largest code length accepted by the length-limited
+// codec.
+const MaxCodeLength = 32
+
+// symbolLength pairs a symbol with its code length.
+type symbolLength struct {
+	sym    byte
+	length uint8
+}
+
+// limitCodeLengths derives code lengths from freq, capped at maxLen. Lengths
+// from an unrestricted Huffman tree are kept when they already fit; otherwise
+// the length counts are rebalanced as in JPEG (ITU T.81 Annex K.3) and
+// reassigned so the most frequent symbols get the shortest codes.
+// Time Complexity: O(m log m + L^2), Space Complexity: O(m)
+func limitCodeLengths(freq map[byte]int, maxLen int) []symbolLength {
+	lengths := make(map[byte]int)
+	codeLengths(buildHuffmanTree(freq), 0, lengths)
+
+	longest := 0
+	for _, l := range lengths {
+		longest = max(longest, l)
+	}
+	out := make([]symbolLength, 0, len(lengths))
+	if longest <= maxLen {
+		for b, l := range lengths {
+			out = append(out, symbolLength{sym: b, length: uint8(l)})
+		}
+		return out
+	}
+
+	counts := make([]int, longest+1)
+	for _, l := range lengths {
+		counts[l]++
+	}
+	for i := longest; i > maxLen; i-- {
+		for counts[i] > 0 {
+			// Move a pair of leaves up: one becomes the sibling of a leaf
+			// that is pushed down from the deepest non-empty shorter level.
+			j := i - 2
+			for counts[j] == 0 {
+				j--
+			}
+			counts[i] -= 2
+			counts[i-1]++
+			counts[j+1] += 2
+			counts[j]--
+		}
+	}
+
+	// Hand out the rebalanced lengths shortest first to the most frequent
+	// symbols, breaking ties by symbol value to stay deterministic.
+	symbols := make([]byte, 0, len(freq))
+	for b := range freq {
+		symbols = append(symbols, b)
+	}
+	slices.SortFunc(symbols, func(a, b byte) int {
+		if freq[a] != freq[b] {
+			return freq[b] - freq[a]
+		}
+		return int(a) - int(b)
+	})
+	l := 1
+	for _, b := range symbols {
+		for counts[l] == 0 {
+			l++
+		}
+		counts[l]--
+		out = append(out, symbolLength{sym: b, length: uint8(l)})
+	}
+	return out
+}
+
+// assignCanonicalCodes fills table with canonical codes for lengths: symbols
+// are ordered by (length, symbol) and given consecutive code values.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func assignCanonicalCodes(lengths []symbolLength, table *codeTable) {
+	sorted := slices.Clone(lengths)
+	slices.SortFunc(sorted, func(a, b symbolLength) int {
+		if a.length != b.length {
+			return int(a.length) - int(b.length)
+		}
+		return int(a.sym) - int(b.sym)
+	})
+	var next uint64
+	var prevLen uint8
+	for i, sl := range sorted {
+		if i > 0 {
+			next = (next + 1) << (sl.length - prevLen)
+		}
+		table[sl.sym] = code{bits: next, length: sl.length}
+		prevLen = sl.length
+	}
+}
+
+// validateKraft checks that lengths describe a complete prefix code, or a
+// single one-bit code for a lone symbol, so the rebuilt tree has no dangling
+// branches for the decoder to fall into.
+// Time Complexity: O(m), Space Complexity: O(1)
+func validateKraft(lengths []symbolLength) error {
+	if len(lengths) == 1 {
+		if lengths[0].length != 1 {
+			return corruptf("invalid code lengths: lone symbol has length %d", lengths[0].length)
+		}
+		return nil
+	}
+	var sum uint64
+	for _, sl := range lengths {
+		sum += 1 << (MaxCodeLength - uint(sl.length))
+	}
+	if sum != 1<<MaxCodeLength {
+		return corruptf("invalid code lengths: Kraft sum %d/%d is not complete", sum, uint64(1)<<MaxCodeLength)
+	}
+	return nil
+}
+
+// buildCanonicalTree rebuilds a decoding tree from a canonical code table.
+// Time Complexity: O(m * L), Space Complexity: O(m * L)
+func buildCanonicalTree(lengths []symbolLength, table *codeTable) (*Node, error) {
+	root := &Node{}
+	leaves := make(map[*Node]bool, len(lengths))
+	for _, sl := range lengths {
+		c := table[sl.sym]
+		node := root
+		for i := int(c.length) - 1; i >= 0; i-- {
+			if leaves[node] {
+				return nil, corruptf("invalid code lengths: code for 0x%02x is not prefix-free", sl.sym)
+			}
+			next := &node.Left
+			if (c.bits>>uint(i))&1 == 1 {
+				next = &node.Right
+			}
+			if *next == nil {
+				*next = &Node{}
+			}
+			node = *next
+		}
+		if leaves[node] || node.Left != nil || node.Right != nil {
+			return nil, corruptf("invalid code lengths: code for 0x%02x is not prefix-free", sl.sym)
+		}
+		node.Char = sl.sym
+		leaves[node] = true
+	}
+	return root, nil
+}
+
+// HuffmanCompressLimited builds a ModeCanonical blob whose code lengths never
+// exceed maxCodeLength (1 to MaxCodeLength). The header stores each symbol's
+// code length rather than its frequency, and codes are assigned canonically.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressLimited(data []byte, maxCodeLength int) ([]byte, error) {
+	if maxCodeLength < 1 || maxCodeLength > MaxCodeLength {
+		return nil, fmt.Errorf("max code length %d outside 1-%d", maxCodeLength, MaxCodeLength)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	freq := buildFrequencyTable(data)
+	if len(freq) > 1<<maxCodeLength {
+		return nil, fmt.Errorf("%d symbols cannot fit in codes of at most %d bits", len(freq), maxCodeLength)
+	}
+	return writeCanonicalBlob(data, freq, limitCodeLengths(freq, maxCodeLength), maxCodeLength)
+}
+
+// ExportCanonicalLengths returns the code length of every byte value in the
+// code HuffmanCompressBytes builds for data, indexed by symbol, with 0 for
+// absent symbols. This is the interchange form of DEFLATE and JPEG: giving
+// consecutive code values to symbols ordered by (length, symbol) rebuilds a
+// canonical code with the same lengths, as ModeCanonical does. Lengths are
+// not capped, so they may exceed DEFLATE's 15 bits on skewed input; a
+// single-symbol input has the one length 1.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func ExportCanonicalLengths(data []byte) ([]uint8, error) {
+	lengths, err := CodeLengths(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint8, 256)
+	for b, l := range lengths {
+		out[b] = uint8(l)
+	}
+	return out, nil
+}
+
+// writeCanonicalBlob encodes data as a ModeCanonical blob with canonical
+// codes for lengths, none of which may exceed maxCodeLength.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func writeCanonicalBlob(data []byte, freq map[byte]int, lengths []symbolLength, maxCodeLength int) ([]byte, error) {
+	var table codeTable
+	assignCanonicalCodes(lengths, &table)
+
+	totalBits := 0
+	for b, f := range freq {
+		totalBits += f * int(table[b].length)
+	}
+
+	var out bytes.Buffer
+	out.Grow(containerHeaderSize + 1 + 2 + 2*len(lengths) + 8 + (totalBits+7)/8)
+	writeContainerHeader(&out, ModeCanonical)
+	out.WriteByte(byte(maxCodeLength))
+	if err := binary.Write(&out, byteOrder, uint16(len(lengths))); err != nil {
+		return nil, err
+	}
+	for b := 0; b < 256; b++ {
+		if table[b].length > 0 {
+			out.WriteByte(byte(b))
+			out.WriteByte(table[b].length)
+		}
+	}
+	if err := binary.Write(&out, byteOrder, uint64(totalBits)); err != nil {
+		return nil, err
+	}
+	if _, err := encodeDataWithCount(&out, data, &table, nil); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// decodeCanonicalBody reverses HuffmanCompressLimited for a ModeCanonical body.
+// strict is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeCanonicalBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	root, totalBits, bitData, err := readCanonicalBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPayload(bitData, totalBits, strict); err != nil {
+		return nil, err
+	}
+	return decodeBits(root, bitData, totalBits, maxSize, strict)
+}
+
+// readCanonicalBody parses the code lengths and bit length of a ModeCanonical
+// body, returning the canonical tree, the bit count and the payload.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readCanonicalBody(body []byte) (*Node, uint64, []byte, error) {
+	r := bytes.NewReader(body)
+	limit, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, nil, corruptf("read max code length failed: %w", err)
+	}
+	if limit < 1 || limit > MaxCodeLength {
+		return nil, 0, nil, corruptf("invalid header: max code length %d outside 1-%d", limit, MaxCodeLength)
+	}
+	var numEntries uint16
+	if err := binary.Read(r, byteOrder, &numEntries); err != nil {
+		return nil, 0, nil, corruptf("read header entries failed: %w", err)
+	}
+	if numEntries == 0 || numEntries > 256 {
+		return nil, 0, nil, corruptf("invalid header: %d entries", numEntries)
+	}
+	lengths := make([]symbolLength, 0, numEntries)
+	var seen [256]bool
+	for i := 0; i < int(numEntries); i++ {
+		var entry [2]byte
+		if _, err := io.ReadFull(r, entry[:]); err != nil {
+			return nil, 0, nil, corruptf("read header entry failed: %w", err)
+		}
+		if seen[entry[0]] {
+			return nil, 0, nil, corruptf("invalid header: duplicate symbol 0x%02x", entry[0])
+		}
+		if entry[1] < 1 || entry[1] > limit {
+			return nil, 0, nil, corruptf("invalid header: code length %d for 0x%02x outside 1-%d", entry[1], entry[0], limit)
+		}
+		seen[entry[0]] = true
+		lengths = append(lengths, symbolLength{sym: entry[0], length: entry[1]})
+	}
+	var totalBits uint64
+	if err := binary.Read(r, byteOrder, &totalBits); err != nil {
+		return nil, 0, nil, corruptf("read bit length failed: %w", err)
+	}
+
+	if err := validateKraft(lengths); err != nil {
+		return nil, 0, nil, err
+	}
+	var table codeTable
+	assignCanonicalCodes(lengths, &table)
+	root, err := buildCanonicalTree(lengths, &table)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if len(lengths) == 1 {
+		// A lone symbol's one-bit code is a leaf hanging off the root; decode
+		// it like any single-symbol tree.
+		root = root.Left
+	}
+	return root, totalBits, body[len(body)-r.Len():], nil
+}
@@ -0,0 +1,195 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// fibonacciData returns data whose i-th symbol occurs fib(i) times, the
+// classic distribution that maximizes Huffman tree depth.
+func fibonacciData(symbols int) []byte {
+	var data []byte
+	a, b := 1, 1
+	for i := 0; i < symbols; i++ {
+		data = append(data, bytes.Repeat([]byte{byte(i)}, a)...)
+		a, b = b, a+b
+	}
+	return data
+}
+
+func maxLength(t *testing.T, lengths []symbolLength) int {
+	t.Helper()
+	longest := 0
+	for _, sl := range lengths {
+		longest = max(longest, int(sl.length))
+	}
+	return longest
+}
+
+func TestLimitCodeLengthsFibonacci(t *testing.T) {
+	data := fibonacciData(24)
+	freq := buildFrequencyTable(data)
+
+	unlimited := make(map[byte]int)
+	codeLengths(buildHuffmanTree(freq), 0, unlimited)
+	deepest := 0
+	for _, l := range unlimited {
+		deepest = max(deepest, l)
+	}
+	if deepest <= 15 {
+		t.Fatalf("test distribution too shallow: unlimited depth %d", deepest)
+	}
+
+	for _, limit := range []int{5, 8, 15} {
+		lengths := limitCodeLengths(freq, limit)
+		if got := maxLength(t, lengths); got > limit {
+			t.Errorf("limit %d: longest code is %d bits", limit, got)
+		}
+		if len(lengths) != len(freq) {
+			t.Errorf("limit %d: expected %d symbols, got %d", limit, len(freq), len(lengths))
+		}
+		if err := validateKraft(lengths); err != nil {
+			t.Errorf("limit %d: %v", limit, err)
+		}
+	}
+}
+
+func TestHuffmanCompressLimited(t *testing.T) {
+	rng := rand.New(rand.NewSource(12))
+	random := make([]byte, 4096)
+	rng.Read(random)
+
+	tests := []struct {
+		name    string
+		content []byte
+		limit   int
+	}{
+		{name: "Fibonacci capped at 15", content: fibonacciData(24), limit: 15},
+		{name: "Fibonacci capped at 8", content: fibonacciData(24), limit: 8},
+		{name: "Within limit", content: []byte("aaaaabbbbcccdde"), limit: 15},
+		{name: "Single symbol", content: []byte("zzzz"), limit: 1},
+		{name: "Random full alphabet", content: random, limit: 8},
+		{name: "Max limit", content: fibonacciData(26), limit: MaxCodeLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := HuffmanCompressLimited(tt.content, tt.limit)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			decompressed, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressLimitedInvalid(t *testing.T) {
+	if _, err := HuffmanCompressLimited([]byte("abc"), 0); err == nil {
+		t.Error("expected error for zero limit but got nil")
+	}
+	if _, err := HuffmanCompressLimited([]byte("abc"), MaxCodeLength+1); err == nil {
+		t.Error("expected error for limit above MaxCodeLength but got nil")
+	}
+	if _, err := HuffmanCompressLimited([]byte("abc"), 1); err == nil {
+		t.Error("expected error when the alphabet cannot fit the limit but got nil")
+	}
+
+	// Two symbols both claiming length 2 leave half the code space unused.
+	incomplete := wrap(ModeCanonical, []byte{15, 2, 0, 'a', 2, 'b', 2, 4, 0, 0, 0, 0, 0, 0, 0, 0x40})
+	if _, err := Decompress(incomplete); err == nil {
+		t.Error("expected error for incomplete code lengths but got nil")
+	}
+}
+
+func TestExportCanonicalLengths(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Single symbol", data: []byte("aaaa")},
+		{name: "Text", data: []byte("the quick brown fox jumps over the lazy dog")},
+		{name: "Fibonacci", data: fibonacciData(20)},
+		{name: "All bytes", data: func() []byte {
+			data := make([]byte, 256*3)
+			for i := range data {
+				data[i] = byte(i * 7 / 3)
+			}
+			return data
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lengths, err := ExportCanonicalLengths(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected export error: %v", err)
+			}
+			if len(lengths) != 256 {
+				t.Fatalf("expected 256 lengths, got %d", len(lengths))
+			}
+			want, err := CodeLengths(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected code length error: %v", err)
+			}
+			var present []symbolLength
+			for b, l := range lengths {
+				if int(l) != want[byte(b)] {
+					t.Errorf("symbol 0x%02x: expected length %d, got %d", b, want[byte(b)], l)
+				}
+				if l > 0 {
+					present = append(present, symbolLength{sym: byte(b), length: l})
+				}
+			}
+
+			// The lengths form a complete prefix code: their Kraft sum is
+			// exactly one, barring the lone one-bit code of a single symbol.
+			if err := validateKraft(present); err != nil {
+				t.Errorf("unexpected Kraft error: %v", err)
+			}
+
+			// Rebuilding canonical codes from the lengths alone gives a
+			// prefix-free code of the same lengths that round-trips data.
+			var table codeTable
+			assignCanonicalCodes(present, &table)
+			root, err := buildCanonicalTree(present, &table)
+			if err != nil {
+				t.Fatalf("unexpected tree error: %v", err)
+			}
+			codes := make(map[byte]string, len(present))
+			for _, sl := range present {
+				c := table[sl.sym]
+				if int(c.length) != int(sl.length) {
+					t.Errorf("symbol 0x%02x: canonical code has length %d, want %d", sl.sym, c.length, sl.length)
+				}
+				codes[sl.sym] = fmt.Sprintf("%0*b", c.length, c.bits)
+			}
+			packed, totalBits, err := Encode(tt.data, codes)
+			if err != nil {
+				t.Fatalf("unexpected encode error: %v", err)
+			}
+			if len(present) == 1 {
+				root = root.Left
+			}
+			got, err := Decode(packed, totalBits, root)
+			if err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Error("canonical codes did not round-trip the input")
+			}
+		})
+	}
+
+	if _, err := ExportCanonicalLengths(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package huffman
+
+import (
+	"fmt"
+	"io"
+)
+
+// checkpointMagic starts every checkpoint written by SaveCheckpoint. It
+// differs from the blob magic, so a checkpoint is never mistaken for one.
+const checkpointMagic = "HUFK"
+
+// maxCheckpointSize bounds what LoadCheckpoint reads: the magic, version
+// and a frequency table of all 256 symbols.
+const maxCheckpointSize = len(checkpointMagic) + 1 + 2 + 5*256
+
+// SaveCheckpoint writes freq, the result of the counting pass, to w as a
+// small sidecar, so that an interrupted compression of a large input can
+// resume with HuffmanCompressResume instead of reading it all again. The
+// table must be valid, with every count fitting in 32 bits.
+// Time Complexity: O(m), Space Complexity: O(m)
+func SaveCheckpoint(freq FrequencyTable, w io.Writer) error {
+	head, err := freq.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf := append([]byte(checkpointMagic), FormatVersion)
+	if _, err := w.Write(append(buf, head...)); err != nil {
+		return fmt.Errorf("write checkpoint failed: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint reads a frequency table written by SaveCheckpoint.
+// Time Complexity: O(m), Space Complexity: O(m)
+func LoadCheckpoint(r io.Reader) (FrequencyTable, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxCheckpointSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint failed: %w", err)
+	}
+	prefix := len(checkpointMagic) + 1
+	if len(data) < prefix {
+		return nil, corruptf("read checkpoint failed: %d bytes is too short", len(data))
+	}
+	if string(data[:len(checkpointMagic)]) != checkpointMagic {
+		return nil, fmt.Errorf("%w %q", ErrBadMagic, data[:len(checkpointMagic)])
+	}
+	if v := data[len(checkpointMagic)]; v != FormatVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, v)
+	}
+	if len(data) > maxCheckpointSize {
+		return nil, corruptf("invalid checkpoint: longer than %d bytes", maxCheckpointSize)
+	}
+	var freq FrequencyTable
+	if err := freq.UnmarshalBinary(data[prefix:]); err != nil {
+		return nil, err
+	}
+	return freq, nil
+}
+
+// CountReaderAt counts the first size bytes of r, the first of the two
+// passes HuffmanCompressReaderAt makes. Save the table with SaveCheckpoint
+// to make the second pass resumable.
+// Time Complexity: O(n), Space Complexity: O(m)
+func CountReaderAt(r io.ReaderAt, size int64) (FrequencyTable, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid input size %d", size)
+	}
+	return countReaderAt(r, size)
+}
+
+// HuffmanCompressResume runs the second pass of HuffmanCompressReaderAt
+// with freq, typically loaded by LoadCheckpoint, in place of the first. The
+// output is identical to that of an uninterrupted HuffmanCompressReaderAt.
+// freq must be the table of the first size bytes of r: a total other than
+// size is rejected up front, and input whose counts turn out to differ
+// fails once it has been read, its output unusable. It returns the number
+// of bytes written to w.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func HuffmanCompressResume(r io.ReaderAt, size int64, freq FrequencyTable, w io.Writer) (int64, error) {
+	if size < 0 {
+		return 0, fmt.Errorf("invalid input size %d", size)
+	}
+	if size == 0 || len(freq) == 0 {
+		return 0, ErrEmptyInput
+	}
+	if err := validateFrequencyTable(freq); err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	for _, f := range freq {
+		total += int64(f)
+	}
+	if total != size {
+		return 0, fmt.Errorf("checkpoint counts %d bytes, input has %d", total, size)
+	}
+	return encodeReaderAt(r, size, freq, w, nil)
+}
@@ -0,0 +1,142 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// failingWriter accepts n bytes and then fails, standing in for a job
+// killed partway through writing its output.
+type failingWriter struct {
+	w io.Writer
+	n int
+}
+
+var errInterrupted = errors.New("interrupted")
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.n {
+		n, _ := f.w.Write(p[:f.n])
+		f.n = 0
+		return n, errInterrupted
+	}
+	f.n -= len(p)
+	return f.w.Write(p)
+}
+
+func TestCheckpointResume(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Single symbol", content: []byte("zzzzzz")},
+		{name: "Text", content: []byte(strings.Repeat("resume where you left off. ", 300))},
+		{name: "Multiple chunks", content: bytes.Repeat([]byte("0123456789abcdef"), readerAtChunk/5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := int64(len(tt.content))
+			var want bytes.Buffer
+			if _, err := HuffmanCompressReaderAt(bytes.NewReader(tt.content), size, &want); err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+
+			// Pass one, then a checkpoint, then a second pass that dies
+			// partway through its output.
+			freq, err := CountReaderAt(bytes.NewReader(tt.content), size)
+			if err != nil {
+				t.Fatalf("unexpected count error: %v", err)
+			}
+			var sidecar bytes.Buffer
+			if err := SaveCheckpoint(freq, &sidecar); err != nil {
+				t.Fatalf("unexpected checkpoint error: %v", err)
+			}
+			var partial bytes.Buffer
+			if _, err := HuffmanCompressResume(bytes.NewReader(tt.content), size, freq, &failingWriter{w: &partial, n: want.Len() / 2}); !errors.Is(err, errInterrupted) {
+				t.Fatalf("expected the interrupted write to fail, got %v", err)
+			}
+
+			// A fresh run resumes from the sidecar alone.
+			loaded, err := LoadCheckpoint(bytes.NewReader(sidecar.Bytes()))
+			if err != nil {
+				t.Fatalf("unexpected load error: %v", err)
+			}
+			var got bytes.Buffer
+			n, err := HuffmanCompressResume(bytes.NewReader(tt.content), size, loaded, &got)
+			if err != nil {
+				t.Fatalf("unexpected resume error: %v", err)
+			}
+			if n != int64(got.Len()) {
+				t.Errorf("reported %d bytes written, wrote %d", n, got.Len())
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Error("resumed output differs from an uninterrupted run")
+			}
+		})
+	}
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	var sidecar bytes.Buffer
+	if err := SaveCheckpoint(CountFrequencies([]byte("abcabc")), &sidecar); err != nil {
+		t.Fatalf("unexpected checkpoint error: %v", err)
+	}
+	valid := sidecar.Bytes()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "Empty", data: nil, wantErr: ErrCorruptStream},
+		{name: "Blob magic", data: mustCompress(t, []byte("abcabc")), wantErr: ErrBadMagic},
+		{name: "Wrong version", data: append([]byte(checkpointMagic), FormatVersion+1, 0, 0), wantErr: ErrUnsupportedVersion},
+		{name: "Truncated table", data: valid[:len(valid)-2], wantErr: ErrCorruptStream},
+		{name: "Trailing bytes", data: append(bytes.Clone(valid), 0), wantErr: ErrCorruptStream},
+		{name: "Oversized", data: append(bytes.Clone(valid), make([]byte, maxCheckpointSize)...), wantErr: ErrCorruptStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadCheckpoint(bytes.NewReader(tt.data)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressResumeMismatch(t *testing.T) {
+	content := []byte("the original input")
+	freq := CountFrequencies(content)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		size    int64
+		freq    FrequencyTable
+		wantErr error
+	}{
+		{name: "Empty table", input: content, size: int64(len(content)), freq: FrequencyTable{}, wantErr: ErrEmptyInput},
+		{name: "Zero size", input: content, size: 0, freq: freq, wantErr: ErrEmptyInput},
+		{name: "Negative size", input: content, size: -1, freq: freq},
+		{name: "Size differs", input: content, size: int64(len(content)) - 1, freq: freq},
+		{name: "New symbol", input: []byte("the original inpuX"), size: int64(len(content)), freq: freq},
+		{name: "Counts differ", input: []byte("the originnl input"), size: int64(len(content)), freq: freq},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HuffmanCompressResume(bytes.NewReader(tt.input), tt.size, tt.freq, io.Discard)
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxTreeDepth bounds the trees Decode accepts, as packed codes are at most
+// 64 bits.
+const maxTreeDepth = 64
+
+// maxTreeNodes is the size of a tree with a leaf for every byte value.
+// Decode accepts no larger tree, which also stops one with a cycle from
+// being walked forever.
+const maxTreeNodes = 2*256 - 1
+
+// Encode packs the code of every byte of data, most significant bit first,
+// with no header or framing, and returns the packed bytes and the number of
+// meaningful bits in them; the final byte is padded with zero bits. codes
+// maps each symbol to its code as a string of '0' and '1', as
+// HuffmanCompressVerbose reports them. The codes must be non-empty, at most
+// 64 bits and prefix-free, and every byte of data must have one.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func Encode(data []byte, codes map[byte]string) (packed []byte, totalBits int, err error) {
+	var table codeTable
+	for b, s := range codes {
+		c, err := parseCode(s)
+		if err != nil {
+			return nil, 0, fmt.Errorf("code for symbol 0x%02x: %w", b, err)
+		}
+		table[b] = c
+	}
+	if err := checkPrefixFree(codes); err != nil {
+		return nil, 0, err
+	}
+	var buf bytes.Buffer
+	totalBits, err = encodeDataWithCount(&buf, data, &table, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), totalBits, nil
+}
+
+// parseCode converts a code written as '0' and '1' characters to its packed
+// form.
+func parseCode(s string) (code, error) {
+	if s == "" || len(s) > maxTreeDepth {
+		return code{}, fmt.Errorf("code %q must be 1-%d bits long", s, maxTreeDepth)
+	}
+	var c code
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '0':
+			c.bits <<= 1
+		case '1':
+			c.bits = c.bits<<1 | 1
+		default:
+			return code{}, fmt.Errorf("code %q contains %q, want only '0' and '1'", s, s[i])
+		}
+	}
+	c.length = uint8(len(s))
+	return c, nil
+}
+
+// checkPrefixFree reports a code that is a prefix of another, which would
+// make the packed stream ambiguous. After sorting, any code that is a prefix
+// of another is also a prefix of the code right after it.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func checkPrefixFree(codes map[byte]string) error {
+	symbols := make([]byte, 0, len(codes))
+	for b := range codes {
+		symbols = append(symbols, b)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return codes[symbols[i]] < codes[symbols[j]] })
+	for i := 1; i < len(symbols); i++ {
+		prev, next := symbols[i-1], symbols[i]
+		if strings.HasPrefix(codes[next], codes[prev]) {
+			return fmt.Errorf("code %q for symbol 0x%02x is a prefix of code %q for symbol 0x%02x", codes[prev], prev, codes[next], next)
+		}
+	}
+	return nil
+}
+
+// Decode reverses Encode: it decodes the first totalBits bits of packed by
+// walking root, with no header or framing. A tree that is a single leaf
+// decodes every bit as that symbol, matching the one-bit code 0 it gets
+// when compressing. Every internal node of root must have two children, and
+// the tree at most 256 leaves. It fails if packed holds fewer than totalBits
+// bits, if the last code is cut off, or once the output would exceed
+// DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(n + m), Space Complexity: O(n + m)
+func Decode(packed []byte, totalBits int, root *Node) ([]byte, error) {
+	if root == nil {
+		return nil, fmt.Errorf("decode requires a tree")
+	}
+	if totalBits < 0 {
+		return nil, fmt.Errorf("invalid bit length %d", totalBits)
+	}
+	if err := checkExternalTree(root); err != nil {
+		return nil, err
+	}
+	if uint64(len(packed)) < (uint64(totalBits)+7)/8 {
+		return nil, truncatedError(uint64(len(packed))*8, uint64(totalBits))
+	}
+	return newDecodeTable(root, tableBitsFor(root, nil)).decode(nil, packed, uint64(totalBits), DefaultMaxDecompressedSize, true)
+}
+
+// checkTree reports a node with one child, or a tree deeper than
+// maxTreeDepth or with more than maxTreeNodes nodes, none of which the
+// decoders can walk. nodes counts the nodes visited so far.
+// Time Complexity: O(m), Space Complexity: O(depth)
+func checkTree(n *Node, depth int, nodes *int) error {
+	if depth > maxTreeDepth {
+		return fmt.Errorf("tree is deeper than %d levels", maxTreeDepth)
+	}
+	if *nodes++; *nodes > maxTreeNodes {
+		return fmt.Errorf("tree has more than %d nodes", maxTreeNodes)
+	}
+	if n.Left == nil && n.Right == nil {
+		return nil
+	}
+	if n.Left == nil || n.Right == nil {
+		return fmt.Errorf("tree node at depth %d has one child", depth)
+	}
+	if err := checkTree(n.Left, depth+1, nodes); err != nil {
+		return err
+	}
+	return checkTree(n.Right, depth+1, nodes)
+}
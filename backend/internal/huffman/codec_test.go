@@ -0,0 +1,188 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// manualTree builds the tree for the codes a=0, b=10, c=11 by hand.
+func manualTree() *Node {
+	return &Node{
+		Left: &Node{Char: 'a'},
+		Right: &Node{
+			Left:  &Node{Char: 'b'},
+			Right: &Node{Char: 'c'},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		codes     map[byte]string
+		root      *Node
+		wantBits  int
+		wantBytes []byte
+	}{
+		{
+			name:      "Manual codes",
+			data:      []byte("abcab"),
+			codes:     map[byte]string{'a': "0", 'b': "10", 'c': "11"},
+			root:      manualTree(),
+			wantBits:  8,
+			wantBytes: []byte{0b01011010},
+		},
+		{
+			name:      "Padded final byte",
+			data:      []byte("cc"),
+			codes:     map[byte]string{'a': "0", 'b': "10", 'c': "11"},
+			root:      manualTree(),
+			wantBits:  4,
+			wantBytes: []byte{0b11110000},
+		},
+		{
+			name:      "Single symbol",
+			data:      []byte("zzz"),
+			codes:     map[byte]string{'z': "0"},
+			root:      &Node{Char: 'z'},
+			wantBits:  3,
+			wantBytes: []byte{0b00000000},
+		},
+		{
+			name:     "Empty input",
+			data:     nil,
+			codes:    map[byte]string{'a': "0", 'b': "10", 'c': "11"},
+			root:     manualTree(),
+			wantBits: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packed, totalBits, err := Encode(tt.data, tt.codes)
+			if err != nil {
+				t.Fatalf("unexpected encode error: %v", err)
+			}
+			if totalBits != tt.wantBits || !bytes.Equal(packed, tt.wantBytes) {
+				t.Errorf("expected %d bits %08b, got %d bits %08b", tt.wantBits, tt.wantBytes, totalBits, packed)
+			}
+			got, err := Decode(packed, totalBits, tt.root)
+			if err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("expected %q, got %q", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeMatchesCompress(t *testing.T) {
+	data := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog ", 30))
+	blob, codes, err := HuffmanCompressVerbose(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	packed, totalBits, err := Encode(data, codes)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	_, blobBits, payload := splitBlob(t, blob)
+	if uint64(totalBits) != blobBits || !bytes.Equal(packed, payload) {
+		t.Error("packed codes differ from the blob payload")
+	}
+	got, err := Decode(packed, totalBits, buildHuffmanTree(buildFrequencyTable(data)))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("decoded output does not match original")
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		codes   map[byte]string
+		wantErr string
+	}{
+		{name: "Missing code", data: []byte("abd"), codes: map[byte]string{'a': "0", 'b': "1"}, wantErr: "symbol 0x64 at offset 2 has no code"},
+		{name: "Empty code", data: []byte("a"), codes: map[byte]string{'a': ""}, wantErr: "must be 1-64 bits"},
+		{name: "Too long", data: []byte("a"), codes: map[byte]string{'a': strings.Repeat("1", 65)}, wantErr: "must be 1-64 bits"},
+		{name: "Not binary", data: []byte("a"), codes: map[byte]string{'a': "012"}, wantErr: "contains '2'"},
+		{name: "Not prefix-free", data: []byte("ab"), codes: map[byte]string{'a': "1", 'b': "10"}, wantErr: "is a prefix of code"},
+		{name: "Duplicate code", data: []byte("ab"), codes: map[byte]string{'a': "01", 'b': "01"}, wantErr: "is a prefix of code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Encode(tt.data, tt.codes)
+			if err == nil {
+				t.Fatal("expected encode error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	deep := &Node{Char: 'x'}
+	for i := 0; i < 70; i++ {
+		deep = &Node{Left: deep, Right: &Node{Char: 'y'}}
+	}
+	cycle := &Node{Right: &Node{Char: 'a'}}
+	cycle.Left = cycle
+	level := make([]*Node, 300)
+	for i := range level {
+		level[i] = &Node{Char: byte(i)}
+	}
+	for len(level) > 1 {
+		var next []*Node
+		for i := 0; i+1 < len(level); i += 2 {
+			next = append(next, &Node{Left: level[i], Right: level[i+1]})
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		level = next
+	}
+
+	tests := []struct {
+		name      string
+		packed    []byte
+		totalBits int
+		root      *Node
+		wantErr   error
+		wantMsg   string
+	}{
+		{name: "Nil tree", packed: []byte{0}, totalBits: 1, wantMsg: "requires a tree"},
+		{name: "Negative bits", packed: []byte{0}, totalBits: -1, root: manualTree(), wantMsg: "invalid bit length"},
+		{name: "Bits past the data", packed: []byte{0}, totalBits: 9, root: manualTree(), wantErr: ErrTruncated},
+		{name: "Cut-off code", packed: []byte{0b10000000}, totalBits: 1, root: manualTree(), wantErr: ErrCorruptStream},
+		{name: "One child", packed: []byte{0}, totalBits: 1, root: &Node{Left: &Node{Char: 'a'}}, wantMsg: "has one child"},
+		{name: "Too deep", packed: []byte{0}, totalBits: 1, root: deep, wantMsg: "deeper than 64"},
+		{name: "Too many leaves", packed: []byte{0}, totalBits: 1, root: level[0], wantMsg: "more than 511 nodes"},
+		{name: "Cycle", packed: []byte{0}, totalBits: 1, root: cycle, wantMsg: "deeper than 64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.packed, tt.totalBits, tt.root)
+			if err == nil {
+				t.Fatal("expected decode error but got nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.wantMsg, err)
+			}
+		})
+	}
+}
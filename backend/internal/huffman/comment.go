@@ -0,0 +1,93 @@
+package huffman
+
+import "fmt"
+
+// MaxCommentLength is the longest comment AddComment accepts, in bytes.
+const MaxCommentLength = 255
+
+// AddComment wraps blob in a ModeComment container carrying comment, a short
+// label such as the creating tool or a user tag, which ReadComment returns.
+// The comment must be 1 to MaxCommentLength bytes of printable ASCII, so it
+// shows up legibly in a hex dump. The body is laid out as:
+//
+//	u8             comment length n
+//	n x u8         comment
+//	blob           the wrapped blob, container header included
+//
+// Decompress and HuffmanVerify see through the comment to the wrapped blob,
+// which may carry neither another comment nor a name; AddName the commented
+// blob instead.
+// Time Complexity: O(n), Space Complexity: O(n)
+func AddComment(blob []byte, comment string) ([]byte, error) {
+	if err := validateComment(comment); err != nil {
+		return nil, err
+	}
+	mode, _, err := unwrap(blob)
+	if err != nil {
+		return nil, err
+	}
+	if mode == ModeComment {
+		return nil, fmt.Errorf("blob already carries a comment")
+	}
+	if mode == ModeNamed {
+		return nil, fmt.Errorf("cannot comment a named blob; name the commented blob instead")
+	}
+	body := make([]byte, 0, 1+len(comment)+len(blob))
+	body = append(body, byte(len(comment)))
+	body = append(body, comment...)
+	return wrap(ModeComment, append(body, blob...)), nil
+}
+
+// ReadComment returns the comment AddComment attached to blob, or "" if blob
+// has none.
+// Time Complexity: O(1), Space Complexity: O(1)
+func ReadComment(blob []byte) (string, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return "", err
+	}
+	if mode != ModeComment {
+		return "", nil
+	}
+	comment, _, err := splitComment(body)
+	return comment, err
+}
+
+// validateComment checks that comment can be stored by AddComment.
+func validateComment(comment string) error {
+	if len(comment) == 0 || len(comment) > MaxCommentLength {
+		return fmt.Errorf("comment of %d bytes outside 1-%d", len(comment), MaxCommentLength)
+	}
+	for i := 0; i < len(comment); i++ {
+		if c := comment[i]; c < 0x20 || c > 0x7e {
+			return fmt.Errorf("comment byte 0x%02x at %d is not printable ASCII", c, i)
+		}
+	}
+	return nil
+}
+
+// splitComment parses a ModeComment body into its comment and the wrapped
+// blob.
+// Time Complexity: O(1), Space Complexity: O(1)
+func splitComment(body []byte) (string, []byte, error) {
+	if len(body) == 0 {
+		return "", nil, corruptf("read comment length failed: empty body")
+	}
+	n := int(body[0])
+	if len(body) < 1+n {
+		return "", nil, corruptf("comment of %d bytes truncated at %d", n, len(body)-1)
+	}
+	comment := string(body[1 : 1+n])
+	if err := validateComment(comment); err != nil {
+		return "", nil, corruptf("invalid comment: %v", err)
+	}
+	inner := body[1+n:]
+	mode, _, err := unwrap(inner)
+	if err != nil {
+		return "", nil, err
+	}
+	if mode == ModeComment || mode == ModeNamed {
+		return "", nil, corruptf("%s blob inside a comment", mode)
+	}
+	return comment, inner, nil
+}
@@ -0,0 +1,112 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddComment(t *testing.T) {
+	data := []byte(strings.Repeat("hello world! ", 40))
+	blocks, err := HuffmanCompressBlocks(data, 100)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		comment string
+	}{
+		{name: "Huffman", blob: mustCompress(t, data), comment: "huffmin 2 / nightly backup"},
+		{name: "Blocks", blob: blocks, comment: "x"},
+		{name: "Longest", blob: mustCompress(t, data), comment: strings.Repeat("~", MaxCommentLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commented, err := AddComment(tt.blob, tt.comment)
+			if err != nil {
+				t.Fatalf("unexpected comment error: %v", err)
+			}
+			got, err := ReadComment(commented)
+			if err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			if got != tt.comment {
+				t.Errorf("expected comment %q, got %q", tt.comment, got)
+			}
+			decompressed, err := Decompress(commented)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Error("decompressed output does not match original")
+			}
+			if err := HuffmanVerify(commented); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+		})
+	}
+
+	plain := mustCompress(t, data)
+	if got, err := ReadComment(plain); err != nil || got != "" {
+		t.Errorf("expected no comment on a plain blob, got %q (%v)", got, err)
+	}
+
+	// A commented blob can sit in front of another in a multi-blob stream.
+	commented, err := AddComment(plain, "first")
+	if err != nil {
+		t.Fatalf("unexpected comment error: %v", err)
+	}
+	multi, err := HuffmanDecompressMulti(append(commented, mustCompress(t, []byte("tail"))...))
+	if err != nil {
+		t.Fatalf("unexpected multi decompress error: %v", err)
+	}
+	if !bytes.Equal(multi, append(bytes.Clone(data), "tail"...)) {
+		t.Error("multi-blob output does not match the members")
+	}
+}
+
+func TestHuffmanCompressOptsComment(t *testing.T) {
+	data := []byte(strings.Repeat("abc", 20))
+	blob, err := HuffmanCompressOpts(data, CompressOptions{Comment: "tagged", TieBreak: TieBreakMinChar})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if got, err := ReadComment(blob); err != nil || got != "tagged" {
+		t.Errorf("expected comment %q, got %q (%v)", "tagged", got, err)
+	}
+	if got, err := Decompress(blob); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("expected the original data back, got %v", err)
+	}
+	if _, err := HuffmanCompressOpts(data, CompressOptions{Comment: "bad\ncomment"}); err == nil {
+		t.Error("expected error for a non-printable comment but got nil")
+	}
+}
+
+func TestAddCommentErrors(t *testing.T) {
+	blob := mustCompress(t, []byte("abc"))
+	for _, comment := range []string{"", strings.Repeat("a", MaxCommentLength+1), "tab\there", "café"} {
+		if _, err := AddComment(blob, comment); err == nil {
+			t.Errorf("comment %q: expected error but got nil", comment)
+		}
+	}
+	commented, err := AddComment(blob, "once")
+	if err != nil {
+		t.Fatalf("unexpected comment error: %v", err)
+	}
+	if _, err := AddComment(commented, "twice"); err == nil {
+		t.Error("expected error commenting a commented blob but got nil")
+	}
+
+	truncated := commented[:containerHeaderSize+3]
+	if _, err := ReadComment(truncated); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a truncated comment, got %v", err)
+	}
+	nested := wrap(ModeComment, append([]byte{4, 'o', 'u', 't', 'r'}, commented...))
+	if _, err := Decompress(nested); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a nested comment, got %v", err)
+	}
+}
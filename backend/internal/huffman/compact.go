@@ -0,0 +1,193 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// compactListMax is the most symbols a ModeCompact header lists one byte
+// each; more than that and the 32-byte bitmap is smaller.
+const compactListMax = 32
+
+// compactUsesBitmap reports whether a ModeCompact header over m symbols
+// stores them as a presence bitmap rather than a list. The symbol count
+// leads the header, so it doubles as the flag telling readers which follows.
+func compactUsesBitmap(m int) bool {
+	return m > compactListMax
+}
+
+// readUvarint decodes a uvarint from the front of buf like binary.Uvarint,
+// but also reports n <= 0 for an encoding longer than it needs to be, so
+// each value has one form and re-encoding it gives back the same bytes.
+func readUvarint(buf []byte) (uint64, int) {
+	v, n := binary.Uvarint(buf)
+	if n > 1 && buf[n-1] == 0 {
+		return 0, 0
+	}
+	return v, n
+}
+
+// compressCompact codes data like HuffmanCompressBytes into a ModeCompact
+// blob, whose frequency table spends a byte or two per symbol where
+// ModeHuffman's spends five, and whose bit length takes one to ten bytes
+// rather than eight. The body is:
+//
+//	u8             number of symbols m, minus one
+//	symbols        m x u8 in ascending order if m <= 32, otherwise a
+//	               [32]u8 bitmap with bit b&7 of byte b>>3 set for each
+//	               symbol b
+//	m x uvarint    frequency of each symbol, in ascending symbol order
+//	uvarint        number of meaningful payload bits
+//	payload        as for ModeHuffman
+//
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func compressCompact(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	return compressVarintTable(data, ModeCompact, appendCompactHeader)
+}
+
+// compressVarintTable codes data into a blob of mode, whose body is the
+// frequency table appendTable writes, the bit length as appendBitLength
+// encodes it for mode, and the ModeHuffman payload. It is the shared
+// encoder of ModeCompact and ModeASCII, whose tables differ only in how
+// they record which symbols are present.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func compressVarintTable(data []byte, mode Mode, appendTable func(buf []byte, freq map[byte]int) []byte) ([]byte, error) {
+	freq := buildFrequencyTable(data)
+	if err := validateFrequencyTable(freq); err != nil {
+		return nil, err
+	}
+	var codes codeTable
+	buildCodeTable(buildHuffmanTree(freq), 0, 0, &codes)
+	totalBits := 0
+	for b, f := range freq {
+		totalBits += f * int(codes[b].length)
+	}
+
+	var out bytes.Buffer
+	writeContainerHeader(&out, mode)
+	out.Write(appendTable(out.AvailableBuffer(), freq))
+	out.Write(appendBitLength(out.AvailableBuffer(), mode, uint64(totalBits)))
+	if _, err := encodeDataWithCount(&out, data, &codes, nil); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// appendCompactHeader appends the ModeCompact frequency table of freq, which
+// must hold between 1 and 256 valid entries, to buf.
+// Time Complexity: O(m), Space Complexity: O(m)
+func appendCompactHeader(buf []byte, freq map[byte]int) []byte {
+	buf = append(buf, byte(len(freq)-1))
+	if !compactUsesBitmap(len(freq)) {
+		for b := 0; b < 256; b++ {
+			if _, ok := freq[byte(b)]; ok {
+				buf = append(buf, byte(b))
+			}
+		}
+	} else {
+		var bitmap [32]byte
+		for b := range freq {
+			bitmap[b>>3] |= 1 << (b & 7)
+		}
+		buf = append(buf, bitmap[:]...)
+	}
+	return appendVarintCounts(buf, freq)
+}
+
+// appendVarintCounts appends the frequency of each symbol of freq to buf as
+// a uvarint, in ascending symbol order, as readVarintTable reads them.
+// Time Complexity: O(m), Space Complexity: O(m)
+func appendVarintCounts(buf []byte, freq map[byte]int) []byte {
+	for b := 0; b < 256; b++ {
+		if f, ok := freq[byte(b)]; ok {
+			buf = binary.AppendUvarint(buf, uint64(f))
+		}
+	}
+	return buf
+}
+
+// readCompactBody parses the header and bit length of a ModeCompact body,
+// returning the frequency table, its tree, the bit count and the payload.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readCompactBody(body []byte) (map[byte]int, *Node, uint64, []byte, error) {
+	if len(body) < 1 {
+		return nil, nil, 0, nil, corruptf("read header entries failed: empty body")
+	}
+	m := int(body[0]) + 1
+	rest := body[1:]
+	symbols := make([]byte, 0, m)
+	if !compactUsesBitmap(m) {
+		if len(rest) < m {
+			return nil, nil, 0, nil, corruptf("read header symbols failed: %d bytes left for %d symbols", len(rest), m)
+		}
+		for i, b := range rest[:m] {
+			if i > 0 && b <= symbols[i-1] {
+				return nil, nil, 0, nil, corruptf("invalid header: symbol 0x%02x out of order", b)
+			}
+			symbols = append(symbols, b)
+		}
+		rest = rest[m:]
+	} else {
+		if len(rest) < 32 {
+			return nil, nil, 0, nil, corruptf("read symbol bitmap failed: %d bytes left", len(rest))
+		}
+		for b := 0; b < 256; b++ {
+			if rest[b>>3]&(1<<(b&7)) != 0 {
+				symbols = append(symbols, byte(b))
+			}
+		}
+		if len(symbols) != m {
+			return nil, nil, 0, nil, corruptf("invalid header: bitmap holds %d symbols, count says %d", len(symbols), m)
+		}
+		rest = rest[32:]
+	}
+	return readVarintTable(rest, ModeCompact, symbols)
+}
+
+// readVarintTable parses the uvarint frequency of each of symbols from the
+// front of rest, then the bit length in mode's encoding, returning the
+// frequency table, its tree, the bit count and the payload. It is the
+// shared reader of ModeCompact and ModeASCII bodies once their symbols are
+// known.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readVarintTable(rest []byte, mode Mode, symbols []byte) (map[byte]int, *Node, uint64, []byte, error) {
+	if len(symbols) == 0 {
+		return nil, nil, 0, nil, corruptf("invalid header: no symbols")
+	}
+	freq := make(map[byte]int, len(symbols))
+	for _, b := range symbols {
+		f, n := readUvarint(rest)
+		if n <= 0 {
+			return nil, nil, 0, nil, corruptf("read header freq failed for symbol 0x%02x", b)
+		}
+		if f == 0 || f > math.MaxUint32 {
+			return nil, nil, 0, nil, corruptf("invalid header: symbol 0x%02x has frequency %d", b, f)
+		}
+		freq[b] = int(f)
+		rest = rest[n:]
+	}
+	totalBits, payload, err := readBitLength(rest, mode)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	root := buildHuffmanTree(freq)
+	if err := checkLeaves(root, freq); err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return freq, root, totalBits, payload, nil
+}
+
+// decodeCompactBody reverses compressCompact for a ModeCompact body. strict
+// is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeCompactBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	freq, root, totalBits, bitData, err := readCompactBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHuffmanPayload(nil, freq, root, totalBits, bitData, maxSize, strict)
+}
@@ -0,0 +1,283 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func compactBlob(t *testing.T, data []byte) []byte {
+	t.Helper()
+	blob, err := HuffmanCompressOpts(data, CompressOptions{CompactHeader: true})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	return blob
+}
+
+func TestCompactHeaderRoundTrip(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Single byte", data: []byte("a")},
+		{name: "Single symbol", data: bytes.Repeat([]byte("z"), 1000)},
+		{name: "Small alphabet", data: bytes.Repeat([]byte("ACGTTGCA"), 200)},
+		{name: "Thirty-two symbols", data: all[:32]},
+		{name: "Thirty-three symbols", data: all[:33]},
+		{name: "All bytes", data: bytes.Repeat(all, 3)},
+		{name: "Large frequency", data: bytes.Repeat([]byte("ab"), 100000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob := compactBlob(t, tt.data)
+			if mode, _, err := unwrap(blob); err != nil || mode != ModeCompact {
+				t.Fatalf("expected ModeCompact blob, got mode %v (err %v)", mode, err)
+			}
+			got, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("round trip mismatch: got %d bytes, want %d", len(got), len(tt.data))
+			}
+			if err := HuffmanVerify(blob); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompactHeaderSize(t *testing.T) {
+	data := bytes.Repeat([]byte("ACGTTGCAAC"), 50)
+	plain := mustCompress(t, data)
+	compact := compactBlob(t, data)
+
+	header, totalBits, payload := splitBlob(t, plain)
+	bitsAt, compactBits, compactPayload, err := splitPayload(compact)
+	if err != nil {
+		t.Fatalf("unexpected split error: %v", err)
+	}
+	if !bytes.Equal(compactPayload, payload) {
+		t.Fatalf("expected ModeCompact to carry the ModeHuffman payload unchanged")
+	}
+	if compactBits != totalBits {
+		t.Errorf("expected bit length %d, got %d", totalBits, compactBits)
+	}
+	// One count byte, four symbol bytes, and varints of 150, 150, 100 and 100.
+	compactHeader := bitsAt - containerHeaderSize
+	if want := 1 + 4 + 2 + 2 + 1 + 1; compactHeader != want {
+		t.Errorf("expected a %d-byte compact header, got %d", want, compactHeader)
+	}
+	if compactHeader >= len(header) {
+		t.Errorf("expected compact header to be smaller than %d bytes, got %d", len(header), compactHeader)
+	}
+	// The 1000-bit length takes a two-byte varint.
+	if got := len(compact) - bitsAt - len(payload); got != 2 {
+		t.Errorf("expected a 2-byte bit length, got %d", got)
+	}
+}
+
+func TestCompactBitLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		lengthSize int
+	}{
+		{name: "One byte", data: []byte("a"), lengthSize: 1},
+		{name: "Under 128 bits", data: []byte("hello, world"), lengthSize: 1},
+		{name: "Under 16384 bits", data: bytes.Repeat([]byte("abcd"), 500), lengthSize: 2},
+		{name: "Over 16384 bits", data: bytes.Repeat([]byte("abcd"), 5000), lengthSize: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := mustCompress(t, tt.data)
+			compact := compactBlob(t, tt.data)
+			header, totalBits, _ := splitBlob(t, plain)
+			bitsAt, compactBits, payload, err := splitPayload(compact)
+			if err != nil {
+				t.Fatalf("unexpected split error: %v", err)
+			}
+			if compactBits != totalBits {
+				t.Errorf("expected bit length %d, got %d", totalBits, compactBits)
+			}
+			if got := len(compact) - bitsAt - len(payload); got != tt.lengthSize {
+				t.Errorf("expected a %d-byte bit length, got %d", tt.lengthSize, got)
+			}
+			// The blob shrinks by exactly what the header and bit length save.
+			saved := len(header) - (bitsAt - containerHeaderSize) + 8 - tt.lengthSize
+			if len(plain)-len(compact) != saved {
+				t.Errorf("expected the blob to shrink by %d bytes, got %d", saved, len(plain)-len(compact))
+			}
+			got, err := Decompress(compact)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Error("round trip mismatch")
+			}
+		})
+	}
+
+	// A one-byte file goes from 22 bytes to 11.
+	if plain, compact := mustCompress(t, []byte("a")), compactBlob(t, []byte("a")); len(plain) != 22 || len(compact) != 11 {
+		t.Errorf("expected 22 and 11 bytes for a one-byte file, got %d and %d", len(plain), len(compact))
+	}
+}
+
+func TestCompactNonMinimalVarint(t *testing.T) {
+	// The blob for "a" is the container header, the symbol count, 'a', its
+	// frequency and the bit length, one byte each, then one payload byte.
+	blob := compactBlob(t, []byte("a"))
+	freqAt, bitsAt := containerHeaderSize+2, containerHeaderSize+3
+	if blob[freqAt] != 0x01 || blob[bitsAt] != 0x01 {
+		t.Fatalf("unexpected compact blob layout % x", blob)
+	}
+	// 0x81 0x00 also decodes to 1, but is not the shortest form.
+	stretch := func(at int) []byte {
+		return append(append(bytes.Clone(blob[:at]), 0x81, 0x00), blob[at+1:]...)
+	}
+	for name, bad := range map[string][]byte{"Frequency": stretch(freqAt), "Bit length": stretch(bitsAt)} {
+		if _, err := Decompress(bad); !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("%s: expected ErrCorruptStream for a non-minimal varint, got %v", name, err)
+		}
+		if _, err := EncodeFromBitString(bad, "0"); !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("%s: expected ErrCorruptStream re-encoding a non-minimal varint, got %v", name, err)
+		}
+	}
+}
+
+func TestCompactHeaderOptions(t *testing.T) {
+	data := []byte("compact header with a comment")
+	blob, err := HuffmanCompressOpts(data, CompressOptions{CompactHeader: true, Comment: "note"})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	_, body, err := unwrap(blob)
+	if err != nil {
+		t.Fatalf("unexpected container error: %v", err)
+	}
+	_, inner, err := splitComment(body)
+	if err != nil {
+		t.Fatalf("unexpected comment error: %v", err)
+	}
+	if mode, _, _ := unwrap(inner); mode != ModeCompact {
+		t.Errorf("expected commented blob to wrap ModeCompact, got %v", mode)
+	}
+	got, err := Decompress(blob)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %q, got %q", data, got)
+	}
+
+	// A TieBreak selects ModeCanonical, which already stores only lengths.
+	blob, err = HuffmanCompressOpts(data, CompressOptions{CompactHeader: true, TieBreak: TieBreakMinChar})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if mode, _, _ := unwrap(blob); mode != ModeCanonical {
+		t.Errorf("expected ModeCanonical with a TieBreak, got %v", mode)
+	}
+
+	if _, err := HuffmanCompressOpts(nil, CompressOptions{CompactHeader: true}); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
+
+func TestCompactHeaderCorrupt(t *testing.T) {
+	list := compactBlob(t, []byte("abracadabra"))
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	bitmap := compactBlob(t, all)
+	const countAt = containerHeaderSize
+
+	tests := []struct {
+		name   string
+		blob   []byte
+		mutate func(b []byte) []byte
+	}{
+		{name: "Empty body", blob: list, mutate: func(b []byte) []byte { return b[:countAt] }},
+		{name: "Missing symbols", blob: list, mutate: func(b []byte) []byte { return b[:countAt+3] }},
+		{name: "Symbols out of order", blob: list, mutate: func(b []byte) []byte { b[countAt+1], b[countAt+2] = b[countAt+2], b[countAt+1]; return b }},
+		{name: "Duplicate symbol", blob: list, mutate: func(b []byte) []byte { b[countAt+2] = b[countAt+1]; return b }},
+		{name: "Short bitmap", blob: bitmap, mutate: func(b []byte) []byte { return b[:countAt+20] }},
+		{name: "Bitmap count mismatch", blob: bitmap, mutate: func(b []byte) []byte { b[countAt+1] = 0; return b }},
+		{name: "Zero frequency", blob: list, mutate: func(b []byte) []byte { b[countAt+6] = 0; return b }},
+		{name: "Unterminated frequency", blob: list, mutate: func(b []byte) []byte { return append(b[:countAt+6], 0x80) }},
+		{name: "Missing bit length", blob: list, mutate: func(b []byte) []byte { return b[:countAt+11] }},
+		{name: "Unterminated bit length", blob: list, mutate: func(b []byte) []byte { return append(b[:countAt+11], 0x80) }},
+		{name: "Truncated payload", blob: list, mutate: func(b []byte) []byte { return b[:len(b)-1] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := tt.mutate(bytes.Clone(tt.blob))
+			if _, err := Decompress(corrupt); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("expected ErrCorruptStream, got %v", err)
+			}
+			if err := HuffmanVerify(corrupt); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("expected verify to report ErrCorruptStream, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCompactHeaderSymbolEncoding(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbols    int
+		wantBitmap bool
+	}{
+		{name: "Sparse", symbols: 5, wantBitmap: false},
+		{name: "List limit", symbols: compactListMax, wantBitmap: false},
+		{name: "Past list limit", symbols: compactListMax + 1, wantBitmap: true},
+		{name: "Dense", symbols: 250, wantBitmap: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.symbols)
+			for i := range data {
+				data[i] = byte(255 - i)
+			}
+			blob := compactBlob(t, data)
+			_, body, err := unwrap(blob)
+			if err != nil {
+				t.Fatalf("unexpected container error: %v", err)
+			}
+			if got := compactUsesBitmap(int(body[0]) + 1); got != tt.wantBitmap {
+				t.Errorf("expected bitmap %v for %d symbols, got %v", tt.wantBitmap, tt.symbols, got)
+			}
+			// Every frequency is 1, a one-byte varint.
+			symbolBytes := tt.symbols
+			if tt.wantBitmap {
+				symbolBytes = 32
+			}
+			wantHeader := 1 + symbolBytes + tt.symbols
+			bitsAt, _, _, err := splitPayload(blob)
+			if err != nil {
+				t.Fatalf("unexpected split error: %v", err)
+			}
+			if got := bitsAt - containerHeaderSize; got != wantHeader {
+				t.Errorf("expected a %d-byte header, got %d", wantHeader, got)
+			}
+			got, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("round trip mismatch for %d symbols", tt.symbols)
+			}
+		})
+	}
+}
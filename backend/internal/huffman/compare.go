@@ -0,0 +1,57 @@
+package huffman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"time"
+)
+
+// CodecResult describes the output of one codec on one input. Ratio is the
+// output size over the input size, so lower is better.
+type CodecResult struct {
+	Size     int           `json:"size"`
+	Ratio    float64       `json:"ratio"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Comparison sets the output of HuffmanCompressBytes beside that of gzip
+// for the same input. gzip pairs LZ77 matching with Huffman coding, so it
+// wins on repetitive input, while Huffman coding alone only exploits skewed
+// byte frequencies.
+type Comparison struct {
+	OriginalSize int         `json:"originalSize"`
+	Huffman      CodecResult `json:"huffman"`
+	Gzip         CodecResult `json:"gzip"`
+}
+
+// CompareCodecs compresses data with HuffmanCompressBytes and with gzip at
+// its default level, timing each.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func CompareCodecs(data []byte) (Comparison, error) {
+	if len(data) == 0 {
+		return Comparison{}, ErrEmptyInput
+	}
+	start := time.Now()
+	blob, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return Comparison{}, err
+	}
+	huff := codecResult(len(blob), len(data), time.Since(start))
+
+	start = time.Now()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return Comparison{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Comparison{}, err
+	}
+	gz := codecResult(buf.Len(), len(data), time.Since(start))
+
+	return Comparison{OriginalSize: len(data), Huffman: huff, Gzip: gz}, nil
+}
+
+func codecResult(size, original int, d time.Duration) CodecResult {
+	return CodecResult{Size: size, Ratio: float64(size) / float64(original), Duration: d}
+}
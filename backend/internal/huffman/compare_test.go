@@ -0,0 +1,52 @@
+package huffman
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompareCodecs(t *testing.T) {
+	rng := rand.New(rand.NewSource(44))
+	skewed := make([]byte, 4096)
+	for i := range skewed {
+		// Independent, skewed bytes leave gzip no matches to exploit.
+		skewed[i] = "aaaaaaabbc"[rng.Intn(10)]
+	}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		wantHuffman bool // whether Huffman coding alone should come out smaller
+	}{
+		{name: "Repetitive text", content: []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 100)), wantHuffman: false},
+		{name: "Skewed symbols", content: skewed, wantHuffman: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp, err := CompareCodecs(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compare error: %v", err)
+			}
+			if cmp.OriginalSize != len(tt.content) {
+				t.Errorf("expected original size %d, got %d", len(tt.content), cmp.OriginalSize)
+			}
+			blob := mustCompress(t, tt.content)
+			if cmp.Huffman.Size != len(blob) {
+				t.Errorf("expected huffman size %d, got %d", len(blob), cmp.Huffman.Size)
+			}
+			if want := float64(cmp.Gzip.Size) / float64(len(tt.content)); cmp.Gzip.Ratio != want {
+				t.Errorf("expected gzip ratio %f, got %f", want, cmp.Gzip.Ratio)
+			}
+			if got := cmp.Huffman.Size < cmp.Gzip.Size; got != tt.wantHuffman {
+				t.Errorf("huffman %d bytes vs gzip %d bytes: expected huffman smaller = %v", cmp.Huffman.Size, cmp.Gzip.Size, tt.wantHuffman)
+			}
+		})
+	}
+
+	if _, err := CompareCodecs(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// magic opens every blob produced by this package.
+const magic = "HUFM"
+
+// containerHeaderSize is the size of the magic, version and mode prefix.
+const containerHeaderSize = len(magic) + 2
+
+// Mode identifies the codec used for the body of a blob.
+type Mode byte
+
+// Modes recorded in the container header. Values are part of the wire
+// format and must never be reused.
+const (
+	ModeStore     Mode = iota // body is the input, verbatim
+	ModeHuffman               // body is a frequency-table Huffman stream
+	ModeWords                 // body is a word-symbol Huffman stream
+	ModeRLE                   // body is a Huffman stream of (byte, count) tokens
+	ModeFlate                 // body is a raw DEFLATE stream
+	ModeCanonical             // body is a length-limited canonical Huffman stream
+	ModeModel                 // body is a Huffman stream coded with an external model
+	ModeArchive               // body is a sequence of named member blobs and an index
+	ModeBlocks                // body is a sequence of independently coded blocks and an index
+	ModeComment               // body is a short ASCII comment and another blob
+	ModeEscape                // body is a Huffman stream with rare symbols escaped as literals
+	ModeASCII                 // body is a Huffman stream over printable ASCII with a compact header
+	ModeDelta                 // body is a Huffman stream of differences between consecutive bytes
+	ModeCompact               // body is a Huffman stream with a varint-packed header
+	ModeNamed                 // body is the original file name and another blob
+)
+
+var modeNames = [...]string{
+	ModeStore:     "store",
+	ModeHuffman:   "huffman",
+	ModeWords:     "words",
+	ModeRLE:       "rle",
+	ModeFlate:     "flate",
+	ModeCanonical: "canonical",
+	ModeModel:     "model",
+	ModeArchive:   "archive",
+	ModeBlocks:    "blocks",
+	ModeComment:   "comment",
+	ModeEscape:    "escape",
+	ModeASCII:     "ascii",
+	ModeDelta:     "delta",
+	ModeCompact:   "compact",
+	ModeNamed:     "named",
+}
+
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("mode(%d)", byte(m))
+}
+
+// writeContainerHeader writes the magic, version and mode prefix to buf.
+func writeContainerHeader(buf *bytes.Buffer, mode Mode) {
+	buf.WriteString(magic)
+	buf.WriteByte(FormatVersion)
+	buf.WriteByte(byte(mode))
+}
+
+// wrap prefixes body with a container header for mode.
+// Time Complexity: O(n), Space Complexity: O(n)
+func wrap(mode Mode, body []byte) []byte {
+	out := make([]byte, 0, containerHeaderSize+len(body))
+	out = append(out, magic...)
+	out = append(out, FormatVersion, byte(mode))
+	return append(out, body...)
+}
+
+// retag rewrites the mode of a blob produced by wrap or writeContainerHeader.
+func retag(blob []byte, mode Mode) []byte {
+	blob[len(magic)+1] = byte(mode)
+	return blob
+}
+
+// unwrap validates the container header of blob and returns its mode and body.
+// Time Complexity: O(1), Space Complexity: O(1)
+func unwrap(blob []byte) (Mode, []byte, error) {
+	if len(blob) < containerHeaderSize {
+		return 0, nil, corruptf("read container header failed: %d bytes is too short", len(blob))
+	}
+	if string(blob[:len(magic)]) != magic {
+		return 0, nil, fmt.Errorf("%w %q", ErrBadMagic, blob[:len(magic)])
+	}
+	if v := blob[len(magic)]; v != FormatVersion {
+		return 0, nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, v)
+	}
+	return Mode(blob[len(magic)+1]), blob[containerHeaderSize:], nil
+}
+
+// checkLeafBlob checks that blob is a ModeStore or ModeHuffman blob, the only
+// modes this package writes inside another blob's body. Refusing container
+// modes there keeps blobs from nesting, which decoding would otherwise
+// follow one stack frame per few dozen bytes of input.
+// Time Complexity: O(1), Space Complexity: O(1)
+func checkLeafBlob(blob []byte) error {
+	mode, _, err := unwrap(blob)
+	if err != nil {
+		return err
+	}
+	if mode != ModeStore && mode != ModeHuffman {
+		return corruptf("%s blob cannot be nested", mode)
+	}
+	return nil
+}
+
+// HasMagic reports whether the data r holds starts with a container header
+// of this package's blobs, reading only that header: the magic, FormatVersion
+// and a mode this package knows, as Validate checks. It lets callers spot
+// input that is already compressed without parsing it, while input that
+// merely begins with the four magic bytes is not mistaken for a blob.
+// Time Complexity: O(1), Space Complexity: O(1)
+func HasMagic(r io.ReaderAt) (bool, error) {
+	var head [containerHeaderSize]byte
+	n, err := r.ReadAt(head[:], 0)
+	if n < len(head) {
+		if err == io.EOF {
+			err = nil
+		}
+		return false, err
+	}
+	return Validate(head[:]) == nil, nil
+}
+
+// IsHuffmin reports whether blob opens with a container header of this
+// package: the magic and FormatVersion. It reads only the header, so it is a
+// cheap first check of untrusted input, not a promise that blob decodes.
+// Time Complexity: O(1), Space Complexity: O(1)
+func IsHuffmin(blob []byte) bool {
+	_, _, err := unwrap(blob)
+	return err == nil
+}
+
+// Validate is the stricter form of IsHuffmin: it also requires the mode to
+// be one this package knows, and says what is wrong with the header when
+// it fails. Like IsHuffmin it does not look at the body; HuffmanVerify does
+// that.
+// Time Complexity: O(1), Space Complexity: O(1)
+func Validate(blob []byte) error {
+	mode, _, err := unwrap(blob)
+	if err != nil {
+		return err
+	}
+	if int(mode) >= len(modeNames) {
+		return corruptf("unknown mode %d", byte(mode))
+	}
+	return nil
+}
+
+// ModeOf reports the mode recorded in blob's container header without
+// decoding the body.
+func ModeOf(blob []byte) (Mode, error) {
+	mode, _, err := unwrap(blob)
+	return mode, err
+}
+
+// DefaultMaxDecompressedSize caps the output of Decompress, so a small
+// crafted blob cannot expand until memory runs out.
+const DefaultMaxDecompressedSize = 1 << 30
+
+// sizeLimitError reports output that would grow past maxSize bytes.
+func sizeLimitError(maxSize int) error {
+	return fmt.Errorf("%w of %d bytes", ErrTooLarge, maxSize)
+}
+
+// Decompress decodes any blob produced by this package, dispatching on the
+// mode recorded in its container header. Output is capped at
+// DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func Decompress(blob []byte) ([]byte, error) {
+	return DecompressWithLimit(blob, DefaultMaxDecompressedSize)
+}
+
+// HuffmanDecompressInto is like Decompress but appends the output to dst and
+// returns the extended slice; on error dst is returned unchanged. ModeStore
+// and ModeHuffman blobs decode straight into dst's spare capacity, while
+// other modes are decoded first and then appended. Output is capped at
+// DefaultMaxDecompressedSize bytes, not counting dst's existing contents.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompressInto(dst, blob []byte) ([]byte, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return dst, err
+	}
+	var out []byte
+	switch mode {
+	case ModeStore:
+		if len(body) > DefaultMaxDecompressedSize {
+			return dst, sizeLimitError(DefaultMaxDecompressedSize)
+		}
+		return append(dst, body...), nil
+	case ModeHuffman:
+		out, err = decodeHuffmanBody(dst, body, DefaultMaxDecompressedSize, true)
+	default:
+		var data []byte
+		if data, err = Decompress(blob); err == nil {
+			out = append(dst, data...)
+		}
+	}
+	if err != nil {
+		return dst, err
+	}
+	return out, nil
+}
+
+// DecompressWithLimit is like Decompress but fails as soon as the output
+// would exceed maxSize bytes.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func DecompressWithLimit(blob []byte, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid size limit %d", maxSize)
+	}
+	return decompress(blob, maxSize, true)
+}
+
+// DecompressOptions configures HuffmanDecompressOpts. The zero value decodes
+// exactly like HuffmanDecompress and Decompress: strictly, with the default
+// size cap.
+type DecompressOptions struct {
+	// Lenient ignores payload bytes left over past the bit length and a
+	// final code the bit length cuts off, which are errors by default.
+	// Truncated payloads are always rejected.
+	Lenient bool
+	// MaxSize caps the output; zero means DefaultMaxDecompressedSize.
+	MaxSize int
+	// Verify runs HuffmanVerify before decoding. The format carries no
+	// checksum, so this is the strongest integrity check available: it also
+	// checks that the decoded symbol counts match the header's frequencies.
+	// Verification applies the strict checks, so Lenient has no effect with
+	// Verify.
+	Verify bool
+}
+
+// HuffmanDecompressOpts decodes any blob produced by this package like
+// Decompress, with the error tolerance and size cap set by opts.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompressOpts(blob []byte, opts DecompressOptions) ([]byte, error) {
+	maxSize := opts.MaxSize
+	if maxSize < 0 {
+		return nil, fmt.Errorf("invalid size limit %d", maxSize)
+	}
+	if maxSize == 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	if opts.Verify {
+		if err := HuffmanVerify(blob); err != nil {
+			return nil, err
+		}
+	}
+	return decompress(blob, maxSize, !opts.Lenient || opts.Verify)
+}
+
+// decompress dispatches blob to the decoder for its mode. strict selects
+// whether payload bytes past the bit length and a cut-off final code are
+// errors.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decompress(blob []byte, maxSize int, strict bool) ([]byte, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case ModeStore:
+		if len(body) > maxSize {
+			return nil, sizeLimitError(maxSize)
+		}
+		return bytes.Clone(body), nil
+	case ModeHuffman:
+		return decodeHuffmanBody(nil, body, maxSize, strict)
+	case ModeWords:
+		return decodeWordsBody(body, maxSize, strict)
+	case ModeRLE:
+		return decodeRLEBody(body, maxSize, strict)
+	case ModeFlate:
+		return flateDecompress(body, maxSize)
+	case ModeCanonical:
+		return decodeCanonicalBody(body, maxSize, strict)
+	case ModeModel:
+		return nil, fmt.Errorf("%s blob carries no frequency table; decode it with HuffmanDecompressWithModel", mode)
+	case ModeArchive:
+		return nil, fmt.Errorf("%s blob holds several members; extract it with HuffmanArchiveExtract", mode)
+	case ModeBlocks:
+		return decodeBlocksBody(body, maxSize, strict)
+	case ModeComment:
+		_, inner, err := splitComment(body)
+		if err != nil {
+			return nil, err
+		}
+		return decompress(inner, maxSize, strict)
+	case ModeNamed:
+		_, inner, err := splitName(body)
+		if err != nil {
+			return nil, err
+		}
+		return decompress(inner, maxSize, strict)
+	case ModeEscape:
+		return decodeEscapeBody(body, maxSize, strict)
+	case ModeASCII:
+		return decodeASCIIBody(body, maxSize, strict)
+	case ModeDelta:
+		return decodeDeltaBody(body, maxSize, strict)
+	case ModeCompact:
+		return decodeCompactBody(body, maxSize, strict)
+	default:
+		return nil, corruptf("unknown mode %d", byte(mode))
+	}
+}
+
+// CompressStore wraps data in a container without compressing it, for inputs
+// that coding would only expand.
+// Time Complexity: O(n), Space Complexity: O(n)
+func CompressStore(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	return wrap(ModeStore, data), nil
+}
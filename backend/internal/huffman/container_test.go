@@ -0,0 +1,402 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecompressDispatch(t *testing.T) {
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50))
+	runs := bytes.Repeat([]byte("a"), 5000)
+
+	tests := []struct {
+		name     string
+		compress func([]byte) ([]byte, error)
+		content  []byte
+		wantMode Mode
+	}{
+		{name: "Store", compress: CompressStore, content: text, wantMode: ModeStore},
+		{name: "Huffman", compress: HuffmanCompressBytes, content: text, wantMode: ModeHuffman},
+		{
+			name:     "Words",
+			compress: func(data []byte) ([]byte, error) { return HuffmanCompressWords(data, 2) },
+			content:  append(bytes.Clone(text), 'x'),
+			wantMode: ModeWords,
+		},
+		{name: "RLE", compress: HuffmanCompressRLE, content: runs, wantMode: ModeRLE},
+		{name: "Flate", compress: CompressBest, content: text, wantMode: ModeFlate},
+		{
+			name:     "Canonical",
+			compress: func(data []byte) ([]byte, error) { return HuffmanCompressLimited(data, 12) },
+			content:  text,
+			wantMode: ModeCanonical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := tt.compress(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if !bytes.HasPrefix(blob, []byte(magic)) {
+				t.Fatalf("blob does not start with magic: % x", blob[:min(len(blob), 8)])
+			}
+			if got := Mode(blob[len(magic)+1]); got != tt.wantMode {
+				t.Errorf("expected mode %v, got %v", tt.wantMode, got)
+			}
+
+			decompressed, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestDecompressInvalidContainer(t *testing.T) {
+	huff, err := HuffmanCompressBytes([]byte("abc"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	badVersion := bytes.Clone(huff)
+	badVersion[len(magic)] = FormatVersion + 1
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		wantErr string
+	}{
+		{name: "Empty", blob: nil, wantErr: "too short"},
+		{name: "Truncated header", blob: []byte("HUFM\x02"), wantErr: "too short"},
+		{name: "Bad magic", blob: append([]byte("HUFF"), huff[len(magic):]...), wantErr: "bad magic"},
+		{name: "Bad version", blob: badVersion, wantErr: "unsupported format version"},
+		{name: "Unknown mode", blob: wrap(Mode(200), []byte{1, 2, 3}), wantErr: "unknown mode 200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decompress(tt.blob)
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestDecompressWithLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("ab"), 50000)
+	runs := bytes.Repeat([]byte("a"), 100000)
+
+	tests := []struct {
+		name     string
+		compress func([]byte) ([]byte, error)
+		content  []byte
+	}{
+		{name: "Store", compress: CompressStore, content: content},
+		{name: "Huffman", compress: HuffmanCompressBytes, content: content},
+		{name: "Words", compress: func(data []byte) ([]byte, error) { return HuffmanCompressWords(data, 2) }, content: content},
+		{name: "RLE", compress: HuffmanCompressRLE, content: runs},
+		{name: "Flate", compress: CompressBest, content: runs},
+		{name: "Canonical", compress: func(data []byte) ([]byte, error) { return HuffmanCompressLimited(data, 8) }, content: content},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := tt.compress(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if _, err := DecompressWithLimit(blob, len(tt.content)); err != nil {
+				t.Errorf("unexpected error at exactly the limit: %v", err)
+			}
+			_, err = DecompressWithLimit(blob, len(tt.content)-1)
+			if err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+				t.Errorf("expected size limit error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecompressPayloadLength(t *testing.T) {
+	content := []byte(strings.Repeat("hello world! ", 40))
+	runs := bytes.Repeat([]byte("a"), 1000)
+	model := buildFrequencyTable(content)
+
+	tests := []struct {
+		name       string
+		compress   func([]byte) ([]byte, error)
+		decompress func([]byte) ([]byte, error)
+		content    []byte
+	}{
+		{name: "Huffman", compress: HuffmanCompressBytes, content: content},
+		{name: "Words", compress: func(data []byte) ([]byte, error) { return HuffmanCompressWords(data, 2) }, content: content},
+		{name: "RLE", compress: HuffmanCompressRLE, content: runs},
+		{name: "Canonical", compress: func(data []byte) ([]byte, error) { return HuffmanCompressLimited(data, 8) }, content: content},
+		{
+			name:       "Model",
+			compress:   func(data []byte) ([]byte, error) { return HuffmanCompressWithModel(data, model) },
+			decompress: func(blob []byte) ([]byte, error) { return HuffmanDecompressWithModel(blob, model) },
+			content:    content,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decompress := tt.decompress
+			if decompress == nil {
+				decompress = Decompress
+			}
+			blob, err := tt.compress(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			got, err := decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected error for exactly sized payload: %v", err)
+			}
+			if !bytes.Equal(got, tt.content) {
+				t.Error("decompressed output does not match original")
+			}
+
+			oversized := append(bytes.Clone(blob), 0x00)
+			if _, err := decompress(oversized); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("expected ErrCorruptStream for oversized payload, got %v", err)
+			}
+			if tt.decompress == nil {
+				if err := HuffmanVerify(oversized); !errors.Is(err, ErrCorruptStream) {
+					t.Errorf("expected verify to reject oversized payload, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressBomb(t *testing.T) {
+	// A single symbol claiming 2^32-1 occurrences would decode to 4 GiB from
+	// a blob of a few dozen bytes; the header sum alone rejects it.
+	bomb := craftBlob(1, []headerEntry{{'a', math.MaxUint32}}, math.MaxUint32, make([]byte, 16))
+	start := time.Now()
+	_, err := Decompress(bomb)
+	if err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("limit took %v to fire", elapsed)
+	}
+
+	// A header that understates the output is caught while decoding.
+	liar := craftBlob(1, []headerEntry{{'a', 1}}, 1<<23, make([]byte, 1<<20))
+	_, err = DecompressWithLimit(liar, 4096)
+	if err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+
+	header, totalBits, payload := splitBlob(t, liar)
+	dec, err := NewDecoder(header)
+	if err != nil {
+		t.Fatalf("unexpected decoder error: %v", err)
+	}
+	dec.MaxSize = 4096
+	if _, err := dec.Decode(payload, totalBits); err == nil {
+		t.Error("expected Decoder to enforce MaxSize but got nil")
+	}
+
+	if _, err := DecompressWithLimit(liar, 0); err == nil {
+		t.Error("expected error for a zero limit but got nil")
+	}
+}
+
+func TestHuffmanDecompressInto(t *testing.T) {
+	data := []byte(strings.Repeat("hello world! the quick brown fox. ", 50))
+	huff := mustCompress(t, data)
+	stored, err := CompressStore(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	runs, err := HuffmanCompressRLE(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	// RLE decodes to a temporary slice first, but the output still lands in
+	// dst's spare capacity.
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{name: "Huffman", blob: huff},
+		{name: "Store", blob: stored},
+		{name: "RLE", blob: runs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := []byte("prefix:")
+			dst := append(make([]byte, 0, len(prefix)+len(data)), prefix...)
+			got, err := HuffmanDecompressInto(dst, tt.blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], data) {
+				t.Fatalf("expected the prefix followed by the original data")
+			}
+			if &got[0] != &dst[:1][0] {
+				t.Errorf("output was reallocated despite enough spare capacity")
+			}
+		})
+	}
+
+	prefix := []byte("keep")
+	got, err := HuffmanDecompressInto(prefix, huff[:len(huff)-1])
+	if !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a truncated blob, got %v", err)
+	}
+	if !bytes.Equal(got, prefix) {
+		t.Errorf("expected dst unchanged on error, got %q", got)
+	}
+}
+
+func TestHuffmanDecompressOpts(t *testing.T) {
+	trailing := append(bytes.Clone(formatFixture), 0x00)
+	// Six of the fixture's seven bits leave c's code cut off after "aaab".
+	partial := bytes.Clone(formatFixture)
+	partial[len(partial)-9] = 6
+	// Flipping the first payload bit still decodes, to "cabc", but the
+	// symbol counts no longer match the header.
+	flipped := bytes.Clone(formatFixture)
+	flipped[len(flipped)-1] ^= 0x80
+
+	lenient := DecompressOptions{Lenient: true}
+	strict := DecompressOptions{}
+	verify := DecompressOptions{Verify: true}
+	both := DecompressOptions{Lenient: true, Verify: true}
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		opts    DecompressOptions
+		want    string
+		wantErr error
+	}{
+		{name: "Clean/Lenient", blob: formatFixture, opts: lenient, want: "aaabc"},
+		{name: "Clean/Strict", blob: formatFixture, opts: strict, want: "aaabc"},
+		{name: "Clean/Verify", blob: formatFixture, opts: verify, want: "aaabc"},
+		{name: "Clean/LenientVerify", blob: formatFixture, opts: both, want: "aaabc"},
+		{name: "Trailing/Lenient", blob: trailing, opts: lenient, want: "aaabc"},
+		{name: "Trailing/Strict", blob: trailing, opts: strict, wantErr: ErrCorruptStream},
+		{name: "Trailing/Verify", blob: trailing, opts: verify, wantErr: ErrCorruptStream},
+		{name: "Trailing/LenientVerify", blob: trailing, opts: both, wantErr: ErrCorruptStream},
+		{name: "Partial/Lenient", blob: partial, opts: lenient, want: "aaab"},
+		{name: "Partial/Strict", blob: partial, opts: strict, wantErr: ErrCorruptStream},
+		{name: "Partial/Verify", blob: partial, opts: verify, wantErr: ErrCorruptStream},
+		{name: "Partial/LenientVerify", blob: partial, opts: both, wantErr: ErrCorruptStream},
+		{name: "Flipped/Lenient", blob: flipped, opts: lenient, want: "cabc"},
+		{name: "Flipped/Strict", blob: flipped, opts: strict, want: "cabc"},
+		{name: "Flipped/Verify", blob: flipped, opts: verify, wantErr: ErrCorruptStream},
+		{name: "Flipped/LenientVerify", blob: flipped, opts: both, wantErr: ErrCorruptStream},
+		{name: "MaxSize/Fits", blob: formatFixture, opts: DecompressOptions{MaxSize: 5}, want: "aaabc"},
+		{name: "MaxSize/Lenient", blob: formatFixture, opts: DecompressOptions{Lenient: true, MaxSize: 4}, wantErr: ErrTooLarge},
+		{name: "MaxSize/Verify", blob: formatFixture, opts: DecompressOptions{Verify: true, MaxSize: 4}, wantErr: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HuffmanDecompressOpts(tt.blob, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// The zero value agrees with HuffmanDecompress.
+	if _, err := HuffmanDecompress(trailing); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected HuffmanDecompress to reject trailing bytes like the zero options, got %v", err)
+	}
+	if _, err := HuffmanDecompressOpts(formatFixture, DecompressOptions{MaxSize: -1}); err == nil {
+		t.Error("expected error for a negative limit but got nil")
+	}
+}
+
+func TestHasMagic(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{name: "Blob", data: formatFixture, want: true},
+		{name: "Header only", data: []byte("HUFM\x02\x00"), want: true},
+		{name: "Magic only", data: []byte(magic), want: false},
+		{name: "Magic then text", data: []byte("HUFM is how this note starts"), want: false},
+		{name: "Wrong version", data: []byte("HUFM\x09\x01"), want: false},
+		{name: "Unknown mode", data: []byte("HUFM\x02\xff"), want: false},
+		{name: "Plain", data: []byte("hello world"), want: false},
+		{name: "Short", data: []byte(magic[:2]), want: false},
+		{name: "Empty", data: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HasMagic(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsHuffminAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		blob    []byte
+		want    bool
+		wantErr error
+	}{
+		{name: "Blob", blob: formatFixture, want: true},
+		{name: "Header only", blob: []byte("HUFM\x02\x00"), want: true},
+		{name: "Unknown mode", blob: []byte("HUFM\x02\xff"), want: true, wantErr: ErrCorruptStream},
+		{name: "Wrong magic", blob: []byte("HUFZ\x02\x01rest"), wantErr: ErrBadMagic},
+		{name: "Plain text", blob: []byte("hello world"), wantErr: ErrBadMagic},
+		{name: "Wrong version", blob: []byte("HUFM\x09\x01"), wantErr: ErrUnsupportedVersion},
+		{name: "Too short", blob: []byte("HUFM\x02"), wantErr: ErrCorruptStream},
+		{name: "Empty", blob: nil, wantErr: ErrCorruptStream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsHuffmin(tt.blob); got != tt.want {
+				t.Errorf("expected IsHuffmin %v, got %v", tt.want, got)
+			}
+			err := Validate(tt.blob)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected validate error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
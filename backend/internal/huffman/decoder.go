@@ -0,0 +1,55 @@
+package huffman
+
+import "bytes"
+
+// Decoder decodes payloads that share one frequency table. The tree is built
+// once in NewDecoder and reused by every Decode call, and a Decoder is safe
+// for concurrent use.
+type Decoder struct {
+	// MaxSize caps the output of each Decode call; zero means
+	// DefaultMaxDecompressedSize.
+	MaxSize int
+	// Strict rejects payloads longer than the ceil(totalBits/8) bytes they
+	// need, and payloads whose last code is cut off by totalBits. It is off
+	// by default so callers can pass a payload that is a prefix of a larger
+	// buffer; a cut-off code is then dropped. Short payloads are always
+	// rejected.
+	Strict bool
+
+	root  *Node
+	table *decodeTable
+}
+
+// NewDecoder builds a Decoder from a serialized frequency table, i.e. the
+// header that follows the container header of a HuffmanCompressBytes blob.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func NewDecoder(header []byte) (*Decoder, error) {
+	r := bytes.NewReader(header)
+	freq, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, corruptf("invalid header: %d trailing bytes", r.Len())
+	}
+	root := buildHuffmanTree(freq)
+	if root == nil {
+		return nil, corruptf("invalid tree")
+	}
+	return &Decoder{root: root, table: newDecodeTable(root, tableBitsFor(root, freq))}, nil
+}
+
+// Decode decodes the first totalBits bits of bits.
+// Time Complexity: O(n), Space Complexity: O(n)
+func (d *Decoder) Decode(bits []byte, totalBits uint64) ([]byte, error) {
+	maxSize := d.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	if d.Strict {
+		if err := checkPayloadLength(bits, totalBits); err != nil {
+			return nil, err
+		}
+	}
+	return d.table.decode(nil, bits, totalBits, maxSize, d.Strict)
+}
@@ -0,0 +1,215 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// splitBlob separates a HuffmanCompressBytes blob into its header, bit
+// length and payload.
+func splitBlob(t *testing.T, blob []byte) ([]byte, uint64, []byte) {
+	t.Helper()
+	_, body, err := unwrap(blob)
+	if err != nil {
+		t.Fatalf("unexpected container error: %v", err)
+	}
+	n := int(byteOrder.Uint16(body))
+	size := headerSize(n)
+	return body[:size], byteOrder.Uint64(body[size:]), body[size+8:]
+}
+
+func TestDecoderReuse(t *testing.T) {
+	rng := rand.New(rand.NewSource(10))
+	base := []byte("hello world! the quick brown fox jumps over the lazy dog")
+
+	// Permutations share a frequency table, so their headers are identical.
+	var payloads [][]byte
+	for i := 0; i < 5; i++ {
+		p := bytes.Clone(base)
+		rng.Shuffle(len(p), func(i, j int) { p[i], p[j] = p[j], p[i] })
+		payloads = append(payloads, p)
+	}
+
+	first, err := HuffmanCompressBytes(payloads[0])
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	header, _, _ := splitBlob(t, first)
+	dec, err := NewDecoder(header)
+	if err != nil {
+		t.Fatalf("unexpected decoder error: %v", err)
+	}
+
+	for i, p := range payloads {
+		blob, err := HuffmanCompressBytes(p)
+		if err != nil {
+			t.Fatalf("payload %d: unexpected compress error: %v", i, err)
+		}
+		_, totalBits, bits := splitBlob(t, blob)
+
+		got, err := dec.Decode(bits, totalBits)
+		if err != nil {
+			t.Fatalf("payload %d: unexpected decode error: %v", i, err)
+		}
+		fresh, err := HuffmanDecompress(blob)
+		if err != nil {
+			t.Fatalf("payload %d: unexpected decompress error: %v", i, err)
+		}
+		if !bytes.Equal(got, fresh) || !bytes.Equal(got, p) {
+			t.Errorf("payload %d: reused decoder output differs.\nGot: %q\nFresh: %q\nWant: %q", i, got, fresh, p)
+		}
+	}
+}
+
+func TestNewDecoderInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{name: "Empty", header: nil},
+		{name: "Zero entries", header: []byte{0, 0}},
+		{name: "Trailing bytes", header: []byte{1, 0, 'a', 1, 0, 0, 0, 0xFF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDecoder(tt.header); err == nil {
+				t.Error("expected decoder error but got nil")
+			}
+		})
+	}
+}
+
+func TestDecoderTruncatedPayload(t *testing.T) {
+	blob, err := HuffmanCompressBytes([]byte("aaaaabbbbcccdde"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	header, totalBits, bits := splitBlob(t, blob)
+	dec, err := NewDecoder(header)
+	if err != nil {
+		t.Fatalf("unexpected decoder error: %v", err)
+	}
+	if _, err := dec.Decode(bits[:len(bits)-1], totalBits); err == nil {
+		t.Error("expected error for truncated payload but got nil")
+	}
+}
+
+func TestDecoderStrict(t *testing.T) {
+	content := []byte("aaaaabbbbcccdde")
+	blob, err := HuffmanCompressBytes(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	header, totalBits, bits := splitBlob(t, blob)
+	oversized := append(bytes.Clone(bits), 0x00, 0x00)
+
+	tests := []struct {
+		name    string
+		strict  bool
+		bits    []byte
+		wantErr bool
+	}{
+		{name: "Strict exact", strict: true, bits: bits},
+		{name: "Strict oversized", strict: true, bits: oversized, wantErr: true},
+		{name: "Lenient exact", strict: false, bits: bits},
+		{name: "Lenient oversized", strict: false, bits: oversized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec, err := NewDecoder(header)
+			if err != nil {
+				t.Fatalf("unexpected decoder error: %v", err)
+			}
+			dec.Strict = tt.strict
+			decoded, err := dec.Decode(tt.bits, totalBits)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCorruptStream) {
+					t.Errorf("expected ErrCorruptStream, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if !bytes.Equal(decoded, content) {
+				t.Error("decoded output does not match original")
+			}
+		})
+	}
+}
+
+func TestDecodePartialFinalCode(t *testing.T) {
+	// Cutting the fixture's 7 bits to 6 leaves 111000: three a's, a b, and
+	// the first bit of c's two-bit code.
+	cut := bytes.Clone(formatFixture)
+	cut[len(cut)-9] = 6
+	const wantErr = "trailing bits do not form a complete code"
+
+	if _, err := Decompress(cut); !errors.Is(err, ErrCorruptStream) || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("Decompress: expected corrupt stream error containing %q, got %v", wantErr, err)
+	}
+
+	header, totalBits, bits := splitBlob(t, cut)
+	dec, err := NewDecoder(header)
+	if err != nil {
+		t.Fatalf("unexpected decoder error: %v", err)
+	}
+	// A one-bit table finds the cut inside the bit-by-bit walk, a two-bit
+	// table when the looked-up code runs past the end.
+	for _, width := range []int{1, 2} {
+		if err := dec.SetTableBits(width); err != nil {
+			t.Fatalf("unexpected error setting %d table bits: %v", width, err)
+		}
+		dec.Strict = true
+		if _, err := dec.Decode(bits, totalBits); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%d table bits, strict: expected error containing %q, got %v", width, wantErr, err)
+		}
+		dec.Strict = false
+		decoded, err := dec.Decode(bits, totalBits)
+		if err != nil {
+			t.Fatalf("%d table bits, lenient: unexpected decode error: %v", width, err)
+		}
+		if want := []byte("aaab"); !bytes.Equal(decoded, want) {
+			t.Errorf("%d table bits, lenient: expected the partial code dropped, got %q", width, decoded)
+		}
+	}
+}
+
+func TestDecodeTruncatedPayload(t *testing.T) {
+	// The fixture's single payload byte holds 8 bits; claiming 9 needs a
+	// second byte that is not there.
+	long := bytes.Clone(formatFixture)
+	long[len(long)-9] = 9
+
+	check := func(name string, err error) {
+		t.Helper()
+		if !errors.Is(err, ErrTruncated) || !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("%s: expected ErrTruncated wrapping ErrCorruptStream, got %v", name, err)
+		}
+	}
+	_, err := Decompress(long)
+	check("Decompress", err)
+	_, err = HuffmanDecompressOpts(long, DecompressOptions{Lenient: true})
+	check("lenient HuffmanDecompressOpts", err)
+	check("HuffmanVerify", HuffmanVerify(long))
+
+	header, totalBits, bits := splitBlob(t, long)
+	dec, err := NewDecoder(header)
+	if err != nil {
+		t.Fatalf("unexpected decoder error: %v", err)
+	}
+	for _, strict := range []bool{true, false} {
+		dec.Strict = strict
+		_, err := dec.Decode(bits, totalBits)
+		check(fmt.Sprintf("Decoder strict=%v", strict), err)
+	}
+	// A bit length far past the payload fails before any decoding.
+	_, err = dec.Decode(bits, 1<<40)
+	check("Decoder huge bit length", err)
+}
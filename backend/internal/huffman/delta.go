@@ -0,0 +1,59 @@
+package huffman
+
+// deltaEncode replaces every byte after the first with its difference from
+// the byte before, modulo 256. Smooth data such as ramps and slowly changing
+// samples becomes a few small values repeated many times.
+// Time Complexity: O(n), Space Complexity: O(n)
+func deltaEncode(data []byte) []byte {
+	out := make([]byte, len(data))
+	var prev byte
+	for i, b := range data {
+		out[i] = b - prev
+		prev = b
+	}
+	return out
+}
+
+// deltaDecode reverses deltaEncode in place.
+// Time Complexity: O(n), Space Complexity: O(1)
+func deltaDecode(deltas []byte) []byte {
+	var prev byte
+	for i, d := range deltas {
+		prev += d
+		deltas[i] = prev
+	}
+	return deltas
+}
+
+// HuffmanCompressDelta delta-encodes data before Huffman coding the
+// differences, which pays off on sequential or slowly varying bytes such as
+// counters, ramps and sensor samples. When the delta pass does not pay for
+// itself the plain ModeHuffman encoding is returned instead; the container
+// mode records which was chosen.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressDelta(data []byte) ([]byte, error) {
+	plain, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	deltas, err := HuffmanCompressBytes(deltaEncode(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(deltas) < len(plain) {
+		// The differences are themselves a ModeHuffman body; only the mode
+		// differs.
+		return retag(deltas, ModeDelta), nil
+	}
+	return plain, nil
+}
+
+// decodeDeltaBody reverses HuffmanCompressDelta for a ModeDelta body.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeDeltaBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	deltas, err := decodeHuffmanBody(nil, body, maxSize, strict)
+	if err != nil {
+		return nil, err
+	}
+	return deltaDecode(deltas), nil
+}
@@ -0,0 +1,87 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanCompressDelta(t *testing.T) {
+	rng := rand.New(rand.NewSource(366))
+	ramp := make([]byte, 8192)
+	for i := range ramp {
+		ramp[i] = byte(i)
+	}
+	sawtooth := make([]byte, 8192)
+	for i := range sawtooth {
+		sawtooth[i] = byte(i % 50 * 3)
+	}
+	// A slowly drifting signal, each sample a small step from the last.
+	sensor := make([]byte, 8192)
+	level := 128
+	for i := range sensor {
+		level = min(255, max(0, level+rng.Intn(5)-2))
+		sensor[i] = byte(level)
+	}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		wantMode    Mode
+		wantShrink4 bool
+	}{
+		{name: "Ramp", content: ramp, wantMode: ModeDelta, wantShrink4: true},
+		{name: "Sawtooth", content: sawtooth, wantMode: ModeDelta, wantShrink4: true},
+		{name: "Sensor", content: sensor, wantMode: ModeDelta},
+		// Every change between the two symbols costs two delta symbols.
+		{name: "Sparse changes", content: bytes.Repeat([]byte("aaaaaaab"), 100), wantMode: ModeHuffman},
+		{name: "Text", content: []byte(strings.Repeat("hello world! ", 50)), wantMode: ModeHuffman},
+		{name: "Single byte", content: []byte{0x7f}, wantMode: ModeHuffman},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := HuffmanCompressDelta(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if mode, _, _ := unwrap(compressed); mode != tt.wantMode {
+				t.Errorf("expected mode %v, got %v", tt.wantMode, mode)
+			}
+
+			plain, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if tt.wantShrink4 && len(compressed)*4 > len(plain) {
+				t.Errorf("expected delta output (%d bytes) to be much smaller than plain (%d bytes)", len(compressed), len(plain))
+			}
+			if len(compressed) > len(plain) {
+				t.Errorf("delta output (%d bytes) is larger than plain (%d bytes)", len(compressed), len(plain))
+			}
+
+			decompressed, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(tt.content))
+			}
+			if err := HuffmanVerify(compressed); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	data := []byte{0, 1, 2, 255, 0, 128, 127, 3}
+	deltas := deltaEncode(data)
+	if want := []byte{0, 1, 1, 253, 1, 128, 255, 132}; !bytes.Equal(deltas, want) {
+		t.Errorf("expected deltas %v, got %v", want, deltas)
+	}
+	if got := deltaDecode(deltas); !bytes.Equal(got, data) {
+		t.Errorf("expected %v, got %v", data, got)
+	}
+}
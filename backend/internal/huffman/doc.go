@@ -0,0 +1,44 @@
+// Package huffman implements byte-oriented Huffman coding and the huffmin
+// wire format.
+//
+// # Wire format
+//
+// Every multi-byte integer is little-endian (see byteOrder). A blob opens
+// with a container header:
+//
+//	[4]u8          magic "HUFM"
+//	u8             FormatVersion
+//	u8             Mode of the body that follows
+//
+// Decompress reads the header and dispatches on the mode. Blobs written at
+// FormatVersion 1 predate the header and are bare ModeHuffman bodies;
+// Decompress rejects them, since nothing marks them, and UpgradeV1 converts
+// one to the current format.
+//
+// A ModeHuffman body, as produced by HuffmanCompressBytes, is laid out as:
+//
+//	u16            number of header entries m (at most 256)
+//	m x (u8, u32)  symbol and its frequency, in ascending symbol order
+//	u64            number of meaningful payload bits
+//	payload        codes packed most significant bit first; unused low bits
+//	               of the final byte are zero
+//
+// The payload must be exactly ceil(bits/8) bytes long, and the bits must end
+// on a code boundary; Decompress rejects blobs with bytes missing or left
+// over, or whose last code is cut off.
+//
+// The decoder rebuilds the tree from the frequencies, so tree construction
+// is part of the format: nodes are merged in order of frequency, with ties
+// broken by the smallest symbol in each subtree. A table with a single
+// symbol gives that symbol the one-bit code 0.
+//
+// A ModeRLE body uses the same layout over the (byte, count) tokens of
+// rleEncode, and a ModeDelta body over the byte differences of deltaEncode.
+// The word-symbol and length-limited bodies are described on
+// HuffmanCompressWords and HuffmanCompressLimited, the archive body on
+// HuffmanArchive, the block body on HuffmanCompressBlocks, the comment body
+// on AddComment, the named body on AddName, the escaped body on
+// HuffmanCompressEscape, the compact ASCII body on HuffmanCompressASCII, and
+// the packed-header body on CompressOptions.CompactHeader. ModeFlate holds a
+// raw DEFLATE stream and ModeStore the input itself.
+package huffman
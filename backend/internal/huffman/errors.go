@@ -0,0 +1,47 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned by this package, wrapped with context. Test for
+// them with errors.Is.
+var (
+	// ErrEmptyInput reports an attempt to compress zero bytes.
+	ErrEmptyInput = errors.New("cannot compress empty file")
+	// ErrCorruptStream reports a blob that is truncated or malformed.
+	ErrCorruptStream = errors.New("corrupt stream")
+	// ErrTruncated reports a payload holding fewer bits than its header
+	// records. Errors wrapping it also wrap ErrCorruptStream.
+	ErrTruncated = errors.New("payload truncated")
+	// ErrBadMagic reports input that is not a huffmin blob.
+	ErrBadMagic = errors.New("bad magic")
+	// ErrUnsupportedVersion reports a blob written in another FormatVersion.
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+	// ErrTooLarge reports output that would exceed the decompression limit.
+	ErrTooLarge = errors.New("decompressed size exceeds limit")
+	// ErrWrongMode reports a blob of another mode passed to a decoder that
+	// reads only one, such as HuffmanDecompressWithModel.
+	ErrWrongMode = errors.New("wrong blob mode")
+	// ErrNotSeekable reports a stream that a two-pass API cannot rewind.
+	ErrNotSeekable = errors.New("input is not seekable")
+	// ErrNotASCII reports input to HuffmanCompressASCII holding a byte
+	// outside its printable ASCII alphabet.
+	ErrNotASCII = errors.New("input is not printable ASCII")
+	// ErrRoundTrip reports input that ValidateRoundTrip could not get back
+	// unchanged, or whose blob changed when compressed again.
+	ErrRoundTrip = errors.New("round trip mismatch")
+)
+
+// corruptf returns an ErrCorruptStream error annotated with a formatted
+// message. The format may use %w to wrap an underlying error as well.
+func corruptf(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrCorruptStream}, args...)...)
+}
+
+// truncatedError reports a payload that ends at bit have of the totalBits its
+// header records.
+func truncatedError(have, totalBits uint64) error {
+	return fmt.Errorf("%w: %w: encoded data ends at bit %d of %d", ErrCorruptStream, ErrTruncated, have, totalBits)
+}
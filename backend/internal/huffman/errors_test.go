@@ -0,0 +1,93 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestErrEmptyInput(t *testing.T) {
+	compressors := map[string]func([]byte) ([]byte, error){
+		"HuffmanCompressBytes": HuffmanCompressBytes,
+		"CompressStore":        CompressStore,
+		"HuffmanCompressRLE":   HuffmanCompressRLE,
+		"CompressBest":         CompressBest,
+		"TreeJSON":             TreeJSON,
+		"HuffmanCompressWords": func(data []byte) ([]byte, error) { return HuffmanCompressWords(data, 1) },
+		"HuffmanCompressLimited": func(data []byte) ([]byte, error) {
+			return HuffmanCompressLimited(data, MaxCodeLength)
+		},
+		"HuffmanCompressLevel": func(data []byte) ([]byte, error) { return HuffmanCompressLevel(data, BestCompression) },
+		"HuffmanCompressWithModel": func(data []byte) ([]byte, error) {
+			return HuffmanCompressWithModel(data, map[byte]int{'a': 1})
+		},
+		"HuffmanCompressAll": func(data []byte) ([]byte, error) { return HuffmanCompressAll(bytes.NewReader(data)) },
+	}
+	for name, compress := range compressors {
+		if _, err := compress(nil); !errors.Is(err, ErrEmptyInput) {
+			t.Errorf("%s: expected ErrEmptyInput, got %v", name, err)
+		}
+	}
+	if _, err := EstimateCompressedSize(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("EstimateCompressedSize: expected ErrEmptyInput, got %v", err)
+	}
+}
+
+func TestDecompressSentinels(t *testing.T) {
+	blob, err := HuffmanCompressBytes([]byte("hello world! hello world!"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	badVersion := bytes.Clone(blob)
+	badVersion[len(magic)] = FormatVersion + 1
+
+	tests := []struct {
+		name string
+		blob []byte
+		want error
+	}{
+		{name: "Too short", blob: []byte("HU"), want: ErrCorruptStream},
+		{name: "Bad magic", blob: append([]byte("GZIP"), blob[len(magic):]...), want: ErrBadMagic},
+		{name: "Unsupported version", blob: badVersion, want: ErrUnsupportedVersion},
+		{name: "Unknown mode", blob: wrap(Mode(99), nil), want: ErrCorruptStream},
+		{name: "Truncated header", blob: blob[:containerHeaderSize+3], want: ErrCorruptStream},
+		{name: "Truncated payload", blob: blob[:len(blob)-2], want: ErrCorruptStream},
+		{name: "Duplicate symbol", blob: craftBlob(2, []headerEntry{{'a', 1}, {'a', 1}}, 2, []byte{0x40}), want: ErrCorruptStream},
+		{name: "Empty table with payload", blob: craftBlob(0, nil, 8, []byte{0}), want: ErrCorruptStream},
+		{name: "Corrupt flate", blob: wrap(ModeFlate, []byte{0xFF, 0xFF}), want: ErrCorruptStream},
+		{name: "Corrupt RLE", blob: retag(bytes.Clone(blob), ModeRLE), want: ErrCorruptStream},
+		{name: "Bad word size", blob: wrap(ModeWords, []byte{3, 0}), want: ErrCorruptStream},
+		{name: "Incomplete code lengths", blob: wrap(ModeCanonical, []byte{15, 2, 0, 'a', 2, 'b', 2, 4, 0, 0, 0, 0, 0, 0, 0, 0x40}), want: ErrCorruptStream},
+		{name: "Too large", blob: craftBlob(1, []headerEntry{{'a', 1 << 31}}, 1<<31, nil), want: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decompress(tt.blob)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected errors.Is(err, %v), got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCorruptStreamWrapsCause(t *testing.T) {
+	blob, err := HuffmanCompressBytes([]byte("abc"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	// Cutting the blob inside the bit length leaves binary.Read short.
+	_, err = Decompress(blob[:len(blob)-4])
+	if !errors.Is(err, ErrCorruptStream) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected ErrCorruptStream wrapping io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	_, err = HuffmanCompressAll(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(boom)))
+	if !errors.Is(err, boom) {
+		t.Errorf("expected read error to be wrapped, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// noEscape marks a ModeEscape body in which no symbol was escaped.
+const noEscape = 0x100
+
+// escapeBody is a parsed ModeEscape body.
+type escapeBody struct {
+	threshold uint32
+	escape    int // escape symbol, or noEscape
+	freq      map[byte]int
+	root      *Node
+	totalBits uint64
+	payload   []byte
+}
+
+// HuffmanCompressEscape codes data like HuffmanCompressBytes, except that
+// every symbol occurring fewer than threshold times shares one leaf of the
+// tree, the escape, and is written as the escape code followed by its raw
+// 8 bits. A long tail of rare symbols then costs one leaf instead of one
+// each, which shortens the codes of the common symbols at the price of
+// literal bytes for the rare ones. The escape symbol is the smallest rare
+// byte; with fewer than two rare symbols nothing is escaped. The body is:
+//
+//	u32            threshold
+//	u16            escape symbol, or 0x100 if nothing is escaped
+//	ModeHuffman    header, bit length and payload, where the escape's
+//	               frequency counts every escaped byte
+//
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressEscape(data []byte, threshold int) ([]byte, error) {
+	if threshold < 0 || threshold > math.MaxUint32 {
+		return nil, fmt.Errorf("escape threshold %d outside 0-%d", threshold, uint32(math.MaxUint32))
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	freq := buildFrequencyTable(data)
+	escape := noEscape
+	rare, rareCount := 0, 0
+	for b, f := range freq {
+		if f < threshold {
+			rare++
+			rareCount += f
+			escape = min(escape, int(b))
+		}
+	}
+	if rare < 2 {
+		escape = noEscape
+	}
+	model := freq
+	if escape != noEscape {
+		model = make(map[byte]int, len(freq)-rare+1)
+		for b, f := range freq {
+			if f >= threshold {
+				model[b] = f
+			}
+		}
+		model[byte(escape)] = rareCount
+	}
+
+	var codes codeTable
+	buildCodeTable(buildHuffmanTree(model), 0, 0, &codes)
+	totalBits := 0
+	for b, f := range freq {
+		if escape != noEscape && f < threshold {
+			totalBits += f * (int(codes[escape].length) + 8)
+		} else {
+			totalBits += f * int(codes[b].length)
+		}
+	}
+	head, err := writeHeader(model)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Grow(containerHeaderSize + 4 + 2 + len(head) + 8 + (totalBits+7)/8)
+	writeContainerHeader(&out, ModeEscape)
+	out.Write(byteOrder.AppendUint32(nil, uint32(threshold)))
+	out.Write(byteOrder.AppendUint16(nil, uint16(escape)))
+	out.Write(head)
+	out.Write(byteOrder.AppendUint64(nil, uint64(totalBits)))
+	bw := newBitWriter(&out)
+	for _, b := range data {
+		if escape != noEscape && freq[b] < threshold {
+			if err := bw.writeCode(codes[escape]); err != nil {
+				return nil, err
+			}
+			if err := bw.WriteBits(uint32(b), 8); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := bw.writeCode(codes[b]); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// readEscapeBody parses a ModeEscape body up to its payload.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readEscapeBody(body []byte) (escapeBody, error) {
+	if len(body) < 4+2 {
+		return escapeBody{}, corruptf("read escape configuration failed: body of %d bytes is too short", len(body))
+	}
+	eb := escapeBody{
+		threshold: byteOrder.Uint32(body),
+		escape:    int(byteOrder.Uint16(body[4:])),
+	}
+	if eb.escape > noEscape {
+		return escapeBody{}, corruptf("invalid escape symbol 0x%x", eb.escape)
+	}
+	var err error
+	eb.freq, eb.root, eb.totalBits, eb.payload, err = readHuffmanBody(body[6:])
+	if err != nil {
+		return escapeBody{}, err
+	}
+	if eb.escape != noEscape {
+		if _, ok := eb.freq[byte(eb.escape)]; !ok {
+			return escapeBody{}, corruptf("escape symbol 0x%02x missing from the header", eb.escape)
+		}
+	}
+	return eb, nil
+}
+
+// decodeEscapeBody reverses HuffmanCompressEscape for a ModeEscape body.
+// strict is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeEscapeBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	eb, err := readEscapeBody(body)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, f := range eb.freq {
+		size += f
+	}
+	if size > maxSize {
+		return nil, sizeLimitError(maxSize)
+	}
+	if err := checkPayload(eb.payload, eb.totalBits, strict); err != nil {
+		return nil, err
+	}
+	// Every symbol takes at least one bit, so the bit length also bounds
+	// the output, whatever the header claims.
+	out := make([]byte, 0, min(uint64(size), eb.totalBits))
+	// The payload covers totalBits, so the reader only runs dry at the bit
+	// length, which must fall between symbols.
+	br := newBitReader(eb.payload, eb.totalBits)
+	for {
+		start := br.BitsRead()
+		if start == eb.totalBits {
+			return out, nil
+		}
+		node := eb.root
+		if node.Left == nil && node.Right == nil {
+			// Single-symbol tree: its code is one bit.
+			br.ReadBit()
+		}
+		for node.Left != nil {
+			bit, err := br.ReadBit()
+			if err != nil {
+				return partialCode(out, start, eb.totalBits, strict)
+			}
+			if bit == 0 {
+				node = node.Left
+			} else {
+				node = node.Right
+			}
+		}
+		b := node.Char
+		if int(b) == eb.escape {
+			literal, err := br.ReadBits(8)
+			if err != nil {
+				return partialCode(out, start, eb.totalBits, strict)
+			}
+			b = byte(literal)
+		}
+		if len(out) == maxSize {
+			return nil, sizeLimitError(maxSize)
+		}
+		out = append(out, b)
+	}
+}
+
+// verifyEscapeBody decodes a ModeEscape body, checking that the codes are
+// complete and that the decoded symbol counts match the header, with every
+// escaped byte counted against the escape symbol.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func verifyEscapeBody(body []byte) error {
+	eb, err := readEscapeBody(body)
+	if err != nil {
+		return err
+	}
+	out, err := decodeEscapeBody(body, DefaultMaxDecompressedSize, true)
+	if err != nil {
+		return err
+	}
+	counts := make(map[byte]int, len(eb.freq))
+	for _, b := range out {
+		if _, common := eb.freq[b]; common && int(b) != eb.escape {
+			counts[b]++
+		} else {
+			counts[byte(eb.escape)]++
+		}
+	}
+	for b, f := range eb.freq {
+		if counts[b] != f {
+			return corruptf("symbol 0x%02x decoded %d times, header says %d", b, counts[b], f)
+		}
+	}
+	return nil
+}
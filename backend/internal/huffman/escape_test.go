@@ -0,0 +1,138 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// longTailFixture is mostly a few letters, with a hundred other byte values
+// appearing once or twice each.
+func longTailFixture() []byte {
+	rng := rand.New(rand.NewSource(360))
+	data := make([]byte, 0, 20000)
+	for i := 0; i < 20000; i++ {
+		data = append(data, "etaoin"[rng.Intn(6)])
+	}
+	for b := 100; b < 200; b++ {
+		for n := 0; n <= b%2; n++ {
+			at := rng.Intn(len(data))
+			data = append(data[:at], append([]byte{byte(b)}, data[at:]...)...)
+		}
+	}
+	return data
+}
+
+func TestHuffmanCompressEscape(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   []byte
+		threshold int
+	}{
+		{name: "Long tail", content: longTailFixture(), threshold: 3},
+		{name: "Threshold zero escapes nothing", content: longTailFixture(), threshold: 0},
+		{name: "Everything rare", content: []byte("abcdefg"), threshold: 10},
+		{name: "One rare symbol", content: []byte("aaaaaaaab"), threshold: 2},
+		{name: "Single symbol", content: bytes.Repeat([]byte{0xFF}, 50), threshold: 1},
+		{name: "Text", content: []byte(strings.Repeat("hello world! ", 40)), threshold: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := HuffmanCompressEscape(tt.content, tt.threshold)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if mode, _ := ModeOf(blob); mode != ModeEscape {
+				t.Errorf("expected a %s blob, got %s", ModeEscape, mode)
+			}
+			got, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, tt.content) {
+				t.Error("decompressed output does not match original")
+			}
+			if err := HuffmanVerify(blob); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+			if _, err := DecodeBitString(blob); err != nil {
+				t.Errorf("unexpected bit string error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressEscapeLongTail(t *testing.T) {
+	data := longTailFixture()
+	plain := mustCompress(t, data)
+	escaped, err := HuffmanCompressEscape(data, 3)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if len(escaped) >= len(plain) {
+		t.Errorf("expected escaping the tail to shrink the blob, got %d bytes against %d", len(escaped), len(plain))
+	}
+
+	_, body, err := unwrap(escaped)
+	if err != nil {
+		t.Fatalf("unexpected container error: %v", err)
+	}
+	eb, err := readEscapeBody(body)
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	if eb.threshold != 3 || eb.escape != 100 {
+		t.Errorf("expected threshold 3 and escape 0x64, got %d and 0x%x", eb.threshold, eb.escape)
+	}
+	if len(eb.freq) != 7 {
+		t.Errorf("expected 6 common symbols and the escape in the header, got %d entries", len(eb.freq))
+	}
+
+	// A concatenated stream finds the end of the escaped member.
+	multi, err := HuffmanDecompressMulti(append(bytes.Clone(escaped), mustCompress(t, []byte("tail"))...))
+	if err != nil || !bytes.Equal(multi, append(bytes.Clone(data), "tail"...)) {
+		t.Errorf("unexpected multi-blob result: %v", err)
+	}
+}
+
+func TestHuffmanCompressEscapeErrors(t *testing.T) {
+	if _, err := HuffmanCompressEscape(nil, 2); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	if _, err := HuffmanCompressEscape([]byte("x"), -1); err == nil {
+		t.Error("expected error for a negative threshold but got nil")
+	}
+
+	blob, err := HuffmanCompressEscape([]byte("aaaaabbbbcz"), 2)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	trailing := append(bytes.Clone(blob), 0x00)
+	if _, err := Decompress(trailing); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for trailing bytes, got %v", err)
+	}
+	// Dropping the final bits cuts off the last symbol, an escaped 'z'.
+	cut := bytes.Clone(blob)
+	bitsAt, _, _, err := splitPayload(cut)
+	if err != nil {
+		t.Fatalf("unexpected payload error: %v", err)
+	}
+	cut[bitsAt]--
+	if _, err := Decompress(cut); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a cut-off literal, got %v", err)
+	}
+	lenient, err := HuffmanDecompressOpts(cut, DecompressOptions{Lenient: true})
+	if err != nil || string(lenient) != "aaaaabbbbc" {
+		t.Errorf("expected the lenient decode to drop the cut-off symbol, got %q (%v)", lenient, err)
+	}
+
+	badEscape := bytes.Clone(blob)
+	badEscape[containerHeaderSize+4] = 0xFF
+	badEscape[containerHeaderSize+5] = 0xFF
+	if _, err := Decompress(badEscape); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for an invalid escape symbol, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package huffman
+
+import "fmt"
+
+// EstimateResult describes the predicted output of HuffmanCompressBytes.
+type EstimateResult struct {
+	OriginalSize  int     `json:"originalSize"`
+	EstimatedSize int     `json:"estimatedSize"`
+	Ratio         float64 `json:"ratio"`
+	MaxCodeLength int     `json:"maxCodeLength"`
+	// Warning is set when the longest code exceeds DeepCodeThreshold.
+	Warning string `json:"warning,omitempty"`
+}
+
+// DeepCodeThreshold is the code length in bits past which a tree is
+// considered pathologically deep. Trees that deep come from highly skewed
+// distributions, where a few symbols dominate and plain Huffman coding
+// gains little; HuffmanCompressLimited or another codec may do better.
+const DeepCodeThreshold = 24
+
+// codeLengths populates lengths with the depth of each leaf, matching the
+// code lengths assigned by generateCodes.
+// Time Complexity: O(m), Space Complexity: O(m)
+func codeLengths(root *Node, depth int, lengths map[byte]int) {
+	if root == nil {
+		return
+	}
+	if root.Left == nil && root.Right == nil {
+		lengths[root.Char] = max(depth, 1)
+		return
+	}
+	codeLengths(root.Left, depth+1, lengths)
+	codeLengths(root.Right, depth+1, lengths)
+}
+
+// treeDepth returns the length of the longest code in the tree at root.
+// Time Complexity: O(m), Space Complexity: O(m)
+func treeDepth(root *Node) int {
+	lengths := make(map[byte]int)
+	codeLengths(root, 0, lengths)
+	depth := 0
+	for _, l := range lengths {
+		depth = max(depth, l)
+	}
+	return depth
+}
+
+// LongestCodeLength returns the length in bits of the longest code
+// HuffmanCompressBytes assigns any symbol of data. Lengths above
+// DeepCodeThreshold mark distributions that Huffman coding handles poorly.
+// (The name MaxCodeLength is taken by the length-limited codec's cap.)
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func LongestCodeLength(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, ErrEmptyInput
+	}
+	return treeDepth(buildHuffmanTree(buildFrequencyTable(data))), nil
+}
+
+// deepCodeWarning describes a longest code of depth bits if it exceeds
+// DeepCodeThreshold, and returns "" otherwise.
+func deepCodeWarning(depth int) string {
+	if depth <= DeepCodeThreshold {
+		return ""
+	}
+	return fmt.Sprintf("longest code is %d bits, over the %d-bit threshold: the byte distribution is highly skewed, so Huffman coding will gain little; consider length-limited coding or another codec", depth, DeepCodeThreshold)
+}
+
+// DeepCodeWarning returns a warning for data if its longest code exceeds
+// DeepCodeThreshold, with that length, and "" otherwise.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func DeepCodeWarning(data []byte) (string, int, error) {
+	depth, err := LongestCodeLength(data)
+	if err != nil {
+		return "", 0, err
+	}
+	return deepCodeWarning(depth), depth, nil
+}
+
+// CodeLengths returns the length in bits of the code HuffmanCompressBytes
+// assigns each symbol of data. A single-symbol input has length 1.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func CodeLengths(data []byte) (map[byte]int, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	lengths := make(map[byte]int)
+	codeLengths(buildHuffmanTree(buildFrequencyTable(data)), 0, lengths)
+	return lengths, nil
+}
+
+// CodeLengthHistogram returns how many symbols of data get a code of each
+// length, keyed by length in bits.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func CodeLengthHistogram(data []byte) (map[int]int, error) {
+	lengths, err := CodeLengths(data)
+	if err != nil {
+		return nil, err
+	}
+	hist := make(map[int]int)
+	for _, l := range lengths {
+		hist[l]++
+	}
+	return hist, nil
+}
+
+// huffmanBlobSize returns the exact size of a ModeHuffman blob with m header
+// entries and totalBits payload bits.
+func huffmanBlobSize(m, totalBits int) int {
+	return containerHeaderSize + headerSize(m) + 8 + (totalBits+7)/8
+}
+
+// EstimateCompressedSize predicts the compressed size of data from its code
+// lengths without encoding the payload.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func EstimateCompressedSize(data []byte) (EstimateResult, error) {
+	if len(data) == 0 {
+		return EstimateResult{}, ErrEmptyInput
+	}
+	freqTable := buildFrequencyTable(data)
+	root := buildHuffmanTree(freqTable)
+	lengths := make(map[byte]int)
+	codeLengths(root, 0, lengths)
+
+	totalBits := 0
+	for b, f := range freqTable {
+		totalBits += f * lengths[b]
+	}
+	size := huffmanBlobSize(len(freqTable), totalBits)
+	depth := treeDepth(root)
+	return EstimateResult{
+		OriginalSize:  len(data),
+		EstimatedSize: size,
+		Ratio:         float64(size) / float64(len(data)),
+		MaxCodeLength: depth,
+		Warning:       deepCodeWarning(depth),
+	}, nil
+}
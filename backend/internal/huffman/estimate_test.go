@@ -0,0 +1,234 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestEstimateCompressedSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 4096)
+	rng.Read(random)
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Simple ASCII", content: []byte("aaaaabbbbcccdde")},
+		{name: "Binary data", content: []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}},
+		{name: "Long repetitive", content: []byte("hello world! hello world! hello world! hello world!")},
+		{name: "Random", content: random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est, err := EstimateCompressedSize(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected estimate error: %v", err)
+			}
+			compressed, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+
+			if est.OriginalSize != len(tt.content) {
+				t.Errorf("expected original size %d, got %d", len(tt.content), est.OriginalSize)
+			}
+			if est.EstimatedSize != len(compressed) {
+				t.Errorf("estimated size %d does not match actual size %d", est.EstimatedSize, len(compressed))
+			}
+			wantRatio := float64(len(compressed)) / float64(len(tt.content))
+			if est.Ratio != wantRatio {
+				t.Errorf("expected ratio %f, got %f", wantRatio, est.Ratio)
+			}
+		})
+	}
+}
+
+func TestEstimateCompressedSizeEmpty(t *testing.T) {
+	if _, err := EstimateCompressedSize(nil); err == nil {
+		t.Error("expected error for empty input but got nil")
+	}
+}
+
+func TestCodeLengths(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		wantLens map[byte]int
+		wantHist map[int]int
+	}{
+		// c(1) and b(2) merge into 3, which then merges with a(4).
+		{name: "Three symbols", content: []byte("aaaabbc"), wantLens: map[byte]int{'a': 1, 'b': 2, 'c': 2}, wantHist: map[int]int{1: 1, 2: 2}},
+		// e(1)+d(2)=3 ties with c(3); merging those gives 6, then b(4)+a(5)=9.
+		{name: "Five symbols", content: []byte("aaaaabbbbcccdde"), wantLens: map[byte]int{'a': 2, 'b': 2, 'c': 2, 'd': 3, 'e': 3}, wantHist: map[int]int{2: 3, 3: 2}},
+		{name: "Single symbol", content: []byte("zzz"), wantLens: map[byte]int{'z': 1}, wantHist: map[int]int{1: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lens, err := CodeLengths(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(lens, tt.wantLens) {
+				t.Errorf("CodeLengths = %v, want %v", lens, tt.wantLens)
+			}
+			hist, err := CodeLengthHistogram(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(hist, tt.wantHist) {
+				t.Errorf("CodeLengthHistogram = %v, want %v", hist, tt.wantHist)
+			}
+		})
+	}
+
+	if _, err := CodeLengths(nil); err == nil {
+		t.Error("expected error for empty input but got nil")
+	}
+}
+
+func TestPresizedOutput(t *testing.T) {
+	rng := rand.New(rand.NewSource(33))
+	data := make([]byte, 1<<16)
+	for i := range data {
+		data[i] = byte(rng.NormFloat64()*16 + 128)
+	}
+	model := buildFrequencyTable(data)
+	lengths, err := CodeLengths(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	totalBits := 0
+	for b, f := range model {
+		totalBits += f * lengths[b]
+	}
+
+	tests := []struct {
+		name       string
+		compress   func([]byte) ([]byte, error)
+		decompress func([]byte) ([]byte, error)
+		wantSize   int
+	}{
+		{name: "Huffman", compress: HuffmanCompressBytes, wantSize: huffmanBlobSize(len(model), totalBits)},
+		{name: "Limited", compress: func(d []byte) ([]byte, error) { return HuffmanCompressLimited(d, MaxCodeLength) }},
+		{
+			name:       "Model",
+			compress:   func(d []byte) ([]byte, error) { return HuffmanCompressWithModel(d, model) },
+			decompress: func(b []byte) ([]byte, error) { return HuffmanDecompressWithModel(b, model) },
+			wantSize:   containerHeaderSize + 8 + (totalBits+7)/8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := tt.compress(data)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if tt.wantSize != 0 && len(blob) != tt.wantSize {
+				t.Errorf("expected %d-byte blob, got %d", tt.wantSize, len(blob))
+			}
+			decompress := tt.decompress
+			if decompress == nil {
+				decompress = Decompress
+			}
+			got, err := decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("decompressed output does not match original")
+			}
+		})
+	}
+}
+
+func BenchmarkCompressPresized(b *testing.B) {
+	rng := rand.New(rand.NewSource(33))
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(rng.NormFloat64()*16 + 128)
+	}
+	model := buildFrequencyTable(data)
+
+	b.Run("Limited", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := HuffmanCompressLimited(data, MaxCodeLength); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Model", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := HuffmanCompressWithModel(data, model); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// fibonacciInput returns symbols 0..k-1 with Fibonacci frequencies 1, 1, 2,
+// 3, 5, ..., which make every merge take the previous subtree and one more
+// leaf, giving a tree of depth k-1.
+func fibonacciInput(k int) []byte {
+	var data []byte
+	a, b := 1, 1
+	for s := 0; s < k; s++ {
+		data = append(data, bytes.Repeat([]byte{byte(s)}, a)...)
+		a, b = b, a+b
+	}
+	return data
+}
+
+func TestLongestCodeLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		want        int
+		wantWarning bool
+	}{
+		{name: "Single symbol", content: []byte("aaaa"), want: 1},
+		{name: "Balanced", content: []byte("abcd"), want: 2},
+		{name: "Fibonacci at threshold", content: fibonacciInput(DeepCodeThreshold + 1), want: DeepCodeThreshold},
+		{name: "Fibonacci past threshold", content: fibonacciInput(26), want: 25, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LongestCodeLength(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected longest code %d, got %d", tt.want, got)
+			}
+			warning, depth, err := DeepCodeWarning(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if depth != tt.want || (warning != "") != tt.wantWarning {
+				t.Errorf("expected depth %d and warning %v, got %d and %q", tt.want, tt.wantWarning, depth, warning)
+			}
+			est, err := EstimateCompressedSize(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected estimate error: %v", err)
+			}
+			if est.MaxCodeLength != tt.want || est.Warning != warning {
+				t.Errorf("estimate reports longest code %d and warning %q", est.MaxCodeLength, est.Warning)
+			}
+		})
+	}
+
+	if _, err := LongestCodeLength(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
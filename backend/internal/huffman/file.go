@@ -0,0 +1,199 @@
+//go:build !js && !wasip1
+
+// Filesystem wrappers live behind this constraint so the core codec can be
+// compiled to WebAssembly without pulling in os.
+
+package huffman
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// HuffmanCompress reads filePath, builds Huffman-coded bytes with header+bitlen.
+// It is HuffmanCompressFS over an os.DirFS rooted at the file's directory.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompress(filePath string) ([]byte, error) {
+	return HuffmanCompressFS(os.DirFS(filepath.Dir(filePath)), filepath.Base(filePath))
+}
+
+// HuffmanCompressFile compresses inPath and atomically writes the result to
+// outPath.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressFile(inPath, outPath string) error {
+	compressed, err := HuffmanCompress(inPath)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(outPath, compressed, 0644)
+}
+
+// HuffmanDecompressFile decompresses inPath and atomically writes the result
+// to outPath.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompressFile(inPath, outPath string) error {
+	blob, err := readFileBuffered(inPath)
+	if err != nil {
+		return err
+	}
+	decompressed, err := HuffmanDecompress(blob)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(outPath, decompressed, 0644)
+}
+
+// HuffmanCompressArchiveFromDir archives every regular file under dir, named
+// by its slash-separated path relative to dir and keeping its permission
+// bits and modification time. Directories contribute only their files, and
+// symlinks and other special files are skipped.
+// Time Complexity: O(n + k·m log m) for k files, Space Complexity: O(n)
+func HuffmanCompressArchiveFromDir(dir string) ([]byte, error) {
+	var members []ArchiveMember
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		members = append(members, ArchiveMember{
+			Name:    filepath.ToSlash(rel),
+			Data:    data,
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return HuffmanArchive(members)
+}
+
+// defaultArchiveFileMode applies to extracted members with no recorded
+// permission bits.
+const defaultArchiveFileMode = 0644
+
+// ExtractArchive writes every member of a ModeArchive blob under destDir,
+// creating directories as needed and restoring permission bits and
+// modification times. Members without recorded permissions get 0644 and
+// those without an mtime keep the time of extraction. Member names that
+// would escape destDir, name the same path, or name a path another member
+// needs as a directory are rejected before anything is written.
+// Time Complexity: O(n + k·m log m) for k members, Space Complexity: O(n)
+func ExtractArchive(blob []byte, destDir string) error {
+	members, err := HuffmanArchiveExtract(blob)
+	if err != nil {
+		return err
+	}
+	if err := checkExtractNames(members); err != nil {
+		return err
+	}
+	for _, m := range members {
+		path := filepath.Join(destDir, filepath.FromSlash(m.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		perm := m.Mode.Perm()
+		if perm == 0 {
+			perm = defaultArchiveFileMode
+		}
+		if err := writeFileAtomic(path, m.Data, perm); err != nil {
+			return err
+		}
+		if !m.ModTime.IsZero() {
+			if err := os.Chtimes(path, m.ModTime, m.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkExtractNames checks that every member names a distinct local path,
+// once cleaned, and that no member's path is a parent directory of another.
+// Time Complexity: O(k·d) for k members d directories deep, Space
+// Complexity: O(k·d)
+func checkExtractNames(members []ArchiveMember) error {
+	files := make(map[string]string, len(members))
+	dirs := make(map[string]string)
+	for _, m := range members {
+		path := filepath.FromSlash(m.Name)
+		if !filepath.IsLocal(path) {
+			return fmt.Errorf("archive member %q escapes the destination", m.Name)
+		}
+		path = filepath.Clean(path)
+		if other, ok := files[path]; ok {
+			return fmt.Errorf("archive members %q and %q extract to the same path", other, m.Name)
+		}
+		if other, ok := dirs[path]; ok {
+			return fmt.Errorf("archive member %q is a file and a directory of %q", m.Name, other)
+		}
+		files[path] = m.Name
+		for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+			if other, ok := files[dir]; ok {
+				return fmt.Errorf("archive member %q is a file and a directory of %q", other, m.Name)
+			}
+			dirs[dir] = m.Name
+		}
+	}
+	return nil
+}
+
+// readFileBuffered reads the whole of path through a buffered reader.
+func readFileBuffered(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(bufio.NewReader(f))
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written output. The
+// temporary file is removed on any failure.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,269 @@
+//go:build !js && !wasip1
+
+package huffman
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func createTempFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, name)
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	return tmpFile
+}
+
+func TestHuffmanCompressDecompress(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		shouldError bool
+	}{
+		{
+			name:        "Empty file",
+			content:     []byte(""),
+			shouldError: true,
+		},
+		{
+			name:        "Simple ASCII",
+			content:     []byte("aaaaabbbbcccdde"),
+			shouldError: false,
+		},
+		{
+			name:        "Binary data",
+			content:     []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03},
+			shouldError: false,
+		},
+		{
+			name:        "Long repetitive",
+			content:     []byte("hello world! hello world! hello world! hello world!"),
+			shouldError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTempFile(t, tt.name, tt.content)
+
+			compressed, err := HuffmanCompress(path)
+
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected compress error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if len(compressed) == 0 {
+				t.Fatal("compressed output is empty")
+			}
+
+			decompressed, err := HuffmanDecompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original.\nGot: %v\nWant: %v", decompressed, tt.content)
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressFileRoundTrip(t *testing.T) {
+	content := []byte("hello world! hello world! hello world! hello world!")
+	inPath := createTempFile(t, "input.txt", content)
+	dir := t.TempDir()
+	compressedPath := filepath.Join(dir, "input.txt.huff")
+	outPath := filepath.Join(dir, "output.txt")
+
+	if err := HuffmanCompressFile(inPath, compressedPath); err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if err := HuffmanDecompressFile(compressedPath, outPath); err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("decompressed output does not match original.\nGot: %v\nWant: %v", got, content)
+	}
+
+	info, err := os.Stat(compressedPath)
+	if err != nil {
+		t.Fatalf("failed to stat output: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("expected output permissions 0644, got %o", perm)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected only the two outputs in %s, got %d entries", dir, len(entries))
+	}
+}
+
+func TestHuffmanCompressFileNoPartialOutput(t *testing.T) {
+	inPath := createTempFile(t, "empty.txt", nil)
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "empty.txt.huff")
+
+	if err := HuffmanCompressFile(inPath, outPath); err == nil {
+		t.Fatal("expected compress error for empty input but got nil")
+	}
+
+	corruptPath := createTempFile(t, "corrupt.huff", []byte{0x01})
+	if err := HuffmanDecompressFile(corruptPath, filepath.Join(dir, "corrupt.txt")); err == nil {
+		t.Fatal("expected decompress error for corrupt input but got nil")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no output after failures, found %d entries", len(entries))
+	}
+}
+
+func TestArchiveDirRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	files := []struct {
+		name  string
+		data  []byte
+		mode  os.FileMode
+		mtime time.Time
+	}{
+		{name: "notes.txt", data: []byte("hello world! hello world!"), mode: 0600, mtime: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "bin/run.sh", data: []byte("#!/bin/sh\nexit 0\n"), mode: 0755, mtime: time.Date(2019, 6, 7, 8, 9, 10, 0, time.UTC)},
+		{name: "bin/deep/empty", data: nil, mode: 0640, mtime: time.Date(2018, 11, 12, 13, 14, 15, 0, time.UTC)},
+	}
+	for _, f := range files {
+		path := filepath.Join(src, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.data, f.mode); err != nil {
+			t.Fatal(err)
+		}
+		// Set the mode explicitly, since WriteFile is subject to the umask.
+		if err := os.Chmod(path, f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, f.mtime, f.mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, err := HuffmanCompressArchiveFromDir(src)
+	if err != nil {
+		t.Fatalf("unexpected archive error: %v", err)
+	}
+	dest := t.TempDir()
+	if err := ExtractArchive(archive, dest); err != nil {
+		t.Fatalf("unexpected extract error: %v", err)
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dest, filepath.FromSlash(f.name))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("%s: %v", f.name, err)
+		}
+		if info.Mode().Perm() != f.mode {
+			t.Errorf("%s: expected mode %v, got %v", f.name, f.mode, info.Mode().Perm())
+		}
+		if !info.ModTime().Equal(f.mtime) {
+			t.Errorf("%s: expected mtime %v, got %v", f.name, f.mtime, info.ModTime())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, f.data) {
+			t.Errorf("%s: extracted data does not match original", f.name)
+		}
+	}
+}
+
+func TestExtractArchiveDefaults(t *testing.T) {
+	before := time.Now().Add(-time.Minute)
+	dest := t.TempDir()
+	if err := ExtractArchive(legacyArchive(t, []ArchiveMember{{Name: "old.txt", Data: []byte("from an older archive")}}), dest); err != nil {
+		t.Fatalf("unexpected extract error: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dest, "old.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != defaultArchiveFileMode {
+		t.Errorf("expected default mode %v, got %v", os.FileMode(defaultArchiveFileMode), info.Mode().Perm())
+	}
+	if info.ModTime().Before(before) {
+		t.Errorf("expected extraction time as mtime, got %v", info.ModTime())
+	}
+}
+
+func TestExtractArchiveRejectsEscapingNames(t *testing.T) {
+	for _, name := range []string{"../evil", "/etc/evil", "a/../../evil"} {
+		archive, err := HuffmanArchive([]ArchiveMember{{Name: "ok", Data: []byte("ok")}, {Name: name, Data: []byte("x")}})
+		if err != nil {
+			t.Fatalf("unexpected archive error: %v", err)
+		}
+		dest := t.TempDir()
+		if err := ExtractArchive(archive, dest); err == nil {
+			t.Errorf("%q: expected error but got nil", name)
+		}
+		if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+			t.Errorf("%q: expected nothing extracted, found %d entries", name, len(entries))
+		}
+	}
+}
+
+func TestExtractArchiveRejectsConflictingNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+	}{
+		{name: "Same path once cleaned", names: []string{"a/b", "a//b"}},
+		{name: "Dot prefix", names: []string{"a", "./a"}},
+		{name: "File then directory", names: []string{"a", "a/b"}},
+		{name: "Directory then file", names: []string{"a/b/c", "a/b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var members []ArchiveMember
+			for _, name := range tt.names {
+				members = append(members, ArchiveMember{Name: name, Data: []byte(name)})
+			}
+			archive, err := HuffmanArchive(members)
+			if err != nil {
+				t.Fatalf("unexpected archive error: %v", err)
+			}
+			dest := t.TempDir()
+			if err := ExtractArchive(archive, dest); err == nil {
+				t.Errorf("%q: expected error but got nil", tt.names)
+			}
+			if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+				t.Errorf("%q: expected nothing extracted, found %d entries", tt.names, len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+// formatFixture is the documented encoding of "aaabc": the container header,
+// three header entries in ascending symbol order, 7 payload bits, and the
+// codes a=1 b=00 c=01 packed as 1110001(0).
+var formatFixture = []byte{
+	'H', 'U', 'F', 'M', // magic
+	0x02,       // format version
+	0x01,       // ModeHuffman
+	0x03, 0x00, // 3 entries
+	'a', 0x03, 0x00, 0x00, 0x00, // a: 3
+	'b', 0x01, 0x00, 0x00, 0x00, // b: 1
+	'c', 0x01, 0x00, 0x00, 0x00, // c: 1
+	0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 7 bits
+	0xE2, // 1110001 + one padding bit
+}
+
+func TestFormatFixtureDecodes(t *testing.T) {
+	got, err := HuffmanDecompress(formatFixture)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if want := []byte("aaabc"); !bytes.Equal(got, want) {
+		t.Errorf("decoded fixture = %q, want %q", got, want)
+	}
+}
+
+func TestFormatFixtureEncodes(t *testing.T) {
+	got, err := HuffmanCompressBytes([]byte("aaabc"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if !bytes.Equal(got, formatFixture) {
+		t.Errorf("encoding drifted from the documented format.\nGot:  % x\nWant: % x", got, formatFixture)
+	}
+}
@@ -0,0 +1,112 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// FrequencyTable maps each byte value to the number of times it occurs.
+// Tables counted over separate shards of an input can be merged into the
+// table of the whole input, so workers can count in parallel and a
+// coordinator build one tree whose codes every shard is encoded with.
+type FrequencyTable map[byte]int
+
+// CountFrequencies counts the bytes of data into a new FrequencyTable.
+// Time Complexity: O(n), Space Complexity: O(m)
+func CountFrequencies(data []byte) FrequencyTable {
+	return buildFrequencyTable(data)
+}
+
+// Merge adds the counts of other to t. Merging is commutative, so shards may
+// be merged in any order. t must not be nil.
+// Time Complexity: O(m), Space Complexity: O(m)
+func (t FrequencyTable) Merge(other FrequencyTable) {
+	for b, f := range other {
+		t[b] += f
+	}
+}
+
+// MarshalBinary serializes t in the frequency table format of a ModeHuffman
+// header. Counts must fit in 32 bits, so very large merged tables may need
+// scaling down first.
+// Time Complexity: O(m), Space Complexity: O(m)
+func (t FrequencyTable) MarshalBinary() ([]byte, error) {
+	return writeHeader(t)
+}
+
+// UnmarshalBinary replaces t with a table written by MarshalBinary.
+// Time Complexity: O(m), Space Complexity: O(m)
+func (t *FrequencyTable) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	freq, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return corruptf("invalid header: %d trailing bytes", r.Len())
+	}
+	if err := validateFrequencyTable(freq); err != nil {
+		return corruptf("%w", err)
+	}
+	*t = freq
+	return nil
+}
+
+// MarshalJSON encodes t as an object mapping each symbol, written as a
+// "0x%02x" string like the symbols of TreeJSON, to its count. The fixed-width
+// keys sort in symbol order, so equal tables always encode identically.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func (t FrequencyTable) MarshalJSON() ([]byte, error) {
+	keyed := make(map[string]int, len(t))
+	for b, f := range t {
+		keyed[symbolKey(b)] = f
+	}
+	return json.Marshal(keyed)
+}
+
+// UnmarshalJSON replaces t with a table encoded by MarshalJSON. Every count
+// must be positive and fit in 32 bits.
+// Time Complexity: O(m), Space Complexity: O(m)
+func (t *FrequencyTable) UnmarshalJSON(data []byte) error {
+	var keyed map[string]int
+	if err := json.Unmarshal(data, &keyed); err != nil {
+		return err
+	}
+	freq := make(FrequencyTable, len(keyed))
+	for k, f := range keyed {
+		if len(k) != 4 || k[:2] != "0x" {
+			return fmt.Errorf("invalid frequency table symbol %q (want 0x00-0xff)", k)
+		}
+		b, err := strconv.ParseUint(k[2:], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid frequency table symbol %q (want 0x00-0xff)", k)
+		}
+		if _, dup := freq[byte(b)]; dup {
+			return fmt.Errorf("invalid frequency table: duplicate symbol %q", k)
+		}
+		freq[byte(b)] = f
+	}
+	if err := validateFrequencyTable(freq); err != nil {
+		return err
+	}
+	*t = freq
+	return nil
+}
+
+// BuildTreeFromTable builds the Huffman tree for t. The tree is the same one
+// a single pass over the whole input would build.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func BuildTreeFromTable(t FrequencyTable) (*Node, error) {
+	return buildModelTree(t)
+}
+
+// CompressWithTable encodes one shard with the codes of t, typically the
+// merged table of every shard, so all shards share one set of codes. The
+// result is a ModeModel blob, which HuffmanDecompressWithModel decodes given
+// the same table. Every symbol in data must appear in t.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func CompressWithTable(data []byte, t FrequencyTable) ([]byte, error) {
+	return HuffmanCompressWithModel(data, t)
+}
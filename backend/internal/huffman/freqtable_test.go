@@ -0,0 +1,126 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFrequencyTableMerge(t *testing.T) {
+	input := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 40)
+	tests := []struct {
+		name   string
+		shards int
+	}{
+		{name: "One shard", shards: 1},
+		{name: "Three shards", shards: 3},
+		{name: "Uneven shards", shards: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shards [][]byte
+			size := (len(input) + tt.shards - 1) / tt.shards
+			for start := 0; start < len(input); start += size {
+				shards = append(shards, input[start:min(start+size, len(input))])
+			}
+
+			// Merge in reverse to check the order does not matter.
+			merged := FrequencyTable{}
+			for i := len(shards) - 1; i >= 0; i-- {
+				merged.Merge(CountFrequencies(shards[i]))
+			}
+			if !reflect.DeepEqual(merged, CountFrequencies(input)) {
+				t.Fatalf("merged table does not match a single-pass count")
+			}
+
+			root, err := BuildTreeFromTable(merged)
+			if err != nil {
+				t.Fatalf("unexpected build error: %v", err)
+			}
+			if !reflect.DeepEqual(root, buildHuffmanTree(buildFrequencyTable(input))) {
+				t.Errorf("merged tree does not match a single-pass build")
+			}
+
+			for i, shard := range shards {
+				compressed, err := CompressWithTable(shard, merged)
+				if err != nil {
+					t.Fatalf("shard %d: unexpected compress error: %v", i, err)
+				}
+				decompressed, err := HuffmanDecompressWithModel(compressed, merged)
+				if err != nil {
+					t.Fatalf("shard %d: unexpected decompress error: %v", i, err)
+				}
+				if !bytes.Equal(decompressed, shard) {
+					t.Errorf("shard %d: decompressed output does not match original", i)
+				}
+			}
+		})
+	}
+}
+
+func TestFrequencyTableBinary(t *testing.T) {
+	table := CountFrequencies([]byte("abracadabra"))
+	data, err := table.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var got FrequencyTable
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(got, table) {
+		t.Errorf("expected %v, got %v", table, got)
+	}
+
+	if err := got.UnmarshalBinary(append(data, 0)); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for trailing bytes, got %v", err)
+	}
+	zero := []byte{1, 0, 'a', 0, 0, 0, 0}
+	if err := got.UnmarshalBinary(zero); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a zero count, got %v", err)
+	}
+	if _, err := (FrequencyTable{'a': 1 << 32}).MarshalBinary(); err == nil {
+		t.Error("expected error for a count overflowing 32 bits but got nil")
+	}
+	if _, err := BuildTreeFromTable(FrequencyTable{}); err == nil {
+		t.Error("expected error for an empty table but got nil")
+	}
+}
+
+func TestFrequencyTableJSON(t *testing.T) {
+	table := CountFrequencies([]byte("hello, world\n\x00\xff"))
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	want := `{"0x00":1,"0x0a":1,"0x20":1,"0x2c":1,"0x64":1,"0x65":1,"0x68":1,"0x6c":3,"0x6f":2,"0x72":1,"0x77":1,"0xff":1}`
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+
+	var got FrequencyTable
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(got, table) {
+		t.Errorf("expected %v, got %v", table, got)
+	}
+
+	for _, bad := range []string{
+		`{"a":1}`,
+		`{"0x100":1}`,
+		`{"0xzz":1}`,
+		`{"0x61":0}`,
+		`{"0x61":-2}`,
+		`{"0x61":4294967296}`,
+		`{"0x6a":1,"0x6A":1}`,
+		`[1,2]`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &got); err == nil {
+			t.Errorf("%s: expected error but got nil", bad)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package huffman
+
+import "io/fs"
+
+// HuffmanCompressFS reads name from fsys and compresses it, so embedded,
+// in-memory and test filesystems can be compressed like files on disk.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressFS(fsys fs.FS, name string) ([]byte, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return HuffmanCompressBytes(data)
+}
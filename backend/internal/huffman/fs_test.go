@@ -0,0 +1,36 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHuffmanCompressFS(t *testing.T) {
+	content := []byte("hello from a virtual file! hello from a virtual file!")
+	fsys := fstest.MapFS{
+		"fixtures/hello.txt": {Data: content},
+		"empty.txt":          {Data: nil},
+	}
+
+	compressed, err := HuffmanCompressFS(fsys, "fixtures/hello.txt")
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(decompressed, content) {
+		t.Errorf("decompressed output does not match original.\nGot: %q\nWant: %q", decompressed, content)
+	}
+
+	if _, err := HuffmanCompressFS(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := HuffmanCompressFS(fsys, "empty.txt"); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
@@ -2,13 +2,23 @@ package huffman
 
 import (
 	"bytes"
-	"container/heap"
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
+	"math"
+	"slices"
 )
 
+// FormatVersion identifies the layout of the blobs produced by this package.
+// It is bumped whenever the wire format changes incompatibly.
+const FormatVersion = 2
+
+// byteOrder is the byte order of every multi-byte integer in the wire format.
+var byteOrder = binary.LittleEndian
+
+// Node is a Huffman tree node. MinChar is the smallest symbol in the node's
+// subtree; it breaks frequency ties so the same frequency table always yields
+// the same tree, regardless of map iteration order.
 type Node struct {
 	Char    byte
 	Freq    int
@@ -17,25 +27,30 @@ type Node struct {
 	Right   *Node
 }
 
-type PriorityQueue []*Node
+// TieBreaker orders two tree nodes of equal frequency during tree
+// construction, reporting whether a should be merged before b. It must be a
+// strict order that distinguishes every pair of distinct nodes, or the tree
+// depends on the order symbols are listed in.
+type TieBreaker func(a, b *Node) bool
 
-func (pq PriorityQueue) Len() int { return len(pq) }
-func (pq PriorityQueue) Less(i, j int) bool {
-	if pq[i].Freq != pq[j].Freq {
-		return pq[i].Freq < pq[j].Freq
-	}
-	return pq[i].MinChar < pq[j].MinChar
+// TieBreakMinChar is the default TieBreaker: the node whose subtree holds the
+// smaller symbol goes first.
+func TieBreakMinChar(a, b *Node) bool {
+	return a.MinChar < b.MinChar
 }
-func (pq PriorityQueue) Swap(i, j int) { pq[i], pq[j] = pq[j], pq[i] }
-func (pq *PriorityQueue) Push(x interface{}) {
-	*pq = append(*pq, x.(*Node))
+
+// nodeLess orders nodes by frequency, breaking ties by the smallest symbol in
+// each subtree so tree construction is deterministic.
+func nodeLess(a, b *Node) bool {
+	return nodeLessBy(a, b, TieBreakMinChar)
 }
-func (pq *PriorityQueue) Pop() interface{} {
-	old := *pq
-	n := len(old)
-	item := old[n-1]
-	*pq = old[:n-1]
-	return item
+
+// nodeLessBy orders nodes by frequency, breaking ties with tieBreak.
+func nodeLessBy(a, b *Node, tieBreak TieBreaker) bool {
+	if a.Freq != b.Freq {
+		return a.Freq < b.Freq
+	}
+	return tieBreak(a, b)
 }
 
 // buildFrequencyTable counts byte frequencies in data.
@@ -51,40 +66,98 @@ func buildFrequencyTable(data []byte) map[byte]int {
 // buildHuffmanTree builds a Huffman tree from frequency table deterministically.
 // Time Complexity: O(m log m), Space Complexity: O(m) where m is unique byte count (<= 256)
 func buildHuffmanTree(freq map[byte]int) *Node {
-	if len(freq) == 0 {
+	var counts [256]int
+	symbols := make([]byte, 0, len(freq))
+	for b, f := range freq {
+		symbols = append(symbols, b)
+		counts[b] = f
+	}
+	var tb treeBuilder
+	return tb.build(symbols, &counts)
+}
+
+// treeBuilder holds the storage for building Huffman trees so a caller
+// building many can reuse it. Trees from one build share its node slab and
+// are only valid until the next build. tieBreak orders nodes of equal
+// frequency; nil means TieBreakMinChar.
+type treeBuilder struct {
+	slab     []Node
+	leaves   []*Node
+	merged   []*Node
+	tieBreak TieBreaker
+}
+
+// build builds the Huffman tree of symbols, the dense list of present
+// symbols, weighted by counts. It uses the two-queue construction: leaves
+// sorted by frequency in one queue and merged nodes in another. Merged nodes
+// are produced in nondecreasing order, so the next smallest node is always at
+// the front of one of the two.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func (tb *treeBuilder) build(symbols []byte, counts *[256]int) *Node {
+	if len(symbols) == 0 {
 		return nil
 	}
-	pq := &PriorityQueue{}
-	heap.Init(pq)
-	for b, f := range freq {
-		node := &Node{Char: b, Freq: f, MinChar: b}
-		heap.Push(pq, node)
+	tieBreak := tb.tieBreak
+	if tieBreak == nil {
+		tieBreak = TieBreakMinChar
+	}
+	less := func(a, b *Node) bool { return nodeLessBy(a, b, tieBreak) }
+	// Every node of the tree is carved out of one allocation, which must not
+	// grow while nodes point into it.
+	if need := 2*len(symbols) - 1; cap(tb.slab) < need {
+		tb.slab = make([]Node, 0, need)
+	}
+	slab := tb.slab[:0]
+	alloc := func(n Node) *Node {
+		slab = append(slab, n)
+		return &slab[len(slab)-1]
 	}
-	for pq.Len() > 1 {
-		left := heap.Pop(pq).(*Node)
-		right := heap.Pop(pq).(*Node)
-		minChar := left.MinChar
-		if right.MinChar < minChar {
-			minChar = right.MinChar
+	leaves := tb.leaves[:0]
+	for _, b := range symbols {
+		leaves = append(leaves, alloc(Node{Char: b, Freq: counts[b], MinChar: b}))
+	}
+	slices.SortFunc(leaves, func(a, b *Node) int {
+		if less(a, b) {
+			return -1
+		}
+		return 1
+	})
+
+	merged := tb.merged[:0]
+	li, mi := 0, 0
+	next := func() *Node {
+		if li < len(leaves) && (mi == len(merged) || less(leaves[li], merged[mi])) {
+			li++
+			return leaves[li-1]
 		}
-		merged := &Node{
+		mi++
+		return merged[mi-1]
+	}
+	for (len(leaves)-li)+(len(merged)-mi) > 1 {
+		left := next()
+		right := next()
+		merged = append(merged, alloc(Node{
 			Freq:    left.Freq + right.Freq,
-			MinChar: minChar,
+			MinChar: min(left.MinChar, right.MinChar),
 			Left:    left,
 			Right:   right,
-		}
-		heap.Push(pq, merged)
+		}))
 	}
-	return heap.Pop(pq).(*Node)
+	tb.slab, tb.leaves, tb.merged = slab, leaves, merged
+	return next()
 }
 
 // generateCodes populates codeMap with bit-strings for each leaf.
+// A tree consisting of a single leaf gets the one-bit code "0".
 // Time Complexity: O(m), Space Complexity: O(m)
 func generateCodes(root *Node, prefix string, codeMap map[byte]string) {
 	if root == nil {
 		return
 	}
 	if root.Left == nil && root.Right == nil {
+		if prefix == "" {
+			prefix = "0"
+		}
 		codeMap[root.Char] = prefix
 		return
 	}
@@ -92,131 +165,339 @@ func generateCodes(root *Node, prefix string, codeMap map[byte]string) {
 	generateCodes(root.Right, prefix+"1", codeMap)
 }
 
-// encodeDataWithCount encodes data, returns bytes and total bit count.
+// code is a packed Huffman code: the low length bits of bits, most
+// significant bit first. Header frequencies are at most 32 bits wide, which
+// bounds tree depth well below 64.
+type code struct {
+	bits   uint64
+	length uint8
+}
+
+// codeTable holds the code for every byte value; absent symbols have length 0.
+type codeTable [256]code
+
+// buildCodeTable populates table with packed codes for each leaf, matching
+// generateCodes.
+// Time Complexity: O(m), Space Complexity: O(m)
+func buildCodeTable(root *Node, bits uint64, depth uint8, table *codeTable) {
+	if root == nil {
+		return
+	}
+	if root.Left == nil && root.Right == nil {
+		table[root.Char] = code{bits: bits, length: max(depth, 1)}
+		return
+	}
+	buildCodeTable(root.Left, bits<<1, depth+1, table)
+	buildCodeTable(root.Right, bits<<1|1, depth+1, table)
+}
+
+// encodeDataWithCount appends the packed codes for data to buf and returns
+// the total bit count. The last byte is padded with zero bits, which decoders
+// never read because they stop after the returned count. It fails if a symbol
+// in data has no code in codes, rather than silently emitting nothing for it.
+// If progress is non-nil it is called with the number of symbols encoded so
+// far every progressInterval symbols and once at the end.
 // Time Complexity: O(n), Space Complexity: O(n)
-func encodeDataWithCount(data []byte, codeMap map[byte]string) ([]byte, int, error) {
-	var buf bytes.Buffer
-	var bitBuf byte
-	var bitCount uint8
-	var totalBits int
+func encodeDataWithCount(buf *bytes.Buffer, data []byte, codes *codeTable, progress func(done int)) (int, error) {
+	bw := newBitWriter(buf)
+	for i, b := range data {
+		if progress != nil && i > 0 && i%progressInterval == 0 {
+			progress(i)
+		}
+		if codes[b].length == 0 {
+			return 0, fmt.Errorf("encode data failed: symbol 0x%02x at offset %d has no code", b, i)
+		}
+		if err := bw.writeCode(codes[b]); err != nil {
+			return 0, fmt.Errorf("encode data failed: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("encode data failed: %w", err)
+	}
+	if progress != nil {
+		progress(len(data))
+	}
+	return bw.Written(), nil
+}
 
-	for _, b := range data {
-		code := codeMap[b]
-		for _, bit := range code {
-			if bit == '1' {
-				bitBuf |= 1 << (7 - bitCount)
-			}
-			bitCount++
-			totalBits++
-			if bitCount == 8 {
-				buf.WriteByte(bitBuf)
-				bitBuf = 0
-				bitCount = 0
-			}
+// headerSize returns the serialized size of a frequency table with m entries.
+func headerSize(m int) int {
+	return 2 + m*(1+4)
+}
+
+// validateFrequencyTable checks that freq fits the wire format: each entry
+// needs a frequency between 1 and math.MaxUint32, since zero-frequency
+// symbols would become useless leaves and larger counts do not fit a header
+// entry.
+// Time Complexity: O(m), Space Complexity: O(1)
+func validateFrequencyTable(freq map[byte]int) error {
+	for b, f := range freq {
+		if f <= 0 {
+			return fmt.Errorf("invalid frequency table: symbol 0x%02x has frequency %d", b, f)
+		}
+		if err := checkFrequencyWidth(b, f); err != nil {
+			return err
 		}
 	}
-	if bitCount > 0 {
-		buf.WriteByte(bitBuf)
+	return nil
+}
+
+// checkFrequencyWidth reports a frequency too large for a header entry.
+func checkFrequencyWidth(b byte, f int) error {
+	if uint64(f) > math.MaxUint32 {
+		return fmt.Errorf("invalid frequency table: symbol 0x%02x frequency %d overflows 32 bits", b, f)
 	}
-	return buf.Bytes(), totalBits, nil
+	return nil
 }
 
-// writeHeader serializes frequency table.
+// writeHeader serializes a valid frequency table in ascending symbol order.
 // Time Complexity: O(m), Space Complexity: O(m)
 func writeHeader(freq map[byte]int) ([]byte, error) {
-	var buf bytes.Buffer
-	if err := binary.Write(&buf, binary.LittleEndian, uint16(len(freq))); err != nil {
+	if err := validateFrequencyTable(freq); err != nil {
 		return nil, err
 	}
-	for b, f := range freq {
-		buf.WriteByte(b)
-		if err := binary.Write(&buf, binary.LittleEndian, uint32(f)); err != nil {
-			return nil, err
+	var counts [256]int
+	symbols := make([]byte, 0, len(freq))
+	// Entries are written in ascending symbol order so identical inputs
+	// always produce identical blobs.
+	for b := 0; b < 256; b++ {
+		if f, ok := freq[byte(b)]; ok {
+			symbols = append(symbols, byte(b))
+			counts[b] = f
 		}
 	}
-	return buf.Bytes(), nil
+	return appendHeader(make([]byte, 0, headerSize(len(freq))), symbols, &counts), nil
 }
 
-// HuffmanCompress reads filePath, builds Huffman-coded bytes with header+bitlen.
-// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
-func HuffmanCompress(filePath string) ([]byte, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-	if len(data) == 0 {
-		return nil, fmt.Errorf("cannot compress empty file")
-	}
-	freqTable := buildFrequencyTable(data)
-	root := buildHuffmanTree(freqTable)
-	codeMap := make(map[byte]string)
-	generateCodes(root, "", codeMap)
-	encoded, totalBits, err := encodeDataWithCount(data, codeMap)
-	if err != nil {
-		return nil, err
+// appendHeader appends the frequency table of symbols, which must be in
+// ascending order and have valid counts, to buf.
+// Time Complexity: O(m), Space Complexity: O(m)
+func appendHeader(buf []byte, symbols []byte, counts *[256]int) []byte {
+	buf = byteOrder.AppendUint16(buf, uint16(len(symbols)))
+	for _, b := range symbols {
+		buf = append(buf, b)
+		buf = byteOrder.AppendUint32(buf, uint32(counts[b]))
 	}
-	head, err := writeHeader(freqTable)
+	return buf
+}
+
+// HuffmanCompressBytes builds a ModeHuffman blob: the container header, then
+// the frequency table, bit length and packed codes for data.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressBytes(data []byte) ([]byte, error) {
+	s := getEncodeScratch()
+	defer putEncodeScratch(s)
+	return s.compress(nil, data, nil)
+}
+
+// HuffmanCompressInto is like HuffmanCompressBytes but appends the blob to
+// dst, growing it only if its spare capacity is too small, and returns the
+// extended slice. Callers that reuse dst across calls compress without
+// allocating.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressInto(dst, data []byte) ([]byte, error) {
+	s := getEncodeScratch()
+	defer putEncodeScratch(s)
+	return s.compress(dst, data, nil)
+}
+
+// HuffmanCompressVerbose compresses data like HuffmanCompressBytes and also
+// returns the code assigned to each symbol as a string of '0' and '1'. A
+// single-symbol input reports the one-bit code "0" it is encoded with.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressVerbose(data []byte) ([]byte, map[byte]string, error) {
+	compressed, err := HuffmanCompressBytes(data)
 	if err != nil {
-		return nil, err
-	}
-	var out bytes.Buffer
-	out.Write(head)
-	if err := binary.Write(&out, binary.LittleEndian, uint64(totalBits)); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	out.Write(encoded)
-	return out.Bytes(), nil
+	codes := make(map[byte]string)
+	generateCodes(buildHuffmanTree(buildFrequencyTable(data)), "", codes)
+	return compressed, codes, nil
 }
 
-// HuffmanDecompress reads header+bitlen+data.
-// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
-func HuffmanDecompress(blob []byte) ([]byte, error) {
-	r := bytes.NewReader(blob)
+// readHeader parses a serialized frequency table as written by writeHeader.
+// Time Complexity: O(m), Space Complexity: O(m)
+func readHeader(r *bytes.Reader) (map[byte]int, error) {
 	var numEntries uint16
-	if err := binary.Read(r, binary.LittleEndian, &numEntries); err != nil {
-		return nil, fmt.Errorf("read header entries failed: %v", err)
+	if err := binary.Read(r, byteOrder, &numEntries); err != nil {
+		return nil, corruptf("read header entries failed: %w", err)
+	}
+	if numEntries > 256 {
+		return nil, corruptf("invalid header: %d entries exceeds 256 symbols", numEntries)
 	}
 	freq := make(map[byte]int)
 	for i := 0; i < int(numEntries); i++ {
 		b, err := r.ReadByte()
 		if err != nil {
-			return nil, fmt.Errorf("read header byte failed: %v", err)
+			return nil, corruptf("read header byte failed: %w", err)
+		}
+		if _, dup := freq[b]; dup {
+			return nil, corruptf("invalid header: duplicate symbol 0x%02x", b)
 		}
 		var count uint32
-		if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
-			return nil, fmt.Errorf("read header freq failed: %v", err)
+		if err := binary.Read(r, byteOrder, &count); err != nil {
+			return nil, corruptf("read header freq failed: %w", err)
 		}
 		freq[b] = int(count)
 	}
+	return freq, nil
+}
+
+// HuffmanDecompress decodes any blob produced by this package. It is
+// equivalent to Decompress, and to HuffmanDecompressOpts with zero
+// DecompressOptions, and kept for existing callers.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompress(blob []byte) ([]byte, error) {
+	return Decompress(blob)
+}
+
+// IsByteFaithful reports that every codec in this package is byte-faithful:
+// it codes bytes, not characters, and Decompress returns exactly the bytes
+// that were compressed. Multibyte UTF-8 sequences, or bytes that are not
+// valid UTF-8 at all, come back unchanged, and no text encoding is assumed
+// or checked. It always returns true; HuffmanCompressASCII, the one
+// alphabet-restricted codec, rejects other bytes rather than altering them.
+func IsByteFaithful() bool {
+	return true
+}
+
+// readHuffmanBody parses the header and bit length of a ModeHuffman body,
+// returning the frequency table, its tree, the bit count and the payload.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readHuffmanBody(body []byte) (map[byte]int, *Node, uint64, []byte, error) {
+	r := bytes.NewReader(body)
+	freq, err := readHeader(r)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
 	var totalBits uint64
-	if err := binary.Read(r, binary.LittleEndian, &totalBits); err != nil {
-		return nil, fmt.Errorf("read bit length failed: %v", err)
+	if err := binary.Read(r, byteOrder, &totalBits); err != nil {
+		return nil, nil, 0, nil, corruptf("read bit length failed: %w", err)
+	}
+	if len(freq) == 0 && totalBits > 0 {
+		return nil, nil, 0, nil, corruptf("invalid header: empty symbol table with nonzero payload of %d bits", totalBits)
 	}
 	root := buildHuffmanTree(freq)
 	if root == nil {
-		return nil, fmt.Errorf("invalid tree")
+		return nil, nil, 0, nil, corruptf("invalid tree")
 	}
-	bitData, err := io.ReadAll(r)
+	if err := checkLeaves(root, freq); err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return freq, root, totalBits, body[len(body)-r.Len():], nil
+}
+
+// checkLeaves checks that the tree rebuilt from a header's freq has exactly
+// one reachable leaf per declared symbol and no others, so no symbol the
+// header promises is silently undecodable.
+// Time Complexity: O(m), Space Complexity: O(m)
+func checkLeaves(root *Node, freq map[byte]int) error {
+	var seen [256]bool
+	leaves := 0
+	var walk func(n *Node) error
+	walk = func(n *Node) error {
+		if n.Left == nil && n.Right == nil {
+			if _, ok := freq[n.Char]; !ok {
+				return corruptf("invalid tree: leaf 0x%02x is not in the header", n.Char)
+			}
+			if seen[n.Char] {
+				return corruptf("invalid tree: symbol 0x%02x has more than one leaf", n.Char)
+			}
+			seen[n.Char] = true
+			leaves++
+			return nil
+		}
+		if n.Left == nil || n.Right == nil {
+			return corruptf("invalid tree: internal node with one child")
+		}
+		if err := walk(n.Left); err != nil {
+			return err
+		}
+		return walk(n.Right)
+	}
+	if err := walk(root); err != nil {
+		return err
+	}
+	if leaves != len(freq) {
+		return corruptf("invalid tree: %d leaves for %d header entries", leaves, len(freq))
+	}
+	return nil
+}
+
+// decodeHuffmanBody reads header+bitlen+data from a ModeHuffman body and
+// appends the output to dst. The header frequencies sum to the output size,
+// so blobs that would exceed maxSize are rejected before any decoding and
+// dst grows once up front. strict is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeHuffmanBody(dst, body []byte, maxSize int, strict bool) ([]byte, error) {
+	freq, root, totalBits, bitData, err := readHuffmanBody(body)
 	if err != nil {
-		return nil, fmt.Errorf("read encoded data failed: %v", err)
+		return nil, err
 	}
-	var out []byte
+	return decodeHuffmanPayload(dst, freq, root, totalBits, bitData, maxSize, strict)
+}
+
+// decodeHuffmanPayload decodes the payload of a body whose frequency table
+// freq has already been read and built into root, as decodeHuffmanBody
+// does for ModeHuffman.
+// Time Complexity: O(n + m), Space Complexity: O(n + m)
+func decodeHuffmanPayload(dst []byte, freq map[byte]int, root *Node, totalBits uint64, bitData []byte, maxSize int, strict bool) ([]byte, error) {
+	size := 0
+	for _, f := range freq {
+		size += f
+	}
+	if size > maxSize {
+		return nil, sizeLimitError(maxSize)
+	}
+	if err := checkPayload(bitData, totalBits, strict); err != nil {
+		return nil, err
+	}
+	// Every symbol takes at least one bit, so a header claiming more output
+	// than the payload could hold cannot make dst grow past the payload.
+	dst = slices.Grow(dst, int(min(uint64(size), totalBits)))
+	return newDecodeTable(root, tableBitsFor(root, freq)).decode(dst, bitData, totalBits, maxSize, strict)
+}
+
+// decodeBits decodes the first totalBits bits of bitData with a lookup table
+// for root, failing once the output would exceed maxSize bytes or, if
+// strict, when the last code is cut off.
+// Time Complexity: O(n), Space Complexity: O(n)
+func decodeBits(root *Node, bitData []byte, totalBits uint64, maxSize int, strict bool) ([]byte, error) {
+	return newDecodeTable(root, tableBitsFor(root, nil)).decode(nil, bitData, totalBits, maxSize, strict)
+}
+
+// walkBits walks root for each of the first totalBits bits of bitData,
+// passing every decoded symbol to emit and stopping at the first error it
+// returns. It reports whether the walk ended on a symbol boundary.
+// Time Complexity: O(n), Space Complexity: O(1)
+func walkBits(root *Node, bitData []byte, totalBits uint64, emit func(b byte) error) (bool, error) {
+	br := newBitReader(bitData, totalBits)
 	node := root
-	bitsRead := uint64(0)
-	for i := 0; bitsRead < totalBits; i++ {
-		byteVal := bitData[i]
-		for j := 0; j < 8 && bitsRead < totalBits; j++ {
-			bitsRead++
-			if (byteVal>>(7-j))&1 == 0 {
-				node = node.Left
-			} else {
-				node = node.Right
+	for {
+		bit, err := br.ReadBit()
+		if err == io.EOF {
+			return node == root, nil
+		}
+		if err != nil {
+			return false, truncatedError(br.BitsRead(), totalBits)
+		}
+		if root.Left == nil && root.Right == nil {
+			// Single-symbol tree: every bit encodes one occurrence.
+			if err := emit(root.Char); err != nil {
+				return false, err
 			}
-			if node.Left == nil && node.Right == nil {
-				out = append(out, node.Char)
-				node = root
+			continue
+		}
+		if bit == 0 {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+		if node.Left == nil && node.Right == nil {
+			if err := emit(node.Char); err != nil {
+				return false, err
 			}
+			node = root
 		}
 	}
-	return out, nil
 }
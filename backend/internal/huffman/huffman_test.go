@@ -2,77 +2,535 @@ package huffman
 
 import (
 	"bytes"
-	"os"
-	"path/filepath"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
-func createTempFile(t *testing.T, name string, content []byte) string {
-	t.Helper()
-	tmpDir := t.TempDir()
-	tmpFile := filepath.Join(tmpDir, name)
-	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
-		t.Fatalf("failed to create test file: %v", err)
+type headerEntry struct {
+	sym  byte
+	freq uint32
+}
+
+// craftBlob assembles a blob from raw header fields so tests can produce
+// inputs the encoder would never emit.
+func craftBlob(numEntries uint16, entries []headerEntry, totalBits uint64, payload []byte) []byte {
+	var buf bytes.Buffer
+	writeContainerHeader(&buf, ModeHuffman)
+	binary.Write(&buf, byteOrder, numEntries)
+	for _, e := range entries {
+		buf.WriteByte(e.sym)
+		binary.Write(&buf, byteOrder, e.freq)
 	}
-	return tmpFile
+	binary.Write(&buf, byteOrder, totalBits)
+	buf.Write(payload)
+	return buf.Bytes()
 }
 
-func TestHuffmanCompressDecompress(t *testing.T) {
+func TestHuffmanDecompressInvalidHeader(t *testing.T) {
+	tooMany := make([]headerEntry, 300)
+	for i := range tooMany {
+		tooMany[i] = headerEntry{sym: byte(i), freq: 1}
+	}
+
 	tests := []struct {
-		name        string
-		content     []byte
-		shouldError bool
+		name    string
+		blob    []byte
+		wantErr string
 	}{
 		{
-			name:        "Empty file",
-			content:     []byte(""),
-			shouldError: true,
-		},
-		{
-			name:        "Simple ASCII",
-			content:     []byte("aaaaabbbbcccdde"),
-			shouldError: false,
+			name:    "Duplicate symbol",
+			blob:    craftBlob(3, []headerEntry{{'a', 2}, {'b', 1}, {'a', 5}}, 4, []byte{0x00}),
+			wantErr: "duplicate symbol 0x61",
 		},
 		{
-			name:        "Binary data",
-			content:     []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03},
-			shouldError: false,
+			name:    "Too many entries",
+			blob:    craftBlob(300, tooMany, 8, []byte{0x00}),
+			wantErr: "exceeds 256 symbols",
 		},
 		{
-			name:        "Long repetitive",
-			content:     []byte("hello world! hello world! hello world! hello world!"),
-			shouldError: false,
+			name:    "Empty table with payload",
+			blob:    craftBlob(0, nil, 1<<40, nil),
+			wantErr: "empty symbol table with nonzero payload",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			path := createTempFile(t, tt.name, tt.content)
+			_, err := HuffmanDecompress(tt.blob)
+			if err == nil {
+				t.Fatal("expected decompress error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressSingleSymbol(t *testing.T) {
+	for _, content := range [][]byte{[]byte("a"), []byte("aaaaaaaaaaaaaaaaaaaa"), bytes.Repeat([]byte{0xFF}, 9)} {
+		compressed, err := HuffmanCompressBytes(content)
+		if err != nil {
+			t.Fatalf("unexpected compress error: %v", err)
+		}
+		decompressed, err := HuffmanDecompress(compressed)
+		if err != nil {
+			t.Fatalf("unexpected decompress error: %v", err)
+		}
+		if !bytes.Equal(decompressed, content) {
+			t.Errorf("decompressed output does not match original.\nGot: %v\nWant: %v", decompressed, content)
+		}
+	}
+}
+
+func TestEncodeDataMissingCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		tree    []byte
+		data    []byte
+		wantErr string
+	}{
+		{name: "Symbol outside tree", tree: []byte("aab"), data: []byte("abc"), wantErr: "symbol 0x63 at offset 2 has no code"},
+		{name: "Single-symbol tree", tree: []byte("a"), data: []byte("ab"), wantErr: "symbol 0x62 at offset 1 has no code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var codes codeTable
+			buildCodeTable(buildHuffmanTree(buildFrequencyTable(tt.tree)), 0, 0, &codes)
+			var buf bytes.Buffer
+			_, err := encodeDataWithCount(&buf, tt.data, &codes, nil)
+			if err == nil {
+				t.Fatal("expected missing code error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
 
-			compressed, err := HuffmanCompress(path)
+func TestHuffmanCompressVerbose(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Text", content: []byte("hello world! hello world! hello world!")},
+		{name: "Binary", content: []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}},
+		{name: "Single symbol", content: []byte("aaaa")},
+	}
 
-			if tt.shouldError {
-				if err == nil {
-					t.Errorf("expected compress error but got nil")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, codes, err := HuffmanCompressVerbose(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			plain, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if !bytes.Equal(compressed, plain) {
+				t.Error("verbose output differs from HuffmanCompressBytes")
+			}
+
+			for a, ca := range codes {
+				if ca == "" {
+					t.Errorf("symbol 0x%02x has an empty code", a)
+				}
+				for b, cb := range codes {
+					if a != b && strings.HasPrefix(cb, ca) {
+						t.Errorf("code %q for 0x%02x is a prefix of %q for 0x%02x", ca, a, cb, b)
+					}
 				}
-				return
 			}
 
+			wantBits := 0
+			for _, b := range tt.content {
+				c, ok := codes[b]
+				if !ok {
+					t.Fatalf("symbol 0x%02x has no code", b)
+				}
+				wantBits += len(c)
+			}
+			_, totalBits, payload := splitBlob(t, compressed)
+			if totalBits != uint64(wantBits) {
+				t.Errorf("expected %d payload bits from the codes, blob records %d", wantBits, totalBits)
+			}
+			if len(payload) != (wantBits+7)/8 {
+				t.Errorf("expected %d payload bytes, got %d", (wantBits+7)/8, len(payload))
+			}
+		})
+	}
+}
+
+func FuzzCompressDecompress(f *testing.F) {
+	f.Add([]byte("aaaaabbbbcccdde"))
+	f.Add([]byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03})
+	f.Add([]byte("hello world! hello world! hello world! hello world!"))
+	f.Add([]byte("a"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		compressed, err := HuffmanCompressBytes(data)
+		if len(data) == 0 {
+			if err == nil {
+				t.Fatal("expected compress error for empty input but got nil")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected compress error: %v", err)
+		}
+		decompressed, err := HuffmanDecompress(compressed)
+		if err != nil {
+			t.Fatalf("unexpected decompress error: %v", err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Fatalf("decompressed output does not match original.\nGot: %v\nWant: %v", decompressed, data)
+		}
+	})
+}
+
+func TestByteFaithful(t *testing.T) {
+	if !IsByteFaithful() {
+		t.Fatal("expected the codecs to be byte-faithful")
+	}
+	tests := []struct {
+		name    string
+		content []byte
+		valid   bool
+	}{
+		{name: "Emoji", content: []byte("🙂🚀🎉 family: 👩‍👩‍👧‍👦, flag: 🇯🇵"), valid: true},
+		{name: "CJK", content: []byte("漢字かなカナ한국어 中文測試 日本語のテキスト"), valid: true},
+		{name: "Mixed scripts", content: []byte("naïve café Ελληνικά Русский עברית العربية"), valid: true},
+		{name: "Lone continuation byte", content: []byte("ab\x80cd")},
+		{name: "Truncated sequence", content: []byte("€ then \xe2\x82 cut short")},
+		{name: "Overlong encoding", content: []byte("\xc0\xaf\xe0\x80\xaf")},
+		{name: "Surrogate half", content: []byte("\xed\xa0\x80")},
+		{name: "Invalid lead bytes", content: []byte("\xfe\xff\xf8\x88\x80\x80\x80")},
+		{name: "BOM and NUL", content: []byte("\xef\xbb\xbfhello\x00world"), valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if utf8.Valid(tt.content) != tt.valid {
+				t.Fatalf("expected fixture validity %v", tt.valid)
+			}
+			content := bytes.Repeat(tt.content, 8)
+			compressors := map[string]func([]byte) ([]byte, error){
+				"huffman": HuffmanCompressBytes,
+				"auto":    CompressAuto,
+				"words":   func(d []byte) ([]byte, error) { return HuffmanCompressWords(d, 2) },
+				"rle":     HuffmanCompressRLE,
+				"delta":   HuffmanCompressDelta,
+				"blocks":  func(d []byte) ([]byte, error) { return HuffmanCompressBlocks(d, 7) },
+			}
+			for name, compress := range compressors {
+				blob, err := compress(content)
+				if err != nil {
+					t.Fatalf("%s: unexpected compress error: %v", name, err)
+				}
+				got, err := Decompress(blob)
+				if err != nil {
+					t.Fatalf("%s: unexpected decompress error: %v", name, err)
+				}
+				if !bytes.Equal(got, content) {
+					t.Errorf("%s: decompressed bytes differ from the input", name)
+				}
+			}
+		})
+	}
+}
+
+// fuzzSeedBlobs returns a valid blob of every mode, for FuzzDecompress to
+// start from.
+func fuzzSeedBlobs(f *testing.F) [][]byte {
+	f.Helper()
+	text := []byte("hello world! hello world! abracadabra 0123456789")
+	compressors := []func() ([]byte, error){
+		func() ([]byte, error) { return CompressStore(text) },
+		func() ([]byte, error) { return HuffmanCompressBytes(text) },
+		func() ([]byte, error) { return HuffmanCompressBytes([]byte("a")) },
+		func() ([]byte, error) { return HuffmanCompressWords(text, 2) },
+		func() ([]byte, error) { return HuffmanCompressRLE([]byte("aaaaaaaabbbbbbbbbbcd")) },
+		func() ([]byte, error) { return CompressAuto(bytes.Repeat(text, 4)) },
+		func() ([]byte, error) { return HuffmanCompressLimited(text, 6) },
+		func() ([]byte, error) { return HuffmanCompressBlocks(text, 16) },
+		func() ([]byte, error) { return HuffmanCompressEscape(text, 2) },
+		func() ([]byte, error) { return HuffmanCompressASCII(text) },
+		func() ([]byte, error) { return HuffmanCompressDelta([]byte("abcdefghijklmnopqrstuvwxyz")) },
+		func() ([]byte, error) { return HuffmanCompressOpts(text, CompressOptions{CompactHeader: true}) },
+		func() ([]byte, error) {
+			return HuffmanArchive([]ArchiveMember{{Name: "a.txt", Data: text}, {Name: "b.txt", Data: []byte("b")}})
+		},
+		func() ([]byte, error) { return HuffmanCompressOpts(text, CompressOptions{Comment: "seed"}) },
+	}
+	var blobs [][]byte
+	for _, compress := range compressors {
+		blob, err := compress()
+		if err != nil {
+			f.Fatalf("unexpected compress error: %v", err)
+		}
+		blobs = append(blobs, blob)
+	}
+	return blobs
+}
+
+// FuzzDecompress feeds arbitrary bytes to HuffmanDecompress, which must
+// return an error or output within the size limit, and never panic. The
+// corpus starts from valid blobs of every mode along with truncated and
+// mutated copies of them.
+func FuzzDecompress(f *testing.F) {
+	for _, blob := range fuzzSeedBlobs(f) {
+		f.Add(blob)
+		f.Add(blob[:len(blob)/2])
+		f.Add(blob[:len(blob)-1])
+		flipped := bytes.Clone(blob)
+		flipped[len(flipped)-1] ^= 0x5a
+		f.Add(flipped)
+		header := bytes.Clone(blob)
+		header[containerHeaderSize] ^= 0xff
+		f.Add(header)
+	}
+	f.Add([]byte{})
+	f.Add([]byte(magic))
+
+	f.Fuzz(func(t *testing.T, blob []byte) {
+		out, err := HuffmanDecompress(blob)
+		if err != nil {
+			if out != nil {
+				t.Fatalf("expected no output alongside error %v, got %d bytes", err, len(out))
+			}
+			return
+		}
+		if len(out) > DefaultMaxDecompressedSize {
+			t.Fatalf("decompressed %d bytes, over the %d byte limit", len(out), DefaultMaxDecompressedSize)
+		}
+	})
+}
+
+func TestWriteHeaderInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		freq    map[byte]int
+		wantErr string
+	}{
+		{name: "Zero frequency", freq: map[byte]int{'a': 3, 'b': 0}, wantErr: "symbol 0x62 has frequency 0"},
+		{name: "Negative frequency", freq: map[byte]int{'a': -1}, wantErr: "symbol 0x61 has frequency -1"},
+		{name: "Frequency overflow", freq: map[byte]int{'a': 1 << 32}, wantErr: "overflows 32 bits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := writeHeader(tt.freq)
+			if err == nil {
+				t.Fatal("expected header error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFullAlphabetRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(24))
+	allOnce := make([]byte, 256)
+	for i := range allOnce {
+		allOnce[i] = byte(i)
+	}
+	var ascending []byte
+	for i := 0; i < 256; i++ {
+		ascending = append(ascending, bytes.Repeat([]byte{byte(i)}, i+1)...)
+	}
+	rng.Shuffle(len(ascending), func(i, j int) { ascending[i], ascending[j] = ascending[j], ascending[i] })
+	random := make([]byte, 1<<16)
+	rng.Read(random)
+	// Fibonacci weights on the low symbols and a single occurrence of every
+	// other value give the deepest tree a full alphabet reaches in practice.
+	deep := append(fibonacciData(30), allOnce[30:]...)
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Every value once", content: allOnce},
+		{name: "Ascending frequencies", content: ascending},
+		{name: "High entropy", content: random},
+		{name: "Deep tree", content: deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			header, _, _ := splitBlob(t, compressed)
+			if n := byteOrder.Uint16(header); n != 256 {
+				t.Errorf("expected 256 header entries, got %d", n)
+			}
+			decompressed, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(tt.content))
+			}
+			if err := HuffmanVerify(compressed); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+
+			limited, err := HuffmanCompressLimited(tt.content, MaxCodeLength)
+			if err != nil {
+				t.Fatalf("unexpected limited compress error: %v", err)
+			}
+			decompressed, err = Decompress(limited)
+			if err != nil {
+				t.Fatalf("unexpected limited decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Error("limited: decompressed output does not match original")
+			}
+		})
+	}
+}
+
+func TestWriteHeaderFullAlphabet(t *testing.T) {
+	freq := make(map[byte]int, 256)
+	for i := 0; i < 256; i++ {
+		freq[byte(i)] = i + 1
+	}
+	head, err := writeHeader(freq)
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	if len(head) != headerSize(256) {
+		t.Errorf("expected %d header bytes, got %d", headerSize(256), len(head))
+	}
+}
+
+func TestEncodeByteBoundaries(t *testing.T) {
+	// With two symbols every code is one bit, so the symbol count is the bit
+	// count. The input is mostly 0xFF, which gets the code 1, so a stray
+	// padding bit would show up as a set bit, and zero padding bits would
+	// decode as extra 0x00 bytes if decoding ran past totalBits.
+	tests := []struct {
+		name      string
+		bits      int
+		wantBytes int
+	}{
+		{name: "Exactly one byte", bits: 8, wantBytes: 1},
+		{name: "Exactly two bytes", bits: 16, wantBytes: 2},
+		{name: "One bit past two bytes", bits: 17, wantBytes: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := append(bytes.Repeat([]byte{0xFF}, tt.bits-1), 0x00)
+			compressed, codes, err := HuffmanCompressVerbose(content)
 			if err != nil {
 				t.Fatalf("unexpected compress error: %v", err)
 			}
-			if len(compressed) == 0 {
-				t.Fatal("compressed output is empty")
+			if codes[0xFF] != "1" || codes[0x00] != "0" {
+				t.Fatalf("expected codes 0xFF=1 and 0x00=0, got %v", codes)
+			}
+			header, totalBits, payload := splitBlob(t, compressed)
+			if totalBits != uint64(tt.bits) {
+				t.Errorf("expected %d total bits, got %d", tt.bits, totalBits)
+			}
+			if len(payload) != tt.wantBytes {
+				t.Fatalf("expected %d payload bytes, got %d", tt.wantBytes, len(payload))
+			}
+
+			want := make([]byte, tt.wantBytes)
+			for i := 0; i < tt.bits-1; i++ {
+				want[i/8] |= 0x80 >> (i % 8)
+			}
+			if !bytes.Equal(payload, want) {
+				t.Errorf("expected payload %08b, got %08b", want, payload)
 			}
 
 			decompressed, err := HuffmanDecompress(compressed)
 			if err != nil {
 				t.Fatalf("unexpected decompress error: %v", err)
 			}
+			if !bytes.Equal(decompressed, content) {
+				t.Errorf("decompressed %d bytes, want %d", len(decompressed), len(content))
+			}
+			dec, err := NewDecoder(header)
+			if err != nil {
+				t.Fatalf("unexpected decoder error: %v", err)
+			}
+			for _, bits := range []int{1, 8, MaxTableBits} {
+				if err := dec.SetTableBits(bits); err != nil {
+					t.Fatalf("unexpected error setting %d table bits: %v", bits, err)
+				}
+				decoded, err := dec.Decode(payload, totalBits)
+				if err != nil {
+					t.Fatalf("%d table bits: unexpected decode error: %v", bits, err)
+				}
+				if !bytes.Equal(decoded, content) {
+					t.Errorf("%d table bits: decoded %d bytes, want %d", bits, len(decoded), len(content))
+				}
+			}
+		})
+	}
+}
 
-			if !bytes.Equal(decompressed, tt.content) {
-				t.Errorf("decompressed output does not match original.\nGot: %v\nWant: %v", decompressed, tt.content)
+func TestCheckLeaves(t *testing.T) {
+	freq := map[byte]int{'a': 3, 'b': 2, 'c': 1}
+	leaf := func(c byte) *Node { return &Node{Char: c} }
+	pair := func(l, r *Node) *Node { return &Node{Left: l, Right: r} }
+
+	tests := []struct {
+		name    string
+		root    *Node
+		wantErr string
+	}{
+		{name: "Built tree", root: buildHuffmanTree(freq)},
+		{name: "Missing symbol", root: pair(leaf('a'), leaf('b')), wantErr: "2 leaves for 3 header entries"},
+		{name: "Stray leaf", root: pair(leaf('a'), pair(leaf('b'), leaf('z'))), wantErr: "leaf 0x7a is not in the header"},
+		{name: "Duplicate leaf", root: pair(pair(leaf('a'), leaf('b')), pair(leaf('c'), leaf('a'))), wantErr: "0x61 has more than one leaf"},
+		{name: "One child", root: pair(leaf('a'), &Node{Left: pair(leaf('b'), leaf('c'))}), wantErr: "internal node with one child"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkLeaves(tt.root, freq)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrCorruptStream) {
+				t.Fatalf("expected ErrCorruptStream, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
 			}
 		})
 	}
 }
+
+func TestReadHuffmanBodyLeaves(t *testing.T) {
+	// A full table of skewed frequencies builds the deepest trees a header
+	// can describe; every declared symbol must still be a reachable leaf.
+	entries := make([]headerEntry, 256)
+	for i := range entries {
+		entries[i] = headerEntry{sym: byte(i), freq: uint32(1) << (i % 31)}
+	}
+	blob := craftBlob(256, entries, 0, nil)
+	freq, root, _, _, err := readHuffmanBody(blob[containerHeaderSize:])
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	if err := checkLeaves(root, freq); err != nil {
+		t.Errorf("unexpected leaf error: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// BlobInfo describes a blob. OriginalName is the name AddName recorded, if
+// any, and Mode the codec of the blob it wraps. SymbolCount and PayloadBits come from
+// a ModeHuffman header and are left zero for other modes.
+type BlobInfo struct {
+	OriginalName   string `json:"originalName,omitempty"`
+	Mode           string `json:"mode"`
+	OriginalSize   int    `json:"originalSize"`
+	CompressedSize int    `json:"compressedSize"`
+	SymbolCount    int    `json:"symbolCount,omitempty"`
+	PayloadBits    uint64 `json:"payloadBits,omitempty"`
+}
+
+// Inspect describes blob, which may be of any mode Decompress reads. For
+// ModeHuffman it reads only the header: the original size is the sum of the
+// header frequencies, since every input byte is counted exactly once.
+// ModeStore and ModeBlocks record their size directly; other modes do not,
+// so they are decoded, up to DefaultMaxDecompressedSize bytes, to measure
+// it. A ModeNamed blob is described by the blob it wraps.
+// Time Complexity: O(m), or O(n + m log m) for modes that do not record
+// their size, Space Complexity: O(m), or O(n + m) for those modes
+func Inspect(blob []byte) (BlobInfo, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	info := BlobInfo{Mode: mode.String(), CompressedSize: len(blob)}
+	switch mode {
+	case ModeHuffman:
+		return inspectHuffmanBody(body, info)
+	case ModeNamed:
+		name, inner, err := splitName(body)
+		if err != nil {
+			return BlobInfo{}, err
+		}
+		if info, err = Inspect(inner); err != nil {
+			return BlobInfo{}, err
+		}
+		info.OriginalName, info.CompressedSize = name, len(blob)
+		return info, nil
+	case ModeStore:
+		info.OriginalSize = len(body)
+		return info, nil
+	case ModeBlocks:
+		_, _, size, err := readBlockIndex(body)
+		if err != nil {
+			return BlobInfo{}, err
+		}
+		if size > DefaultMaxDecompressedSize {
+			return BlobInfo{}, sizeLimitError(DefaultMaxDecompressedSize)
+		}
+		info.OriginalSize = int(size)
+		return info, nil
+	default:
+		data, err := Decompress(blob)
+		if err != nil {
+			return BlobInfo{}, err
+		}
+		info.OriginalSize = len(data)
+		return info, nil
+	}
+}
+
+// inspectHuffmanBody fills in info from the header of a ModeHuffman body.
+// Time Complexity: O(m), Space Complexity: O(m)
+func inspectHuffmanBody(body []byte, info BlobInfo) (BlobInfo, error) {
+	r := bytes.NewReader(body)
+	freq, err := readHeader(r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	var totalBits uint64
+	if err := binary.Read(r, byteOrder, &totalBits); err != nil {
+		return BlobInfo{}, corruptf("read bit length failed: %w", err)
+	}
+	for _, f := range freq {
+		info.OriginalSize += f
+	}
+	info.SymbolCount = len(freq)
+	info.PayloadBits = totalBits
+	return info, nil
+}
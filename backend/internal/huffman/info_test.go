@@ -0,0 +1,82 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	content := []byte("hello world! hello world!")
+	blob, err := HuffmanCompressBytes(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	freq := buildFrequencyTable(content)
+	lengths := make(map[byte]int)
+	codeLengths(buildHuffmanTree(freq), 0, lengths)
+	var wantBits uint64
+	for b, f := range freq {
+		wantBits += uint64(f * lengths[b])
+	}
+
+	info, err := Inspect(blob)
+	if err != nil {
+		t.Fatalf("unexpected inspect error: %v", err)
+	}
+	want := BlobInfo{
+		Mode:           "huffman",
+		OriginalSize:   len(content),
+		CompressedSize: len(blob),
+		SymbolCount:    len(freq),
+		PayloadBits:    wantBits,
+	}
+	if info != want {
+		t.Errorf("expected %+v, got %+v", want, info)
+	}
+}
+
+func TestInspectModes(t *testing.T) {
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50))
+	runs := bytes.Repeat([]byte("a"), 5000)
+
+	tests := []struct {
+		name     string
+		compress func([]byte) ([]byte, error)
+		content  []byte
+		wantMode Mode
+	}{
+		{name: "Store", compress: CompressStore, content: text, wantMode: ModeStore},
+		{name: "RLE", compress: HuffmanCompressRLE, content: runs, wantMode: ModeRLE},
+		{name: "Flate", compress: CompressBest, content: text, wantMode: ModeFlate},
+		{name: "Blocks", compress: func(data []byte) ([]byte, error) { return HuffmanCompressBlocks(data, 100) }, content: text, wantMode: ModeBlocks},
+		{name: "Auto", compress: CompressAuto, content: text, wantMode: ModeFlate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := tt.compress(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			info, err := Inspect(blob)
+			if err != nil {
+				t.Fatalf("unexpected inspect error: %v", err)
+			}
+			want := BlobInfo{Mode: tt.wantMode.String(), OriginalSize: len(tt.content), CompressedSize: len(blob)}
+			if info != want {
+				t.Errorf("expected %+v, got %+v", want, info)
+			}
+		})
+	}
+}
+
+func TestInspectInvalid(t *testing.T) {
+	if _, err := Inspect([]byte{0x01}); err == nil {
+		t.Error("expected error for truncated header but got nil")
+	}
+	if _, err := Inspect([]byte{0x01, 0x00, 'a', 0x01, 0x00, 0x00, 0x00}); err == nil {
+		t.Error("expected error for missing bit length but got nil")
+	}
+}
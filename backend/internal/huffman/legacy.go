@@ -0,0 +1,35 @@
+package huffman
+
+import "fmt"
+
+// legacyVersion is the FormatVersion of blobs written before the container
+// header existed. HuffmanCompressBytes then produced a bare ModeHuffman
+// body, with no magic, version or mode in front of it.
+const legacyVersion = 1
+
+// UpgradeV1 converts a blob written by HuffmanCompressBytes at
+// FormatVersion 1 into the current format, checking first that it decodes
+// cleanly. A version 1 blob carries no magic, so nothing marks it as one and
+// Decompress turns it away with ErrBadMagic; callers holding such blobs
+// upgrade them here once and decode the result as usual. The blob produced
+// by the version 1 RLE, CompressBest and word-symbol variants, each prefixed
+// or laid out differently, cannot be upgraded.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func UpgradeV1(blob []byte) ([]byte, error) {
+	upgraded := wrap(ModeHuffman, blob)
+	if err := HuffmanVerify(upgraded); err != nil {
+		return nil, fmt.Errorf("not a version %d blob: %w", legacyVersion, err)
+	}
+	return upgraded, nil
+}
+
+// DecompressV1 decodes a blob written by HuffmanCompressBytes at
+// FormatVersion 1, as Decompress does a current one.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func DecompressV1(blob []byte) ([]byte, error) {
+	upgraded, err := UpgradeV1(blob)
+	if err != nil {
+		return nil, err
+	}
+	return Decompress(upgraded)
+}
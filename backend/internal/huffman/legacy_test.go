@@ -0,0 +1,64 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// legacyFixture is HuffmanCompressBytes("version one blob") as written at
+// FormatVersion 1: ten header entries, 52 payload bits and the packed codes,
+// with no container header.
+var legacyFixture = []byte{
+	0x0a, 0x00, 0x20, 0x02, 0x00, 0x00, 0x00, 0x62, 0x02, 0x00, 0x00, 0x00,
+	0x65, 0x02, 0x00, 0x00, 0x00, 0x69, 0x01, 0x00, 0x00, 0x00, 0x6c, 0x01,
+	0x00, 0x00, 0x00, 0x6e, 0x02, 0x00, 0x00, 0x00, 0x6f, 0x03, 0x00, 0x00,
+	0x00, 0x72, 0x01, 0x00, 0x00, 0x00, 0x73, 0x01, 0x00, 0x00, 0x00, 0x76,
+	0x01, 0x00, 0x00, 0x00, 0x34, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xe7, 0x9b, 0x05, 0xf2, 0xbf, 0x52, 0x20,
+}
+
+func TestDecompressV1(t *testing.T) {
+	if _, err := Decompress(legacyFixture); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("expected ErrBadMagic decoding a version 1 blob directly, got %v", err)
+	}
+	got, err := DecompressV1(legacyFixture)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if want := []byte("version one blob"); !bytes.Equal(got, want) {
+		t.Errorf("decoded fixture = %q, want %q", got, want)
+	}
+
+	upgraded, err := UpgradeV1(legacyFixture)
+	if err != nil {
+		t.Fatalf("unexpected upgrade error: %v", err)
+	}
+	if err := Validate(upgraded); err != nil {
+		t.Errorf("unexpected validate error on the upgraded blob: %v", err)
+	}
+	// The payload is unchanged, so today's encoder writes the same blob.
+	if want := mustCompress(t, []byte("version one blob")); !bytes.Equal(upgraded, want) {
+		t.Errorf("upgraded blob differs from a fresh encoding.\nGot:  % x\nWant: % x", upgraded, want)
+	}
+}
+
+func TestUpgradeV1Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{name: "Empty", blob: nil},
+		{name: "Truncated", blob: legacyFixture[:len(legacyFixture)-1]},
+		{name: "Current blob", blob: mustCompress(t, []byte("version two blob"))},
+		{name: "Plain text", blob: []byte("just some text that is not a blob")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UpgradeV1(tt.blob); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("expected ErrCorruptStream, got %v", err)
+			}
+		})
+	}
+}
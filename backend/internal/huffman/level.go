@@ -0,0 +1,57 @@
+package huffman
+
+import "fmt"
+
+// Compression levels accepted by HuffmanCompressLevel, mirroring the dial
+// offered by compress/gzip.
+const (
+	NoCompression      = 0 // ModeStore
+	BestSpeed          = 1 // ModeHuffman with the full frequency header
+	DefaultCompression = 2 // ModeCanonical, storing only code lengths
+	BestCompression    = 3 // smallest of canonical, RLE and DEFLATE
+)
+
+// HuffmanCompressLevel compresses data at the given level. Higher levels
+// spend more time to produce output no larger than lower ones on typical
+// input. The level is not recorded; Decompress detects the codec from the
+// container mode.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressLevel(data []byte, level int) ([]byte, error) {
+	switch level {
+	case NoCompression:
+		return CompressStore(data)
+	case BestSpeed:
+		return HuffmanCompressBytes(data)
+	case DefaultCompression:
+		return HuffmanCompressLimited(data, MaxCodeLength)
+	case BestCompression:
+		return compressSmallest(data)
+	default:
+		return nil, fmt.Errorf("invalid compression level %d (want %d-%d)", level, NoCompression, BestCompression)
+	}
+}
+
+// compressSmallest tries every codec worth considering at BestCompression and
+// keeps the smallest blob, preferring the earlier candidate on ties.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func compressSmallest(data []byte) ([]byte, error) {
+	best, err := HuffmanCompressLimited(data, MaxCodeLength)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := HuffmanCompressRLE(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) < len(best) {
+		best = runs
+	}
+	deflated, err := flateCompress(data)
+	if err != nil {
+		return nil, err
+	}
+	if containerHeaderSize+len(deflated) < len(best) {
+		best = wrap(ModeFlate, deflated)
+	}
+	return best, nil
+}
@@ -0,0 +1,51 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanCompressLevel(t *testing.T) {
+	inputs := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Prose", content: []byte(strings.Repeat("It was the best of times, it was the worst of times. ", 40))},
+		{name: "Short text", content: []byte("hello world!")},
+		{name: "Single symbol", content: bytes.Repeat([]byte("z"), 300)},
+	}
+
+	for _, in := range inputs {
+		t.Run(in.name, func(t *testing.T) {
+			prev := -1
+			for level := NoCompression; level <= BestCompression; level++ {
+				compressed, err := HuffmanCompressLevel(in.content, level)
+				if err != nil {
+					t.Fatalf("level %d: unexpected compress error: %v", level, err)
+				}
+				decompressed, err := Decompress(compressed)
+				if err != nil {
+					t.Fatalf("level %d: unexpected decompress error: %v", level, err)
+				}
+				if !bytes.Equal(decompressed, in.content) {
+					t.Errorf("level %d: decompressed output does not match original.\nGot: %q\nWant: %q", level, decompressed, in.content)
+				}
+				// Store can beat Huffman on tiny inputs, so sizes are only
+				// compared from BestSpeed upward.
+				if level > BestSpeed && len(compressed) > prev {
+					t.Errorf("level %d output (%d bytes) is larger than level %d (%d bytes)", level, len(compressed), level-1, prev)
+				}
+				prev = len(compressed)
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressLevelInvalid(t *testing.T) {
+	for _, level := range []int{-1, BestCompression + 1} {
+		if _, err := HuffmanCompressLevel([]byte("abc"), level); err == nil {
+			t.Errorf("expected error for level %d but got nil", level)
+		}
+	}
+}
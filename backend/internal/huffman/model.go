@@ -0,0 +1,132 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// HuffmanCompressWithModel builds a ModeModel blob coded with the codes of a
+// shared frequency model instead of data's own frequencies. The model is not
+// stored, so the body is only the bit length and payload; every symbol in
+// data must appear in the model.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressWithModel(data []byte, model map[byte]int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	root, err := buildModelTree(model)
+	if err != nil {
+		return nil, err
+	}
+	return compressWithTree(data, root, "model")
+}
+
+// CompressWithTree builds a ModeModel blob coded with the codes of root, a
+// tree built elsewhere or loaded from a standard table, for example with
+// BuildTreeFromTable, so one tree can serve many inputs. The tree is not
+// stored; DecompressWithTree decodes the blob given the same tree. root must
+// be a tree Decode accepts, and every symbol in data must have a leaf. For
+// a bare payload with no framing at all, use Encode and Decode.
+// Time Complexity: O(n + m), Space Complexity: O(n + m)
+func CompressWithTree(data []byte, root *Node) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if err := checkExternalTree(root); err != nil {
+		return nil, err
+	}
+	return compressWithTree(data, root, "tree")
+}
+
+// compressWithTree writes the ModeModel blob of data coded with root.
+// source names where root came from in errors.
+// Time Complexity: O(n + m), Space Complexity: O(n + m)
+func compressWithTree(data []byte, root *Node, source string) ([]byte, error) {
+	var table codeTable
+	buildCodeTable(root, 0, 0, &table)
+
+	totalBits := 0
+	for b, f := range buildFrequencyTable(data) {
+		if table[b].length == 0 {
+			return nil, fmt.Errorf("symbol 0x%02x is not in the %s", b, source)
+		}
+		totalBits += f * int(table[b].length)
+	}
+
+	var out bytes.Buffer
+	out.Grow(containerHeaderSize + 8 + (totalBits+7)/8)
+	writeContainerHeader(&out, ModeModel)
+	if err := binary.Write(&out, byteOrder, uint64(totalBits)); err != nil {
+		return nil, err
+	}
+	if _, err := encodeDataWithCount(&out, data, &table, nil); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// HuffmanDecompressWithModel reverses HuffmanCompressWithModel. model must be
+// the frequency model the blob was compressed with. Output is capped at
+// DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanDecompressWithModel(blob []byte, model map[byte]int) ([]byte, error) {
+	root, err := buildModelTree(model)
+	if err != nil {
+		return nil, err
+	}
+	return decompressWithTree(blob, root)
+}
+
+// DecompressWithTree reverses CompressWithTree. root must be the tree the
+// blob was compressed with. Output is capped at DefaultMaxDecompressedSize
+// bytes.
+// Time Complexity: O(n + m), Space Complexity: O(n + m)
+func DecompressWithTree(blob []byte, root *Node) ([]byte, error) {
+	if err := checkExternalTree(root); err != nil {
+		return nil, err
+	}
+	return decompressWithTree(blob, root)
+}
+
+// decompressWithTree decodes a ModeModel blob by walking root.
+// Time Complexity: O(n), Space Complexity: O(n)
+func decompressWithTree(blob []byte, root *Node) ([]byte, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return nil, err
+	}
+	if mode != ModeModel {
+		return nil, fmt.Errorf("%w: expected %s blob, got %s", ErrWrongMode, ModeModel, mode)
+	}
+	if len(body) < 8 {
+		return nil, corruptf("read bit length failed: body of %d bytes is too short", len(body))
+	}
+	totalBits := byteOrder.Uint64(body)
+	if err := checkPayloadLength(body[8:], totalBits); err != nil {
+		return nil, err
+	}
+	return decodeBits(root, body[8:], totalBits, DefaultMaxDecompressedSize, true)
+}
+
+// checkExternalTree reports a caller-supplied tree that Decode would refuse.
+// Time Complexity: O(m), Space Complexity: O(depth)
+func checkExternalTree(root *Node) error {
+	if root == nil {
+		return fmt.Errorf("a Huffman tree is required")
+	}
+	nodes := 0
+	return checkTree(root, 0, &nodes)
+}
+
+// buildModelTree validates model and builds its Huffman tree.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func buildModelTree(model map[byte]int) (*Node, error) {
+	if len(model) == 0 {
+		return nil, fmt.Errorf("invalid model: no symbols")
+	}
+	if err := validateFrequencyTable(model); err != nil {
+		return nil, err
+	}
+	return buildHuffmanTree(model), nil
+}
@@ -0,0 +1,182 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanCompressWithModel(t *testing.T) {
+	corpus := []byte("GET /index.html HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n")
+	model := buildFrequencyTable(corpus)
+
+	files := [][]byte{
+		[]byte("GET /a.html HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		[]byte("GET /index.html HTTP/1.1\r\nAccept: */*\r\n\r\n"),
+		[]byte("Host: example.com\r\n"),
+	}
+
+	for i, content := range files {
+		compressed, err := HuffmanCompressWithModel(content, model)
+		if err != nil {
+			t.Fatalf("file %d: unexpected compress error: %v", i, err)
+		}
+		plain, err := HuffmanCompressBytes(content)
+		if err != nil {
+			t.Fatalf("file %d: unexpected compress error: %v", i, err)
+		}
+		if len(compressed) >= len(plain) {
+			t.Errorf("file %d: model blob (%d bytes) is not smaller than one with a header (%d bytes)", i, len(compressed), len(plain))
+		}
+
+		decompressed, err := HuffmanDecompressWithModel(compressed, model)
+		if err != nil {
+			t.Fatalf("file %d: unexpected decompress error: %v", i, err)
+		}
+		if !bytes.Equal(decompressed, content) {
+			t.Errorf("file %d: decompressed output does not match original.\nGot: %q\nWant: %q", i, decompressed, content)
+		}
+	}
+}
+
+func TestHuffmanCompressWithModelErrors(t *testing.T) {
+	model := buildFrequencyTable([]byte("aaaabbc"))
+
+	_, err := HuffmanCompressWithModel([]byte("abcd"), model)
+	if err == nil || !strings.Contains(err.Error(), "symbol 0x64 is not in the model") {
+		t.Errorf("expected missing symbol error, got %v", err)
+	}
+	if _, err := HuffmanCompressWithModel([]byte("abc"), nil); err == nil {
+		t.Error("expected error for empty model but got nil")
+	}
+	if _, err := HuffmanCompressWithModel([]byte("abc"), map[byte]int{'a': 1, 'b': 0, 'c': 1}); err == nil {
+		t.Error("expected error for zero-frequency model entry but got nil")
+	}
+
+	blob, err := HuffmanCompressWithModel([]byte("abc"), model)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := Decompress(blob); err == nil {
+		t.Error("expected Decompress to reject a model blob but got nil")
+	}
+	plain, err := HuffmanCompressBytes([]byte("abc"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := HuffmanDecompressWithModel(plain, model); !errors.Is(err, ErrWrongMode) {
+		t.Errorf("expected ErrWrongMode for a blob of another mode, got %v", err)
+	}
+}
+
+func TestCompressWithTree(t *testing.T) {
+	// One tree, built once from a shared table, codes several payloads.
+	root, err := BuildTreeFromTable(CountFrequencies([]byte("the quick brown fox jumps over the lazy dog")))
+	if err != nil {
+		t.Fatalf("unexpected tree error: %v", err)
+	}
+	payloads := [][]byte{
+		[]byte("the lazy fox"),
+		[]byte("a quick brown dog jumps"),
+		[]byte("zzz"),
+		bytes.Repeat([]byte("over "), 200),
+	}
+
+	for i, content := range payloads {
+		blob, err := CompressWithTree(content, root)
+		if err != nil {
+			t.Fatalf("payload %d: unexpected compress error: %v", i, err)
+		}
+		if mode, _ := ModeOf(blob); mode != ModeModel {
+			t.Errorf("payload %d: expected %s blob, got %s", i, ModeModel, mode)
+		}
+		got, err := DecompressWithTree(blob, root)
+		if err != nil {
+			t.Fatalf("payload %d: unexpected decompress error: %v", i, err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("payload %d: expected %q, got %q", i, content, got)
+		}
+	}
+}
+
+func TestCompressWithTreeMatchesModel(t *testing.T) {
+	model := buildFrequencyTable([]byte("aaaabbbccd"))
+	root, err := BuildTreeFromTable(model)
+	if err != nil {
+		t.Fatalf("unexpected tree error: %v", err)
+	}
+	content := []byte("abcdabcaba")
+	fromTree, err := CompressWithTree(content, root)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	fromModel, err := HuffmanCompressWithModel(content, model)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if !bytes.Equal(fromTree, fromModel) {
+		t.Error("expected the tree of a model to code like the model itself")
+	}
+	got, err := HuffmanDecompressWithModel(fromTree, model)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("expected the model to decode the tree's blob, got %q, %v", got, err)
+	}
+}
+
+func TestCompressWithTreeHandBuilt(t *testing.T) {
+	// A fixed table, in the style of a standard JPEG one: x=0, y=10, z=11.
+	root := &Node{Left: &Node{Char: 'x'}, Right: &Node{Left: &Node{Char: 'y'}, Right: &Node{Char: 'z'}}}
+	content := []byte("xyzzyx")
+	blob, err := CompressWithTree(content, root)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	bits, err := DecodeBitString(blob)
+	if err != nil {
+		t.Fatalf("unexpected bit string error: %v", err)
+	}
+	if want := "0101111100"; bits != want {
+		t.Errorf("expected bits %s, got %s", want, bits)
+	}
+	got, err := DecompressWithTree(blob, root)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected %q, got %q", content, got)
+	}
+}
+
+func TestCompressWithTreeErrors(t *testing.T) {
+	root := &Node{Left: &Node{Char: 'a'}, Right: &Node{Char: 'b'}}
+	oneChild := &Node{Left: &Node{Char: 'a'}}
+
+	if _, err := CompressWithTree(nil, root); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	if _, err := CompressWithTree([]byte("abc"), root); err == nil || !strings.Contains(err.Error(), "symbol 0x63 is not in the tree") {
+		t.Errorf("expected missing symbol error, got %v", err)
+	}
+	if _, err := CompressWithTree([]byte("a"), nil); err == nil {
+		t.Error("expected error for a nil tree but got nil")
+	}
+	if _, err := CompressWithTree([]byte("a"), oneChild); err == nil || !strings.Contains(err.Error(), "one child") {
+		t.Errorf("expected one-child error, got %v", err)
+	}
+
+	blob, err := CompressWithTree([]byte("abba"), root)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := DecompressWithTree(blob, oneChild); err == nil || !strings.Contains(err.Error(), "one child") {
+		t.Errorf("expected one-child error, got %v", err)
+	}
+	if _, err := DecompressWithTree(mustCompress(t, []byte("abba")), root); err == nil {
+		t.Error("expected error for a ModeHuffman blob but got nil")
+	}
+	if _, err := DecompressWithTree(blob[:len(blob)-1], root); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a cut payload, got %v", err)
+	}
+}
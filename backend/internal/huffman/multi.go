@@ -0,0 +1,171 @@
+package huffman
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// HuffmanDecompressMulti decodes a sequence of blobs concatenated back to
+// back, as produced by appending the outputs of separate compress calls, and
+// returns their outputs joined in order. A blob records no overall length,
+// so each member's end is found from its own header: ModeStore, ModeArchive
+// and ModeBlocks members run to the end of the input and must come last. A
+// ModeStore member whose data ends in complete members is rejected, since
+// it cannot be told from a stored member placed in front of them. The
+// combined output is capped at DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(n + k·m log m) for k members, Space Complexity: O(n + m)
+func HuffmanDecompressMulti(blob []byte) ([]byte, error) {
+	if len(blob) == 0 {
+		return nil, corruptf("no members in %d bytes", len(blob))
+	}
+	var out []byte
+	remaining := DefaultMaxDecompressedSize
+	for rest := blob; len(rest) > 0; {
+		n, err := memberLength(rest)
+		if err != nil {
+			return nil, err
+		}
+		if remaining == 0 {
+			return nil, sizeLimitError(DefaultMaxDecompressedSize)
+		}
+		data, err := DecompressWithLimit(rest[:n], remaining)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data...)
+		remaining -= len(data)
+		rest = rest[n:]
+	}
+	return out, nil
+}
+
+// memberLength returns the length of the blob at the start of data,
+// container header included.
+// Time Complexity: O(m log m), or O(n) for ModeFlate and ModeStore, Space
+// Complexity: O(m), or O(n) for ModeStore
+func memberLength(data []byte) (int, error) {
+	return memberSpan(data, true)
+}
+
+// memberSpan is memberLength, checking a ModeStore member for members
+// stored after it only when checkStored is set.
+// Time Complexity: O(m log m), or O(n) for ModeFlate and ModeStore, Space
+// Complexity: O(m), or O(n) for ModeStore
+func memberSpan(data []byte, checkStored bool) (int, error) {
+	mode, body, err := unwrap(data)
+	if err != nil {
+		return 0, err
+	}
+	var payloadStart int
+	var totalBits uint64
+	switch mode {
+	case ModeHuffman, ModeRLE, ModeDelta:
+		_, _, bits, payload, err := readHuffmanBody(body)
+		if err != nil {
+			return 0, err
+		}
+		payloadStart, totalBits = len(body)-len(payload), bits
+	case ModeWords:
+		wb, err := readWordsBody(body)
+		if err != nil {
+			return 0, err
+		}
+		payloadStart, totalBits = len(body)-len(wb.payload), wb.totalBits
+	case ModeASCII:
+		_, _, bits, payload, err := readASCIIBody(body)
+		if err != nil {
+			return 0, err
+		}
+		payloadStart, totalBits = len(body)-len(payload), bits
+	case ModeCompact:
+		_, _, bits, payload, err := readCompactBody(body)
+		if err != nil {
+			return 0, err
+		}
+		payloadStart, totalBits = len(body)-len(payload), bits
+	case ModeCanonical:
+		_, bits, payload, err := readCanonicalBody(body)
+		if err != nil {
+			return 0, err
+		}
+		payloadStart, totalBits = len(body)-len(payload), bits
+	case ModeEscape:
+		eb, err := readEscapeBody(body)
+		if err != nil {
+			return 0, err
+		}
+		payloadStart, totalBits = len(body)-len(eb.payload), eb.totalBits
+	case ModeFlate:
+		// A DEFLATE stream marks its own final block, and flate reads a
+		// bytes.Reader one byte at a time, so what it consumed is the stream.
+		r := bytes.NewReader(body)
+		fr := flate.NewReader(r)
+		defer fr.Close()
+		if _, err := io.Copy(io.Discard, fr); err != nil {
+			return 0, corruptf("flate decode failed: %w", err)
+		}
+		return containerHeaderSize + len(body) - r.Len(), nil
+	case ModeComment, ModeNamed:
+		split := splitComment
+		if mode == ModeNamed {
+			split = splitName
+		}
+		_, inner, err := split(body)
+		if err != nil {
+			return 0, err
+		}
+		n, err := memberSpan(inner, checkStored)
+		if err != nil {
+			return 0, err
+		}
+		return len(data) - len(inner) + n, nil
+	case ModeStore:
+		if !checkStored {
+			return len(data), nil
+		}
+		if at := trailingMembers(body); at >= 0 {
+			return 0, corruptf("stored member is followed by another member at byte %d; a stored member must come last", containerHeaderSize+at)
+		}
+		return len(data), nil
+	default:
+		return len(data), nil
+	}
+	payloadBytes := (totalBits + 7) / 8
+	if payloadBytes > uint64(len(body)-payloadStart) {
+		return 0, truncatedError(uint64(len(body)-payloadStart)*8, totalBits)
+	}
+	return containerHeaderSize + payloadStart + int(payloadBytes), nil
+}
+
+// trailingMembers returns the offset of the first magic in body from which
+// complete members run to its end, or -1 if there is none. A run ending in
+// a ModeStore member counts, as that member would take the rest. Offsets a
+// failed run passed through are not tried again, so the scan parses each
+// member at most once.
+// Time Complexity: O(n + k·m log m) for k members, Space Complexity: O(n)
+func trailingMembers(body []byte) int {
+	dead := make(map[int]bool)
+	for at := 0; at < len(body); at++ {
+		i := bytes.Index(body[at:], []byte(magic))
+		if i < 0 {
+			return -1
+		}
+		at += i
+		var visited []int
+		for pos := at; !dead[pos]; {
+			visited = append(visited, pos)
+			n, err := memberSpan(body[pos:], false)
+			if err != nil {
+				break
+			}
+			if pos += n; pos == len(body) {
+				return at
+			}
+		}
+		for _, pos := range visited {
+			dead[pos] = true
+		}
+	}
+	return -1
+}
@@ -0,0 +1,98 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanDecompressMulti(t *testing.T) {
+	first := []byte(strings.Repeat("first member ", 50))
+	second := []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}
+	runs := bytes.Repeat([]byte("r"), 500)
+
+	compressors := []struct {
+		name     string
+		compress func([]byte) ([]byte, error)
+	}{
+		{name: "Huffman", compress: HuffmanCompressBytes},
+		{name: "Words", compress: func(data []byte) ([]byte, error) { return HuffmanCompressWords(data, 2) }},
+		{name: "RLE", compress: HuffmanCompressRLE},
+		{name: "Flate", compress: CompressBest},
+		{name: "Canonical", compress: func(data []byte) ([]byte, error) { return HuffmanCompressLimited(data, 8) }},
+	}
+
+	for _, c := range compressors {
+		t.Run(c.name, func(t *testing.T) {
+			var blob, want []byte
+			for _, content := range [][]byte{first, second, runs} {
+				member, err := c.compress(content)
+				if err != nil {
+					t.Fatalf("unexpected compress error: %v", err)
+				}
+				blob = append(blob, member...)
+				want = append(want, content...)
+			}
+			// A stored member has no length of its own, so it goes last.
+			stored, err := CompressStore(first)
+			if err != nil {
+				t.Fatalf("unexpected store error: %v", err)
+			}
+			blob = append(blob, stored...)
+			want = append(want, first...)
+
+			got, err := HuffmanDecompressMulti(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decompressed output does not match concatenated originals (got %d bytes, want %d)", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestHuffmanDecompressMultiSingle(t *testing.T) {
+	blob := mustCompress(t, []byte("just one member"))
+	got, err := HuffmanDecompressMulti(blob)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if string(got) != "just one member" {
+		t.Errorf("expected %q, got %q", "just one member", got)
+	}
+}
+
+func TestHuffmanDecompressMultiErrors(t *testing.T) {
+	a := mustCompress(t, []byte("aaaabbc"))
+	b := mustCompress(t, []byte("hello"))
+	stored, err := CompressStore([]byte("stored in front"))
+	if err != nil {
+		t.Fatalf("unexpected store error: %v", err)
+	}
+	commented, err := AddComment(stored, "note")
+	if err != nil {
+		t.Fatalf("unexpected comment error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		blob []byte
+		want error
+	}{
+		{name: "Empty", blob: nil, want: ErrCorruptStream},
+		{name: "Trailing garbage", blob: append(bytes.Clone(a), "junkjunk"...), want: ErrBadMagic},
+		{name: "Truncated second member", blob: append(bytes.Clone(a), b[:len(b)-1]...), want: ErrCorruptStream},
+		{name: "Stored member first", blob: append(bytes.Clone(stored), b...), want: ErrCorruptStream},
+		{name: "Stored member in a comment", blob: append(bytes.Clone(commented), b...), want: ErrCorruptStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := HuffmanDecompressMulti(tt.blob); !errors.Is(err, tt.want) {
+				t.Errorf("expected errors.Is(err, %v), got %v", tt.want, err)
+			}
+		})
+	}
+}
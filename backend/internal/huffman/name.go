@@ -0,0 +1,109 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest file name AddName accepts, in bytes.
+const MaxNameLength = maxArchiveName
+
+// AddName wraps blob in a ModeNamed container recording name, the name of
+// the file blob was compressed from, which ReadName and Inspect return. The
+// name must be 1 to MaxNameLength bytes of UTF-8 with no path separators,
+// quotes or control characters, so it can name a download as it is. The
+// body is laid out as:
+//
+//	u16            name length n
+//	n x u8         name
+//	blob           the wrapped blob, container header included
+//
+// Decompress and HuffmanVerify see through the name to the wrapped blob,
+// which may carry a comment but not another name.
+// Time Complexity: O(n), Space Complexity: O(n)
+func AddName(blob []byte, name string) ([]byte, error) {
+	mode, _, err := unwrap(blob)
+	if err != nil {
+		return nil, err
+	}
+	if mode == ModeNamed {
+		return nil, fmt.Errorf("blob already carries a name")
+	}
+	head, err := NameHeader(name)
+	if err != nil {
+		return nil, err
+	}
+	return append(head, blob...), nil
+}
+
+// NameHeader returns the bytes AddName puts in front of a blob to record
+// name, so a blob being streamed can be named without holding it whole.
+// Time Complexity: O(1), Space Complexity: O(1)
+func NameHeader(name string) ([]byte, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeContainerHeader(&buf, ModeNamed)
+	head := byteOrder.AppendUint16(buf.Bytes(), uint16(len(name)))
+	return append(head, name...), nil
+}
+
+// ReadName returns the name AddName recorded in blob, or "" if blob has
+// none.
+// Time Complexity: O(1), Space Complexity: O(1)
+func ReadName(blob []byte) (string, error) {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return "", err
+	}
+	if mode != ModeNamed {
+		return "", nil
+	}
+	name, _, err := splitName(body)
+	return name, err
+}
+
+// validateName checks that name can be stored by AddName.
+func validateName(name string) error {
+	if len(name) == 0 || len(name) > MaxNameLength {
+		return fmt.Errorf("name of %d bytes outside 1-%d", len(name), MaxNameLength)
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("name is not valid UTF-8")
+	}
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return r == '/' || r == '\\' || r == '"' || unicode.IsControl(r)
+	}); i >= 0 {
+		return fmt.Errorf("name byte 0x%02x at %d is not allowed in a file name", name[i], i)
+	}
+	return nil
+}
+
+// splitName parses a ModeNamed body into its name and the wrapped blob.
+// Time Complexity: O(1), Space Complexity: O(1)
+func splitName(body []byte) (string, []byte, error) {
+	if len(body) < 2 {
+		return "", nil, corruptf("read name length failed: body of %d bytes is too short", len(body))
+	}
+	n := int(byteOrder.Uint16(body))
+	if len(body) < 2+n {
+		return "", nil, corruptf("name of %d bytes truncated at %d", n, len(body)-2)
+	}
+	name := string(body[2 : 2+n])
+	if err := validateName(name); err != nil {
+		return "", nil, corruptf("invalid name: %v", err)
+	}
+	inner := body[2+n:]
+	mode, _, err := unwrap(inner)
+	if err != nil {
+		return "", nil, err
+	}
+	if mode == ModeNamed {
+		return "", nil, corruptf("nested name")
+	}
+	return name, inner, nil
+}
@@ -0,0 +1,137 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddName(t *testing.T) {
+	data := []byte(strings.Repeat("hello world! ", 40))
+	blocks, err := HuffmanCompressBlocks(data, 100)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	commented, err := AddComment(mustCompress(t, data), "tagged")
+	if err != nil {
+		t.Fatalf("unexpected comment error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		blob     []byte
+		fileName string
+		wantMode Mode
+	}{
+		{name: "Huffman", blob: mustCompress(t, data), fileName: "notes.txt", wantMode: ModeHuffman},
+		{name: "Blocks", blob: blocks, fileName: "b", wantMode: ModeBlocks},
+		{name: "Commented", blob: commented, fileName: "tagged.txt", wantMode: ModeComment},
+		{name: "Unicode", blob: mustCompress(t, data), fileName: "résumé 2024.txt", wantMode: ModeHuffman},
+		{name: "Longest", blob: mustCompress(t, data), fileName: strings.Repeat("n", MaxNameLength), wantMode: ModeHuffman},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			named, err := AddName(tt.blob, tt.fileName)
+			if err != nil {
+				t.Fatalf("unexpected name error: %v", err)
+			}
+			got, err := ReadName(named)
+			if err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			if got != tt.fileName {
+				t.Errorf("expected name %q, got %q", tt.fileName, got)
+			}
+			decompressed, err := Decompress(named)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Error("decompressed output does not match original")
+			}
+			if err := HuffmanVerify(named); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+			info, err := Inspect(named)
+			if err != nil {
+				t.Fatalf("unexpected inspect error: %v", err)
+			}
+			if info.OriginalName != tt.fileName || info.Mode != tt.wantMode.String() || info.OriginalSize != len(data) || info.CompressedSize != len(named) {
+				t.Errorf("unexpected info %+v", info)
+			}
+		})
+	}
+
+	plain := mustCompress(t, data)
+	if got, err := ReadName(plain); err != nil || got != "" {
+		t.Errorf("expected no name on a plain blob, got %q (%v)", got, err)
+	}
+
+	// A named blob can sit in front of another in a multi-blob stream.
+	named, err := AddName(plain, "first")
+	if err != nil {
+		t.Fatalf("unexpected name error: %v", err)
+	}
+	multi, err := HuffmanDecompressMulti(append(named, mustCompress(t, []byte("tail"))...))
+	if err != nil {
+		t.Fatalf("unexpected multi decompress error: %v", err)
+	}
+	if !bytes.Equal(multi, append(bytes.Clone(data), "tail"...)) {
+		t.Error("multi-blob output does not match the members")
+	}
+}
+
+func TestAddNameErrors(t *testing.T) {
+	blob := mustCompress(t, []byte("abc"))
+	for _, name := range []string{"", strings.Repeat("n", MaxNameLength+1), "a/b", `a\b`, `say "hi"`, "tab\there", "\xff"} {
+		if _, err := AddName(blob, name); err == nil {
+			t.Errorf("%q: expected error but got nil", name)
+		}
+	}
+
+	named, err := AddName(blob, "once")
+	if err != nil {
+		t.Fatalf("unexpected name error: %v", err)
+	}
+	if _, err := AddName(named, "twice"); err == nil {
+		t.Error("expected error naming a named blob but got nil")
+	}
+	if _, err := AddComment(named, "late"); err == nil {
+		t.Error("expected error commenting a named blob but got nil")
+	}
+
+	truncated := named[:containerHeaderSize+3]
+	if _, err := ReadName(truncated); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a truncated name, got %v", err)
+	}
+	nested := wrap(ModeNamed, append([]byte{4, 0, 'o', 'u', 't', 'r'}, named...))
+	if _, err := Decompress(nested); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a nested name, got %v", err)
+	}
+	// A comment may not wrap a name, or the two could alternate without end.
+	inComment := wrap(ModeComment, append([]byte{1, 'c'}, named...))
+	if _, err := Decompress(inComment); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a name inside a comment, got %v", err)
+	}
+	unsafe := wrap(ModeNamed, append([]byte{3, 0, 'a', '"', 'b'}, blob...))
+	if _, err := ReadName(unsafe); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for a name with a quote, got %v", err)
+	}
+}
+
+func TestNameHeader(t *testing.T) {
+	blob := mustCompress(t, []byte("streamed"))
+	head, err := NameHeader("streamed.txt")
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	named, err := AddName(blob, "streamed.txt")
+	if err != nil {
+		t.Fatalf("unexpected name error: %v", err)
+	}
+	if !bytes.Equal(append(head, blob...), named) {
+		t.Error("header followed by the blob does not match AddName")
+	}
+}
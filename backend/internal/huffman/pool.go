@@ -0,0 +1,99 @@
+package huffman
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer caps the output buffer kept in the pool so one huge input
+// does not pin its memory for the lifetime of the process.
+const maxPooledBuffer = 4 << 20
+
+// encodeScratch holds the per-call working state of HuffmanCompressBytes.
+// A scratch value is owned by exactly one goroutine between Get and Put.
+//
+// The symbols present in the input are remapped to the dense, ascending list
+// symbols, so tree building and header writing touch only those entries and
+// reuse the scratch's storage instead of allocating per call. The wire format
+// still records raw byte values.
+type encodeScratch struct {
+	counts  [256]int
+	symbols []byte
+	tree    treeBuilder
+	codes   codeTable
+	out     bytes.Buffer
+}
+
+var encodePool = sync.Pool{
+	New: func() any { return newEncodeScratch() },
+}
+
+func newEncodeScratch() *encodeScratch {
+	return &encodeScratch{symbols: make([]byte, 0, 256)}
+}
+
+func getEncodeScratch() *encodeScratch {
+	return encodePool.Get().(*encodeScratch)
+}
+
+func putEncodeScratch(s *encodeScratch) {
+	if s.out.Cap() > maxPooledBuffer {
+		return
+	}
+	s.reset()
+	encodePool.Put(s)
+}
+
+func (s *encodeScratch) reset() {
+	s.counts = [256]int{}
+	s.symbols = s.symbols[:0]
+	s.codes = codeTable{}
+	s.out.Reset()
+}
+
+// compress encodes data using the scratch state and appends the blob to dst,
+// reporting to progress if it is non-nil. The returned slice never aliases
+// pooled memory.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func (s *encodeScratch) compress(dst, data []byte, progress func(done, total int)) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	// Counting and encoding each visit every byte once, so total work is 2n.
+	total := 2 * len(data)
+	for i, b := range data {
+		if progress != nil && i > 0 && i%progressInterval == 0 {
+			progress(i, total)
+		}
+		s.counts[b]++
+	}
+	var encodeProgress func(done int)
+	if progress != nil {
+		progress(len(data), total)
+		encodeProgress = func(done int) { progress(len(data)+done, total) }
+	}
+	for b, f := range s.counts {
+		if f > 0 {
+			if err := checkFrequencyWidth(byte(b), f); err != nil {
+				return nil, err
+			}
+			s.symbols = append(s.symbols, byte(b))
+		}
+	}
+	buildCodeTable(s.tree.build(s.symbols, &s.counts), 0, 0, &s.codes)
+
+	totalBits := 0
+	for _, b := range s.symbols {
+		totalBits += s.counts[b] * int(s.codes[b].length)
+	}
+	// The blob size is known exactly from the code lengths, so the buffer
+	// is sized once instead of growing while the payload is written.
+	s.out.Grow(huffmanBlobSize(len(s.symbols), totalBits))
+	writeContainerHeader(&s.out, ModeHuffman)
+	s.out.Write(appendHeader(s.out.AvailableBuffer(), s.symbols, &s.counts))
+	s.out.Write(byteOrder.AppendUint64(s.out.AvailableBuffer(), uint64(totalBits)))
+	if _, err := encodeDataWithCount(&s.out, data, &s.codes, encodeProgress); err != nil {
+		return nil, err
+	}
+	return append(dst, s.out.Bytes()...), nil
+}
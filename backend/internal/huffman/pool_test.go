@@ -0,0 +1,211 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHuffmanCompressBytesConcurrent(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	inputs := make([][]byte, 16)
+	for i := range inputs {
+		inputs[i] = make([]byte, 512+rng.Intn(4096))
+		for j := range inputs[i] {
+			inputs[i][j] = byte(rng.Intn(4 + i*8))
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(inputs)*8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, in := range inputs {
+				compressed, err := HuffmanCompressBytes(in)
+				if err != nil {
+					errs <- err
+					return
+				}
+				out, err := HuffmanDecompress(compressed)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !bytes.Equal(out, in) {
+					errs <- fmt.Errorf("round trip mismatch for %d-byte input", len(in))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestHuffmanCompressBytesDoesNotAliasPool(t *testing.T) {
+	first, err := HuffmanCompressBytes([]byte("aaaaabbbbcccdde"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	want := bytes.Clone(first)
+	for i := 0; i < 10; i++ {
+		if _, err := HuffmanCompressBytes([]byte("zyxwvutsrqponm")); err != nil {
+			t.Fatalf("unexpected compress error: %v", err)
+		}
+	}
+	if !bytes.Equal(first, want) {
+		t.Error("earlier output was modified by later calls")
+	}
+}
+
+func BenchmarkHuffmanCompressBytesParallel(b *testing.B) {
+	rng := rand.New(rand.NewSource(9))
+	data := make([]byte, 16<<10)
+	for i := range data {
+		data[i] = byte(rng.NormFloat64()*16 + 128)
+	}
+
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := HuffmanCompressBytes(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("Unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := newEncodeScratch().compress(nil, data, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
+
+// referenceCompress assembles a ModeHuffman blob from the map-based helpers,
+// independently of the dense symbol path in encodeScratch.
+func referenceCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	freq := buildFrequencyTable(data)
+	var codes codeTable
+	buildCodeTable(buildHuffmanTree(freq), 0, 0, &codes)
+	head, err := writeHeader(freq)
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	var out bytes.Buffer
+	writeContainerHeader(&out, ModeHuffman)
+	out.Write(head)
+	var payload bytes.Buffer
+	totalBits, err := encodeDataWithCount(&payload, data, &codes, nil)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	out.Write(byteOrder.AppendUint64(nil, uint64(totalBits)))
+	out.Write(payload.Bytes())
+	return out.Bytes()
+}
+
+func TestHuffmanCompressBytesSparseAlphabet(t *testing.T) {
+	rng := rand.New(rand.NewSource(32))
+	s := newEncodeScratch()
+	// One scratch is reused across alphabets of shrinking and growing size,
+	// so stale dense state would show up in later outputs.
+	for _, alphabet := range []string{"ACGT", "\x00\xff", "z", "ACGT\x00\x01\x02\x03\x80\x81\xfe", "GT"} {
+		data := make([]byte, 1000)
+		for i := range data {
+			data[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		got, err := s.compress(nil, data, nil)
+		if err != nil {
+			t.Fatalf("alphabet %q: unexpected compress error: %v", alphabet, err)
+		}
+		s.reset()
+		if want := referenceCompress(t, data); !bytes.Equal(got, want) {
+			t.Errorf("alphabet %q: output differs from the map-based encoding", alphabet)
+		}
+		decompressed, err := Decompress(got)
+		if err != nil {
+			t.Fatalf("alphabet %q: unexpected decompress error: %v", alphabet, err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("alphabet %q: decompressed output does not match original", alphabet)
+		}
+	}
+}
+
+func BenchmarkHuffmanCompressBytesSparse(b *testing.B) {
+	rng := rand.New(rand.NewSource(32))
+	data := make([]byte, 64<<10)
+	for i := range data {
+		data[i] = "ACGT"[rng.Intn(4)]
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := HuffmanCompressBytes(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestHuffmanCompressInto(t *testing.T) {
+	data := []byte(strings.Repeat("hello world! the quick brown fox. ", 50))
+	want, err := HuffmanCompressBytes(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	prefix := []byte("prefix:")
+	dst := append(make([]byte, 0, len(prefix)+len(want)), prefix...)
+	got, err := HuffmanCompressInto(dst, data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], want) {
+		t.Errorf("expected the prefix followed by the HuffmanCompressBytes blob")
+	}
+	if &got[0] != &dst[:1][0] {
+		t.Errorf("output was reallocated despite enough spare capacity")
+	}
+
+	// A short dst grows like append.
+	grown, err := HuffmanCompressInto(prefix[:len(prefix):len(prefix)], data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if !bytes.Equal(grown[len(prefix):], want) {
+		t.Errorf("grown output does not match HuffmanCompressBytes")
+	}
+
+	// With a warm scratch and a reused buffer, compressing allocates nothing.
+	s := newEncodeScratch()
+	buf := make([]byte, 0, len(want))
+	if _, err := s.compress(buf, data, nil); err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		s.reset()
+		if _, err := s.compress(buf, data, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations into a buffer with spare capacity, got %.1f", allocs)
+	}
+}
@@ -0,0 +1,15 @@
+package huffman
+
+// progressInterval is how many bytes are processed between progress reports.
+const progressInterval = 64 << 10
+
+// HuffmanCompressWithProgress behaves like HuffmanCompressBytes and reports
+// progress through the frequency-counting and encoding passes. done grows
+// monotonically and reaches total, which is twice the input length, when
+// compression finishes. A nil progress disables reporting.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressWithProgress(data []byte, progress func(done, total int)) ([]byte, error) {
+	s := getEncodeScratch()
+	defer putEncodeScratch(s)
+	return s.compress(nil, data, progress)
+}
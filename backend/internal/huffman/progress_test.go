@@ -0,0 +1,62 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestHuffmanCompressWithProgress(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	data := make([]byte, 5*progressInterval+123)
+	for i := range data {
+		data[i] = byte(rng.Intn(32))
+	}
+
+	type call struct{ done, total int }
+	var calls []call
+	compressed, err := HuffmanCompressWithProgress(data, func(done, total int) {
+		calls = append(calls, call{done, total})
+	})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	if len(calls) < 10 {
+		t.Fatalf("expected periodic progress calls, got %d", len(calls))
+	}
+	for i, c := range calls {
+		if c.total != 2*len(data) {
+			t.Fatalf("call %d: expected total %d, got %d", i, 2*len(data), c.total)
+		}
+		if i > 0 && c.done <= calls[i-1].done {
+			t.Fatalf("call %d: progress not monotonic: %d after %d", i, c.done, calls[i-1].done)
+		}
+	}
+	if last := calls[len(calls)-1]; last.done != last.total {
+		t.Errorf("expected final progress %d, got %d", last.total, last.done)
+	}
+
+	decompressed, err := HuffmanDecompress(compressed)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Error("decompressed output does not match original")
+	}
+}
+
+func TestHuffmanCompressWithProgressNil(t *testing.T) {
+	content := []byte("aaaaabbbbcccdde")
+	compressed, err := HuffmanCompressWithProgress(content, nil)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	decompressed, err := HuffmanDecompress(compressed)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(decompressed, content) {
+		t.Errorf("decompressed output does not match original.\nGot: %v\nWant: %v", decompressed, content)
+	}
+}
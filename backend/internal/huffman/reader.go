@@ -0,0 +1,308 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// readerAtChunk is how much of an io.ReaderAt each compression pass reads
+// at a time.
+const readerAtChunk = 64 << 10
+
+// HuffmanCompressAll reads r until EOF and compresses everything it
+// returned. It is meant for pipes and network streams whose length is not
+// known up front; like HuffmanCompressBytes it rejects an empty stream.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressAll(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read input failed: %w", err)
+	}
+	return HuffmanCompressBytes(data)
+}
+
+// sizedPreallocLimit caps how much HuffmanCompressSized allocates on the
+// strength of its size hint alone, so a hint far larger than the stream
+// that follows, such as a forged Content-Length, cannot claim memory the
+// data never arrives to fill.
+const sizedPreallocLimit = 64 << 20
+
+// HuffmanCompressSized reads exactly size bytes from r and compresses them,
+// for streams such as proxied uploads whose length is known from a header
+// but which cannot seek. The buffer is allocated once from the hint, up to
+// 64 MiB, instead of growing as the stream is read. A stream that ends
+// before size bytes is an error wrapping io.ErrUnexpectedEOF; anything past
+// size bytes is left unread.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressSized(r io.Reader, size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid size hint %d", size)
+	}
+	if size == 0 {
+		return nil, ErrEmptyInput
+	}
+	data := make([]byte, 0, min(size, sizedPreallocLimit))
+	for int64(len(data)) < size {
+		if len(data) == cap(data) {
+			// Past the preallocation, the buffer doubles as data arrives.
+			data = slices.Grow(data, int(min(size-int64(len(data)), int64(cap(data)))))
+		}
+		n, err := r.Read(data[len(data):int(min(int64(cap(data)), size))])
+		data = data[:len(data)+n]
+		if err == io.EOF && int64(len(data)) < size {
+			return nil, fmt.Errorf("read input failed: stream ended after %d of %d bytes: %w", len(data), size, io.ErrUnexpectedEOF)
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("read input failed: %w", err)
+		}
+	}
+	return HuffmanCompressBytes(data)
+}
+
+// HuffmanCompressReaderAt compresses the first size bytes of r into w as a
+// ModeHuffman blob, identical to HuffmanCompressBytes over the same bytes.
+// It makes two passes over r, one to count frequencies and one to encode,
+// so only a chunk of the input is in memory at a time and the blob is
+// written as it is produced. It returns the number of bytes written to w.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func HuffmanCompressReaderAt(r io.ReaderAt, size int64, w io.Writer) (int64, error) {
+	return HuffmanCompressReaderAtSized(r, size, w, nil)
+}
+
+// HuffmanCompressReaderAtBytes compresses the first size bytes of r like
+// HuffmanCompressReaderAt and returns the blob, for inputs such as mmapped
+// files or multipart uploads spooled to disk that are read in place rather
+// than loaded into a slice. The blob is allocated once at its exact size.
+// Time Complexity: O(n + m log m), Space Complexity: O(m) beyond the blob
+func HuffmanCompressReaderAtBytes(r io.ReaderAt, size int64) ([]byte, error) {
+	var out sliceWriter
+	_, err := HuffmanCompressReaderAtSized(r, size, &out, func(info StreamInfo) {
+		out = make(sliceWriter, 0, info.BlobSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sliceWriter is an io.Writer appending to a byte slice. Unlike a
+// bytes.Buffer, it keeps the capacity it is given.
+type sliceWriter []byte
+
+func (s *sliceWriter) Write(p []byte) (int, error) {
+	*s = append(*s, p...)
+	return len(p), nil
+}
+
+// StreamInfo describes the blob HuffmanCompressReaderAtSized is about to
+// write, as known once frequencies are counted and the codes built.
+type StreamInfo struct {
+	// BlobSize is the exact size of the blob in bytes.
+	BlobSize int64
+	// LongestCode is the length in bits of the longest code.
+	LongestCode int
+}
+
+// DeepCodeWarning is DeepCodeWarning for the input being streamed, from
+// the codes already built rather than another pass over the input.
+// Time Complexity: O(1), Space Complexity: O(1)
+func (s StreamInfo) DeepCodeWarning() string {
+	return deepCodeWarning(s.LongestCode)
+}
+
+// HuffmanCompressReaderAtSized is HuffmanCompressReaderAt with a hook: the
+// blob's exact size and code lengths are known once frequencies are
+// counted, and onSize, if not nil, is called with them before anything is
+// written to w, so a caller can announce the size, for example as a
+// Content-Length.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func HuffmanCompressReaderAtSized(r io.ReaderAt, size int64, w io.Writer, onSize func(StreamInfo)) (int64, error) {
+	if size < 0 {
+		return 0, fmt.Errorf("invalid input size %d", size)
+	}
+	freq, err := countReaderAt(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return encodeReaderAt(r, size, freq, w, onSize)
+}
+
+// countReaderAt is the first pass of HuffmanCompressReaderAt, counting the
+// first size bytes of r.
+// Time Complexity: O(n), Space Complexity: O(m)
+func countReaderAt(r io.ReaderAt, size int64) (map[byte]int, error) {
+	if size == 0 {
+		return nil, ErrEmptyInput
+	}
+	var counts [256]int
+	err := scanReaderAt(r, size, func(chunk []byte) error {
+		for _, b := range chunk {
+			counts[b]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	freq := make(map[byte]int, 256)
+	for b, f := range counts {
+		if f > 0 {
+			freq[byte(b)] = f
+		}
+	}
+	return freq, nil
+}
+
+// encodeReaderAt is the second pass of HuffmanCompressReaderAt, encoding the
+// first size bytes of r into w with the codes of freq, their counts.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func encodeReaderAt(r io.ReaderAt, size int64, freq map[byte]int, w io.Writer, onSize func(StreamInfo)) (int64, error) {
+	var codes codeTable
+	buildCodeTable(buildHuffmanTree(freq), 0, 0, &codes)
+	totalBits, longest := 0, 0
+	for b, f := range freq {
+		totalBits += f * int(codes[b].length)
+		longest = max(longest, int(codes[b].length))
+	}
+	head, err := writeHeader(freq)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix := byteOrder.AppendUint64(wrap(ModeHuffman, head), uint64(totalBits))
+	blobSize := int64(len(prefix)) + int64((totalBits+7)/8)
+	if onSize != nil {
+		onSize(StreamInfo{BlobSize: blobSize, LongestCode: longest})
+	}
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(prefix); err != nil {
+		return 0, fmt.Errorf("write output failed: %w", err)
+	}
+	bits := newBitWriter(bw)
+	var counts [256]int
+	err = scanReaderAt(r, size, func(chunk []byte) error {
+		for _, b := range chunk {
+			if codes[b].length == 0 {
+				return fmt.Errorf("input changed between passes: symbol 0x%02x has no code", b)
+			}
+			if err := bits.writeCode(codes[b]); err != nil {
+				return fmt.Errorf("write output failed: %w", err)
+			}
+			counts[b]++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for b, f := range freq {
+		if counts[b] != f {
+			return 0, fmt.Errorf("input changed between passes: symbol 0x%02x occurs %d times, counted %d", b, counts[b], f)
+		}
+	}
+	if err := bits.Flush(); err != nil {
+		return 0, fmt.Errorf("write output failed: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("write output failed: %w", err)
+	}
+	return blobSize, nil
+}
+
+// scanReaderAt calls fn with successive chunks of the first size bytes of r.
+// Time Complexity: O(n), Space Complexity: O(1)
+func scanReaderAt(r io.ReaderAt, size int64, fn func(chunk []byte) error) error {
+	buf := make([]byte, min(size, readerAtChunk))
+	for off := int64(0); off < size; {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-off)], off)
+		if n > 0 {
+			if ferr := fn(buf[:n]); ferr != nil {
+				return ferr
+			}
+			off += int64(n)
+		}
+		if err == io.EOF && off < size {
+			return fmt.Errorf("read input failed: input ended after %d of %d bytes: %w", off, size, io.ErrUnexpectedEOF)
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("read input failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// HuffmanCompressReader compresses the rest of r into w like
+// HuffmanCompressReaderAt. If r can seek, both passes read it in place and
+// it is left at its end. Otherwise, as with pipes and network streams, the
+// whole input is buffered in memory first, costing one byte of memory per
+// input byte; use HuffmanCompressReaderStrict to refuse such input instead.
+// It returns the number of bytes written to w.
+// Time Complexity: O(n + m log m), Space Complexity: O(m), or O(n) if r cannot seek
+func HuffmanCompressReader(r io.Reader, w io.Writer) (int64, error) {
+	return compressReader(r, w, false)
+}
+
+// HuffmanCompressReaderStrict is HuffmanCompressReader, but returns
+// ErrNotSeekable rather than buffering input that cannot seek.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func HuffmanCompressReaderStrict(r io.Reader, w io.Writer) (int64, error) {
+	return compressReader(r, w, true)
+}
+
+func compressReader(r io.Reader, w io.Writer, strict bool) (int64, error) {
+	ra, size, ok := seekableSection(r)
+	if ok {
+		return HuffmanCompressReaderAt(ra, size, w)
+	}
+	if strict {
+		return 0, fmt.Errorf("two-pass compression: %w", ErrNotSeekable)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("read input failed: %w", err)
+	}
+	return HuffmanCompressReaderAt(bytes.NewReader(data), int64(len(data)), w)
+}
+
+// seekableSection returns the rest of r as an io.ReaderAt and its size, if r
+// can seek. Some readers, such as files opened on pipes, implement
+// io.Seeker but fail to seek, so seeking is probed rather than assumed.
+func seekableSection(r io.Reader) (io.ReaderAt, int64, bool) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, 0, false
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, false
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, false
+	}
+	if ra, ok := r.(io.ReaderAt); ok {
+		return io.NewSectionReader(ra, start, end-start), end - start, true
+	}
+	return &seekReaderAt{rs: rs, base: start}, end - start, true
+}
+
+// seekReaderAt adapts an io.ReadSeeker to io.ReaderAt by seeking before
+// every read. It is not safe for concurrent use.
+type seekReaderAt struct {
+	rs   io.ReadSeeker
+	base int64
+}
+
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := s.rs.Seek(s.base+off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(s.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,369 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestHuffmanCompressAll(t *testing.T) {
+	content := []byte(strings.Repeat("hello world! ", 500))
+
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{name: "Whole reads", r: bytes.NewReader(content)},
+		{name: "One byte at a time", r: iotest.OneByteReader(bytes.NewReader(content))},
+		{name: "Half reads", r: iotest.HalfReader(bytes.NewReader(content))},
+		{name: "EOF with final data", r: iotest.DataErrReader(bytes.NewReader(content))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := HuffmanCompressAll(tt.r)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			decompressed, err := HuffmanDecompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(content))
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressAllErrors(t *testing.T) {
+	if _, err := HuffmanCompressAll(bytes.NewReader(nil)); err == nil {
+		t.Error("expected error for empty stream but got nil")
+	}
+
+	boom := errors.New("boom")
+	if _, err := HuffmanCompressAll(iotest.ErrReader(boom)); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected read error to be reported, got %v", err)
+	}
+}
+
+func TestHuffmanCompressSized(t *testing.T) {
+	content := []byte(strings.Repeat("hello world! ", 500))
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		size int64
+	}{
+		{name: "Exact size", r: bytes.NewReader(content), size: int64(len(content))},
+		{name: "One byte at a time", r: iotest.OneByteReader(bytes.NewReader(content)), size: int64(len(content))},
+		{name: "EOF with final data", r: iotest.DataErrReader(bytes.NewReader(content)), size: int64(len(content))},
+		{name: "Longer stream", r: io.MultiReader(bytes.NewReader(content), strings.NewReader("trailing bytes")), size: int64(len(content))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := HuffmanCompressSized(tt.r, tt.size)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			decompressed, err := HuffmanDecompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(content))
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressSizedLeavesRest(t *testing.T) {
+	r := strings.NewReader("abcabcabc rest")
+	if _, err := HuffmanCompressSized(r, 9); err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if string(rest) != " rest" {
+		t.Errorf("expected %q left unread, got %q", " rest", rest)
+	}
+}
+
+func TestHuffmanCompressSizedErrors(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name    string
+		r       io.Reader
+		size    int64
+		wantErr error
+	}{
+		{name: "Short stream", r: strings.NewReader("abc"), size: 10, wantErr: io.ErrUnexpectedEOF},
+		{name: "Empty stream", r: strings.NewReader(""), size: 1, wantErr: io.ErrUnexpectedEOF},
+		{name: "Huge hint", r: strings.NewReader("abc"), size: 1 << 50, wantErr: io.ErrUnexpectedEOF},
+		{name: "Zero size", r: strings.NewReader("abc"), size: 0, wantErr: ErrEmptyInput},
+		{name: "Read error", r: iotest.ErrReader(boom), size: 3, wantErr: boom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := HuffmanCompressSized(tt.r, tt.size); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+	if _, err := HuffmanCompressSized(strings.NewReader("abc"), -1); err == nil {
+		t.Error("expected error for negative size but got nil")
+	}
+}
+
+func TestHuffmanCompressReaderAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Single symbol", content: []byte("aaaa")},
+		{name: "Text", content: []byte(strings.Repeat("hello world! ", 500))},
+		// Spans several chunks, with the last one partial.
+		{name: "Multiple chunks", content: bytes.Repeat([]byte("abcdefg"), 3*readerAtChunk/7+5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			announced := StreamInfo{BlobSize: -1}
+			n, err := HuffmanCompressReaderAtSized(bytes.NewReader(tt.content), int64(len(tt.content)), &out, func(info StreamInfo) {
+				if out.Len() != 0 {
+					t.Errorf("size announced after %d bytes were written", out.Len())
+				}
+				announced = info
+			})
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if n != int64(out.Len()) {
+				t.Errorf("reported %d bytes written, wrote %d", n, out.Len())
+			}
+			if announced.BlobSize != n {
+				t.Errorf("announced %d bytes, wrote %d", announced.BlobSize, n)
+			}
+			if longest, err := LongestCodeLength(tt.content); err != nil || announced.LongestCode != longest {
+				t.Errorf("announced a longest code of %d bits, want %d (%v)", announced.LongestCode, longest, err)
+			}
+			want, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Error("output differs from HuffmanCompressBytes")
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressReaderAtErrors(t *testing.T) {
+	if _, err := HuffmanCompressReaderAt(bytes.NewReader(nil), 0, io.Discard); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	// Claiming more bytes than the reader holds is a short read.
+	if _, err := HuffmanCompressReaderAt(bytes.NewReader([]byte("abc")), 10, io.Discard); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+// shortReaderAt returns at most n bytes per ReadAt call, as io.ReaderAt
+// allows when it also returns an error explaining why.
+type shortReaderAt struct {
+	r io.ReaderAt
+	n int
+}
+
+var errShortRead = errors.New("short read")
+
+func (s shortReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) <= s.n {
+		return s.r.ReadAt(p, off)
+	}
+	n, err := s.r.ReadAt(p[:s.n], off)
+	if err == nil {
+		err = errShortRead
+	}
+	return n, err
+}
+
+func TestHuffmanCompressReaderAtBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Single byte", content: []byte("a")},
+		{name: "Text", content: []byte(strings.Repeat("read me in place. ", 400))},
+		{name: "Multiple chunks", content: bytes.Repeat([]byte("0123456789"), readerAtChunk/4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HuffmanCompressReaderAtBytes(bytes.NewReader(tt.content), int64(len(tt.content)))
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			want, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Error("output differs from HuffmanCompressBytes")
+			}
+			if cap(got) != len(got) {
+				t.Errorf("expected a blob allocated at its exact size %d, got capacity %d", len(got), cap(got))
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressReaderAtBytesErrors(t *testing.T) {
+	content := []byte("hello, reader")
+	tests := []struct {
+		name    string
+		r       io.ReaderAt
+		size    int64
+		wantErr error
+	}{
+		{name: "Empty", r: bytes.NewReader(nil), size: 0, wantErr: ErrEmptyInput},
+		{name: "Size past end", r: bytes.NewReader(content), size: int64(len(content)) + 1, wantErr: io.ErrUnexpectedEOF},
+		{name: "Short read", r: shortReaderAt{r: bytes.NewReader(content), n: 4}, size: int64(len(content)), wantErr: errShortRead},
+		{name: "Negative size", r: bytes.NewReader(content), size: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HuffmanCompressReaderAtBytes(tt.r, tt.size)
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// A size short of the reader's length compresses only that prefix.
+	got, err := HuffmanCompressReaderAtBytes(bytes.NewReader(content), 5)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if want := mustCompress(t, content[:5]); !bytes.Equal(got, want) {
+		t.Error("expected the blob of the first 5 bytes")
+	}
+}
+
+// readSeekerOnly hides every method of its reader but Read and Seek.
+type readSeekerOnly struct{ io.ReadSeeker }
+
+func TestHuffmanCompressReaderSeekable(t *testing.T) {
+	content := []byte(strings.Repeat("seek and you shall find. ", 300))
+	want, err := HuffmanCompressBytes(content[5:])
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		r    func() io.ReadSeeker
+	}{
+		{name: "bytes.Reader", r: func() io.ReadSeeker { return bytes.NewReader(content) }},
+		{name: "Seeker without ReaderAt", r: func() io.ReadSeeker { return readSeekerOnly{bytes.NewReader(content)} }},
+	}
+
+	for _, tt := range tests {
+		for _, strict := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s strict=%v", tt.name, strict), func(t *testing.T) {
+				r := tt.r()
+				// Compression starts from the current position, not the start.
+				if _, err := r.Seek(5, io.SeekStart); err != nil {
+					t.Fatalf("seek failed: %v", err)
+				}
+				compress := HuffmanCompressReader
+				if strict {
+					compress = HuffmanCompressReaderStrict
+				}
+				var out bytes.Buffer
+				if _, err := compress(r, &out); err != nil {
+					t.Fatalf("unexpected compress error: %v", err)
+				}
+				if !bytes.Equal(out.Bytes(), want) {
+					t.Error("output differs from HuffmanCompressBytes")
+				}
+				if pos, _ := r.Seek(0, io.SeekCurrent); pos != int64(len(content)) {
+					t.Errorf("expected reader left at offset %d, got %d", len(content), pos)
+				}
+			})
+		}
+	}
+}
+
+func TestHuffmanCompressReaderNotSeekable(t *testing.T) {
+	content := []byte(strings.Repeat("pipes only go one way. ", 300))
+	want, err := HuffmanCompressBytes(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	pipes := []struct {
+		name string
+		open func(t *testing.T) io.Reader
+	}{
+		{name: "io.Pipe", open: func(t *testing.T) io.Reader {
+			pr, pw := io.Pipe()
+			t.Cleanup(func() { pr.Close() })
+			go func() { pw.CloseWithError(writeAll(pw, content)) }()
+			return pr
+		}},
+		// An *os.File on a pipe implements io.Seeker, but seeking fails.
+		{name: "os.Pipe", open: func(t *testing.T) io.Reader {
+			pr, pw, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("failed to open pipe: %v", err)
+			}
+			t.Cleanup(func() { pr.Close() })
+			go func() {
+				writeAll(pw, content)
+				pw.Close()
+			}()
+			return pr
+		}},
+	}
+
+	for _, p := range pipes {
+		t.Run(p.name+" buffered", func(t *testing.T) {
+			var out bytes.Buffer
+			if _, err := HuffmanCompressReader(p.open(t), &out); err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Error("output differs from HuffmanCompressBytes")
+			}
+		})
+		t.Run(p.name+" strict", func(t *testing.T) {
+			var out bytes.Buffer
+			if _, err := HuffmanCompressReaderStrict(p.open(t), &out); !errors.Is(err, ErrNotSeekable) {
+				t.Errorf("expected ErrNotSeekable, got %v", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("expected no output, got %d bytes", out.Len())
+			}
+		})
+	}
+}
+
+func writeAll(w io.Writer, data []byte) error {
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,77 @@
+package huffman
+
+import "math"
+
+// rleEncode collapses runs into (byte, count) pairs with counts of 1-255.
+// Time Complexity: O(n), Space Complexity: O(n)
+func rleEncode(data []byte) []byte {
+	tokens := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		b := data[i]
+		run := 1
+		for i+run < len(data) && data[i+run] == b && run < 255 {
+			run++
+		}
+		tokens = append(tokens, b, byte(run))
+		i += run
+	}
+	return tokens
+}
+
+// rleDecode expands (byte, count) pairs produced by rleEncode, failing once
+// the output would exceed maxSize bytes.
+// Time Complexity: O(n), Space Complexity: O(n)
+func rleDecode(tokens []byte, maxSize int) ([]byte, error) {
+	if len(tokens)%2 != 0 {
+		return nil, corruptf("invalid RLE stream: odd token count %d", len(tokens))
+	}
+	var out []byte
+	for i := 0; i < len(tokens); i += 2 {
+		if tokens[i+1] == 0 {
+			return nil, corruptf("invalid RLE stream: zero-length run at token %d", i/2)
+		}
+		if len(out)+int(tokens[i+1]) > maxSize {
+			return nil, sizeLimitError(maxSize)
+		}
+		for j := 0; j < int(tokens[i+1]); j++ {
+			out = append(out, tokens[i])
+		}
+	}
+	return out, nil
+}
+
+// HuffmanCompressRLE run-length encodes data before Huffman coding the token
+// stream. When the RLE pass does not pay for itself the plain ModeHuffman
+// encoding is returned instead; the container mode records which was chosen.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressRLE(data []byte) ([]byte, error) {
+	plain, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := HuffmanCompressBytes(rleEncode(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) < len(plain) {
+		// The token stream is itself a ModeHuffman body; only the mode differs.
+		return retag(runs, ModeRLE), nil
+	}
+	return plain, nil
+}
+
+// decodeRLEBody reverses HuffmanCompressRLE for a ModeRLE body.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeRLEBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	// Every run expands to at least one byte, so a valid stream holds at
+	// most two token bytes per output byte.
+	tokenLimit := maxSize
+	if maxSize <= math.MaxInt/2 {
+		tokenLimit = 2 * maxSize
+	}
+	tokens, err := decodeHuffmanBody(nil, body, tokenLimit, strict)
+	if err != nil {
+		return nil, err
+	}
+	return rleDecode(tokens, maxSize)
+}
@@ -0,0 +1,72 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestHuffmanCompressRLE(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	random := make([]byte, 4096)
+	rng.Read(random)
+
+	var runny []byte
+	for i := 0; i < 64; i++ {
+		runny = append(runny, bytes.Repeat([]byte{byte('a' + i%4)}, 100+rng.Intn(400))...)
+	}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		wantMode    Mode
+		wantShrink4 bool
+	}{
+		{name: "Single run", content: bytes.Repeat([]byte("a"), 10000), wantMode: ModeRLE, wantShrink4: true},
+		{name: "Long runs", content: runny, wantMode: ModeRLE, wantShrink4: true},
+		{name: "Run longer than a count byte", content: bytes.Repeat([]byte{0x00}, 256), wantMode: ModeRLE},
+		{name: "Random", content: random, wantMode: ModeHuffman},
+		{name: "Simple ASCII", content: []byte("aaaaabbbbcccdde"), wantMode: ModeHuffman},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := HuffmanCompressRLE(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if mode, _, _ := unwrap(compressed); mode != tt.wantMode {
+				t.Errorf("expected mode %v, got %v", tt.wantMode, mode)
+			}
+
+			plain, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if tt.wantShrink4 && len(compressed)*4 > len(plain) {
+				t.Errorf("expected RLE output (%d bytes) to be much smaller than plain (%d bytes)", len(compressed), len(plain))
+			}
+			if len(compressed) > len(plain) {
+				t.Errorf("RLE output (%d bytes) is larger than plain (%d bytes)", len(compressed), len(plain))
+			}
+
+			decompressed, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Errorf("decompressed output does not match original (got %d bytes, want %d)", len(decompressed), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestDecompressRLEInvalid(t *testing.T) {
+	blob, err := HuffmanCompressBytes([]byte("abc"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if _, err := Decompress(retag(blob, ModeRLE)); err == nil {
+		t.Error("expected error for odd-length token stream but got nil")
+	}
+}
@@ -0,0 +1,116 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Header is a frequency table shared out of band by many small messages, so
+// none of them pays for its own. Build one with BuildSharedHeader, store or
+// send it once with MarshalBinary, and restore it with ParseSharedHeader.
+// A Header is safe for concurrent use.
+type Header struct {
+	freq  map[byte]int
+	codes codeTable
+	table *decodeTable
+}
+
+// BuildSharedHeader counts the bytes of samples into a Header. Every byte
+// value gets one extra count, so messages may contain bytes the samples
+// never did at the cost of a slightly longer code for them.
+// Time Complexity: O(n + m log m) over all samples, Space Complexity: O(m)
+func BuildSharedHeader(samples [][]byte) (Header, error) {
+	if len(samples) == 0 {
+		return Header{}, errors.New("no samples to build a shared header from")
+	}
+	var counts [256]int
+	for _, s := range samples {
+		for _, b := range s {
+			counts[b]++
+		}
+	}
+	freq := make(map[byte]int, 256)
+	for b, c := range counts {
+		freq[byte(b)] = c + 1
+	}
+	return newSharedHeader(freq)
+}
+
+// ParseSharedHeader restores a Header written by MarshalBinary.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func ParseSharedHeader(data []byte) (Header, error) {
+	r := bytes.NewReader(data)
+	freq, err := readHeader(r)
+	if err != nil {
+		return Header{}, err
+	}
+	if r.Len() != 0 {
+		return Header{}, corruptf("invalid header: %d trailing bytes", r.Len())
+	}
+	if len(freq) == 0 {
+		return Header{}, corruptf("invalid header: no symbols")
+	}
+	return newSharedHeader(freq)
+}
+
+func newSharedHeader(freq map[byte]int) (Header, error) {
+	root, err := buildModelTree(freq)
+	if err != nil {
+		return Header{}, err
+	}
+	h := Header{freq: freq, table: newDecodeTable(root, tableBitsFor(root, freq))}
+	buildCodeTable(root, 0, 0, &h.codes)
+	return h, nil
+}
+
+// MarshalBinary serializes h in the frequency table format of a ModeHuffman
+// header.
+func (h Header) MarshalBinary() ([]byte, error) {
+	if h.table == nil {
+		return nil, errors.New("shared header is not initialized")
+	}
+	return writeHeader(h.freq)
+}
+
+// CompressWithHeader encodes data with h's codes and nothing else: no
+// container header and no frequency table, only a uvarint bit length and the
+// packed codes. The result can only be decoded by DecompressWithHeader with
+// the same Header.
+// Time Complexity: O(n), Space Complexity: O(n)
+func CompressWithHeader(data []byte, h Header) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if h.table == nil {
+		return nil, errors.New("shared header is not initialized")
+	}
+	totalBits := uint64(0)
+	for _, b := range data {
+		totalBits += uint64(h.codes[b].length)
+	}
+	buf := make([]byte, 0, binary.MaxVarintLen64+int((totalBits+7)/8))
+	out := bytes.NewBuffer(binary.AppendUvarint(buf, totalBits))
+	if _, err := encodeDataWithCount(out, data, &h.codes, nil); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// DecompressWithHeader reverses CompressWithHeader. Output is capped at
+// DefaultMaxDecompressedSize bytes.
+// Time Complexity: O(n), Space Complexity: O(n)
+func DecompressWithHeader(payload []byte, h Header) ([]byte, error) {
+	if h.table == nil {
+		return nil, errors.New("shared header is not initialized")
+	}
+	totalBits, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return nil, corruptf("read bit length failed: invalid uvarint")
+	}
+	bitData := payload[n:]
+	if err := checkPayloadLength(bitData, totalBits); err != nil {
+		return nil, err
+	}
+	return h.table.decode(nil, bitData, totalBits, DefaultMaxDecompressedSize, true)
+}
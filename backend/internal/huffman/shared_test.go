@@ -0,0 +1,85 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSharedHeader(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 50; i++ {
+		samples = append(samples, []byte(fmt.Sprintf("level=info msg=\"request served\" status=200 id=%d", i)))
+	}
+	h, err := BuildSharedHeader(samples)
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	raw, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	restored, err := ParseSharedHeader(raw)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	messages := [][]byte{
+		[]byte("level=info msg=\"request served\" status=200 id=7"),
+		[]byte("level=warn msg=\"slow request\" status=200 id=99"),
+		// Bytes the samples never contained still encode.
+		[]byte("level=error msg=\"caf\xc3\xa9\" status=500\x00\xff"),
+		[]byte("x"),
+	}
+
+	for i, msg := range messages {
+		payload, err := CompressWithHeader(msg, h)
+		if err != nil {
+			t.Fatalf("message %d: unexpected compress error: %v", i, err)
+		}
+		plain, err := HuffmanCompressBytes(msg)
+		if err != nil {
+			t.Fatalf("message %d: unexpected compress error: %v", i, err)
+		}
+		if len(payload) >= len(plain) {
+			t.Errorf("message %d: headerless payload (%d bytes) is not smaller than a blob (%d bytes)", i, len(payload), len(plain))
+		}
+		got, err := DecompressWithHeader(payload, restored)
+		if err != nil {
+			t.Fatalf("message %d: unexpected decompress error: %v", i, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("message %d: decompressed output does not match original.\nGot: %q\nWant: %q", i, got, msg)
+		}
+	}
+}
+
+func TestSharedHeaderErrors(t *testing.T) {
+	if _, err := BuildSharedHeader(nil); err == nil {
+		t.Error("expected error for no samples but got nil")
+	}
+	h, err := BuildSharedHeader([][]byte{[]byte("abc")})
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	if _, err := CompressWithHeader(nil, h); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	if _, err := CompressWithHeader([]byte("abc"), Header{}); err == nil {
+		t.Error("expected error for zero Header but got nil")
+	}
+
+	payload, err := CompressWithHeader([]byte("abcabc"), h)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	for _, bad := range [][]byte{nil, payload[:len(payload)-1], append(bytes.Clone(payload), 0)} {
+		if _, err := DecompressWithHeader(bad, h); !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("expected ErrCorruptStream for %x, got %v", bad, err)
+		}
+	}
+	if _, err := ParseSharedHeader([]byte{0, 0}); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("expected ErrCorruptStream for empty header, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package huffman
+
+import "slices"
+
+// SymbolStats describes how one byte value is coded.
+type SymbolStats struct {
+	Symbol     byte `json:"symbol"`
+	Frequency  int  `json:"frequency"`
+	CodeLength int  `json:"codeLength"`
+	// Bits is the symbol's share of the payload, Frequency * CodeLength.
+	Bits int `json:"bits"`
+	// BitsSaved is how many bits coding the symbol saves over storing it as
+	// whole bytes. It is negative for rare symbols with codes over 8 bits.
+	BitsSaved int `json:"bitsSaved"`
+}
+
+// CompressStats describes a blob written by HuffmanCompressWithStats.
+type CompressStats struct {
+	OriginalSize   int `json:"originalSize"`
+	CompressedSize int `json:"compressedSize"`
+	// HeaderSize counts the container header and the frequency table.
+	HeaderSize int `json:"headerSize"`
+	TotalBits  int `json:"totalBits"`
+	// Symbols lists every byte value present, those contributing the most
+	// payload bits first and ties in ascending symbol order.
+	Symbols []SymbolStats `json:"symbols"`
+}
+
+// HuffmanCompressWithStats compresses data like HuffmanCompressBytes and
+// also returns a breakdown of the blob, including each symbol's share of
+// the payload. The per-symbol Bits add up to TotalBits.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressWithStats(data []byte) ([]byte, CompressStats, error) {
+	blob, err := HuffmanCompressBytes(data)
+	if err != nil {
+		return nil, CompressStats{}, err
+	}
+	freq := buildFrequencyTable(data)
+	lengths := make(map[byte]int, len(freq))
+	codeLengths(buildHuffmanTree(freq), 0, lengths)
+
+	stats := CompressStats{
+		OriginalSize:   len(data),
+		CompressedSize: len(blob),
+		HeaderSize:     containerHeaderSize + headerSize(len(freq)),
+		Symbols:        make([]SymbolStats, 0, len(freq)),
+	}
+	for b, f := range freq {
+		bits := f * lengths[b]
+		stats.TotalBits += bits
+		stats.Symbols = append(stats.Symbols, SymbolStats{
+			Symbol:     b,
+			Frequency:  f,
+			CodeLength: lengths[b],
+			Bits:       bits,
+			BitsSaved:  8*f - bits,
+		})
+	}
+	slices.SortFunc(stats.Symbols, func(a, b SymbolStats) int {
+		if a.Bits != b.Bits {
+			return b.Bits - a.Bits
+		}
+		return int(a.Symbol) - int(b.Symbol)
+	})
+	return blob, stats, nil
+}
@@ -0,0 +1,81 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestHuffmanCompressWithStats(t *testing.T) {
+	// a:5 b:2 r:2 c:1 d:1 codes a in 1 bit and b and r in 3, so the rarer
+	// b and r each contribute more of the payload than a.
+	data := []byte("abracadabra")
+	blob, stats, err := HuffmanCompressWithStats(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if want := mustCompress(t, data); !bytes.Equal(blob, want) {
+		t.Errorf("expected the HuffmanCompressBytes blob")
+	}
+	header, totalBits, _ := splitBlob(t, blob)
+	if stats.TotalBits != int(totalBits) {
+		t.Errorf("expected TotalBits %d, got %d", totalBits, stats.TotalBits)
+	}
+	if want := containerHeaderSize + len(header); stats.HeaderSize != want {
+		t.Errorf("expected HeaderSize %d, got %d", want, stats.HeaderSize)
+	}
+	if stats.OriginalSize != len(data) || stats.CompressedSize != len(blob) {
+		t.Errorf("expected sizes %d and %d, got %d and %d", len(data), len(blob), stats.OriginalSize, stats.CompressedSize)
+	}
+	if len(stats.Symbols) != 5 {
+		t.Fatalf("expected 5 symbols, got %d", len(stats.Symbols))
+	}
+
+	lengths, err := CodeLengths(data)
+	if err != nil {
+		t.Fatalf("unexpected code length error: %v", err)
+	}
+	sum := 0
+	for i, s := range stats.Symbols {
+		sum += s.Bits
+		if s.Frequency != bytes.Count(data, []byte{s.Symbol}) {
+			t.Errorf("symbol %q: expected frequency %d, got %d", s.Symbol, bytes.Count(data, []byte{s.Symbol}), s.Frequency)
+		}
+		if s.CodeLength != lengths[s.Symbol] {
+			t.Errorf("symbol %q: expected code length %d, got %d", s.Symbol, lengths[s.Symbol], s.CodeLength)
+		}
+		if s.Bits != s.Frequency*s.CodeLength || s.BitsSaved != 8*s.Frequency-s.Bits {
+			t.Errorf("symbol %q: inconsistent bits %+v", s.Symbol, s)
+		}
+		if i > 0 {
+			prev := stats.Symbols[i-1]
+			if prev.Bits < s.Bits || (prev.Bits == s.Bits && prev.Symbol > s.Symbol) {
+				t.Errorf("symbols out of order: %+v before %+v", prev, s)
+			}
+		}
+	}
+	if sum != stats.TotalBits {
+		t.Errorf("expected per-symbol bits to sum to %d, got %d", stats.TotalBits, sum)
+	}
+	if top := stats.Symbols[0]; top.Symbol != 'b' || top.Bits != 6 {
+		t.Errorf("expected 'b' to contribute the most bits, got %+v", top)
+	}
+
+	if _, _, err := HuffmanCompressWithStats(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
+
+func TestHuffmanCompressWithStatsSingleSymbol(t *testing.T) {
+	_, stats, err := HuffmanCompressWithStats([]byte("zzzz"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	want := SymbolStats{Symbol: 'z', Frequency: 4, CodeLength: 1, Bits: 4, BitsSaved: 28}
+	if len(stats.Symbols) != 1 || stats.Symbols[0] != want {
+		t.Errorf("expected %+v, got %+v", want, stats.Symbols)
+	}
+	if stats.TotalBits != 4 {
+		t.Errorf("expected TotalBits 4, got %d", stats.TotalBits)
+	}
+}
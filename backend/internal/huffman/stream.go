@@ -0,0 +1,188 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// HuffmanDecompressStream decodes a blob read from r into w, returning the
+// number of bytes decoded. A ModeHuffman blob, such as HuffmanCompressReader
+// writes, is decoded as it is read, holding only its frequency table in
+// memory; any other mode is read whole, up to DefaultMaxDecompressedSize
+// bytes past the container header, and passed to Decompress. Input bytes
+// past the end of the blob are an error.
+//
+// A ModeHuffman stream is written to w as it is decoded, before the
+// payload's end and the decoded count can be checked, so when an error is
+// returned w may already hold part of the output. Callers that need all or
+// nothing should decode to a temporary destination and discard it on
+// error.
+//
+// progress, if not nil, is called as output is written with the bytes
+// decoded so far and the total the blob decodes to. A ModeHuffman header's
+// frequencies add up to that total, so it is known before the first byte
+// and done reaches it exactly once the stream ends; other modes report once,
+// when their output has been written.
+// Time Complexity: O(n + m log m), Space Complexity: O(m), or O(n) for modes
+// other than ModeHuffman
+func HuffmanDecompressStream(r io.Reader, w io.Writer, progress func(done, total int64)) (int64, error) {
+	return decompressStream(r, w, DefaultMaxDecompressedSize, progress)
+}
+
+// decompressStream is HuffmanDecompressStream with output, and the input
+// read whole for modes other than ModeHuffman, capped at maxSize bytes.
+// Time Complexity: O(n + m log m), Space Complexity: O(m), or O(n) for modes
+// other than ModeHuffman
+func decompressStream(r io.Reader, w io.Writer, maxSize int, progress func(done, total int64)) (int64, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, containerHeaderSize)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return 0, corruptf("read container header failed: %w", err)
+	}
+	mode, _, err := unwrap(head)
+	if err != nil {
+		return 0, err
+	}
+	if mode != ModeHuffman {
+		// A blob of these modes is no smaller than what it decodes to by
+		// more than its headers, so one past the output cap is too large.
+		rest, err := io.ReadAll(io.LimitReader(br, int64(maxSize)+1))
+		if err != nil {
+			return 0, fmt.Errorf("read input failed: %w", err)
+		}
+		if len(rest) > maxSize {
+			return 0, sizeLimitError(maxSize)
+		}
+		out, err := DecompressWithLimit(append(head, rest...), maxSize)
+		if err != nil {
+			return 0, err
+		}
+		n, err := w.Write(out)
+		if err != nil {
+			return int64(n), err
+		}
+		if progress != nil {
+			progress(int64(n), int64(n))
+		}
+		return int64(n), nil
+	}
+
+	freq, totalBits, err := readStreamHeader(br)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, f := range freq {
+		size += int64(f)
+	}
+	if size > int64(maxSize) {
+		return 0, sizeLimitError(maxSize)
+	}
+	if len(freq) == 0 {
+		if totalBits > 0 {
+			return 0, corruptf("invalid header: empty symbol table with nonzero payload of %d bits", totalBits)
+		}
+		return 0, nil
+	}
+	return decodeStream(br, w, buildHuffmanTree(freq), totalBits, size, progress)
+}
+
+// readStreamHeader reads the frequency table and bit length of a ModeHuffman
+// body from br.
+// Time Complexity: O(m), Space Complexity: O(m)
+func readStreamHeader(br *bufio.Reader) (map[byte]int, uint64, error) {
+	var count [2]byte
+	if _, err := io.ReadFull(br, count[:]); err != nil {
+		return nil, 0, corruptf("read header entries failed: %w", err)
+	}
+	m := int(byteOrder.Uint16(count[:]))
+	if m > 256 {
+		return nil, 0, corruptf("invalid header: %d entries exceeds 256 symbols", m)
+	}
+	head := make([]byte, headerSize(m)+8)
+	copy(head, count[:])
+	if _, err := io.ReadFull(br, head[2:]); err != nil {
+		return nil, 0, corruptf("read header failed: %w", err)
+	}
+	freq, err := readHeader(bytes.NewReader(head[:headerSize(m)]))
+	if err != nil {
+		return nil, 0, err
+	}
+	return freq, byteOrder.Uint64(head[headerSize(m):]), nil
+}
+
+// decodeStream walks root over the totalBits payload bits read from br,
+// writing the size bytes they decode to through a buffer and reporting
+// progress every progressInterval bytes.
+// Time Complexity: O(n), Space Complexity: O(1)
+func decodeStream(br *bufio.Reader, w io.Writer, root *Node, totalBits uint64, size int64, progress func(done, total int64)) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var done int64
+	emit := func(b byte) error {
+		if done == size {
+			return corruptf("payload decodes to more than the %d bytes its header records", size)
+		}
+		if err := bw.WriteByte(b); err != nil {
+			return err
+		}
+		done++
+		if progress != nil && done%progressInterval == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			progress(done, size)
+		}
+		return nil
+	}
+
+	node := root
+	var pos uint64
+	for pos < totalBits {
+		cur, err := br.ReadByte()
+		if err != nil {
+			return done, truncatedError(pos, totalBits)
+		}
+		for bit := 0; bit < 8 && pos < totalBits; bit++ {
+			pos++
+			if root.Left == nil {
+				// Single-symbol tree: every bit encodes one occurrence.
+				if err := emit(root.Char); err != nil {
+					return done, err
+				}
+				continue
+			}
+			if cur&(0x80>>bit) == 0 {
+				node = node.Left
+			} else {
+				node = node.Right
+			}
+			if node.Left == nil {
+				if err := emit(node.Char); err != nil {
+					return done, err
+				}
+				node = root
+			}
+		}
+	}
+	if node != root {
+		return done, corruptf("trailing bits do not form a complete code")
+	}
+	if done != size {
+		return done, corruptf("payload decodes to %d bytes, header records %d", done, size)
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		if err != nil {
+			return done, fmt.Errorf("read input failed: %w", err)
+		}
+		return done, corruptf("trailing bytes past %d payload bits", totalBits)
+	}
+	if err := bw.Flush(); err != nil {
+		return done, err
+	}
+	if progress != nil && done%progressInterval != 0 {
+		progress(done, size)
+	}
+	return done, nil
+}
@@ -0,0 +1,143 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanDecompressStream(t *testing.T) {
+	rng := rand.New(rand.NewSource(368))
+	large := make([]byte, 3*progressInterval+123)
+	for i := range large {
+		large[i] = "abcdefgh"[rng.Intn(8)]
+	}
+	store, err := CompressStore([]byte("stored verbatim"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	limited, err := HuffmanCompressLimited(large, 8)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		content []byte
+	}{
+		{name: "Text", blob: mustCompress(t, []byte(strings.Repeat("hello world! ", 100))), content: []byte(strings.Repeat("hello world! ", 100))},
+		{name: "Several intervals", blob: mustCompress(t, large), content: large},
+		{name: "Exact interval", blob: mustCompress(t, large[:2*progressInterval]), content: large[:2*progressInterval]},
+		{name: "Single symbol", blob: mustCompress(t, bytes.Repeat([]byte{'z'}, 1000)), content: bytes.Repeat([]byte{'z'}, 1000)},
+		{name: "Store", blob: store, content: []byte("stored verbatim")},
+		{name: "Canonical", blob: limited, content: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var reports [][2]int64
+			n, err := HuffmanDecompressStream(bytes.NewReader(tt.blob), &out, func(done, total int64) {
+				if int64(out.Len()) != done {
+					t.Errorf("reported %d bytes done with %d written", done, out.Len())
+				}
+				reports = append(reports, [2]int64{done, total})
+			})
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if n != int64(len(tt.content)) || !bytes.Equal(out.Bytes(), tt.content) {
+				t.Fatalf("decompressed output does not match original (got %d bytes, want %d)", n, len(tt.content))
+			}
+			if len(reports) == 0 {
+				t.Fatal("expected progress reports")
+			}
+			for i, r := range reports {
+				if r[1] != int64(len(tt.content)) {
+					t.Errorf("report %d: expected total %d, got %d", i, len(tt.content), r[1])
+				}
+				if i > 0 && r[0] <= reports[i-1][0] {
+					t.Errorf("report %d: progress went from %d to %d", i, reports[i-1][0], r[0])
+				}
+			}
+			if last := reports[len(reports)-1]; last[0] != last[1] {
+				t.Errorf("expected progress to end at %d, got %d", last[1], last[0])
+			}
+		})
+	}
+}
+
+func TestHuffmanDecompressStreamNilProgress(t *testing.T) {
+	content := []byte("abracadabra")
+	var out bytes.Buffer
+	if _, err := HuffmanDecompressStream(bytes.NewReader(mustCompress(t, content)), &out, nil); err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), content) {
+		t.Error("decompressed output does not match original")
+	}
+}
+
+func TestHuffmanDecompressStreamCorrupt(t *testing.T) {
+	blob := mustCompress(t, []byte("abracadabra"))
+	// A single-symbol table whose 4 payload bits decode to four 'a's, two
+	// more than the header records.
+	overlong := craftBlob(1, []headerEntry{{sym: 'a', freq: 2}}, 4, []byte{0x00})
+	tests := []struct {
+		name    string
+		blob    []byte
+		wantErr error
+	}{
+		{name: "Empty", blob: nil, wantErr: ErrCorruptStream},
+		{name: "Bad magic", blob: []byte("HUFZ\x02\x01"), wantErr: ErrBadMagic},
+		{name: "Short header", blob: blob[:containerHeaderSize+3], wantErr: ErrCorruptStream},
+		{name: "Truncated payload", blob: blob[:len(blob)-1], wantErr: ErrTruncated},
+		{name: "Trailing bytes", blob: append(bytes.Clone(blob), 0), wantErr: ErrCorruptStream},
+		{name: "More symbols than header", blob: overlong, wantErr: ErrCorruptStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if _, err := HuffmanDecompressStream(bytes.NewReader(tt.blob), &out, nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDecompressStreamLimit(t *testing.T) {
+	data := []byte(strings.Repeat("limit ", 20))
+	stored, err := CompressStore(data)
+	if err != nil {
+		t.Fatalf("unexpected store error: %v", err)
+	}
+	// A reader that never ends stands in for an oversized upload; it must
+	// be cut off rather than read whole.
+	endless := io.MultiReader(bytes.NewReader(stored), neverEOF{})
+	if _, err := decompressStream(endless, io.Discard, len(data), nil); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge for input past the limit, got %v", err)
+	}
+	for _, blob := range [][]byte{stored, mustCompress(t, data)} {
+		mode, _ := ModeOf(blob)
+		if _, err := decompressStream(bytes.NewReader(blob), io.Discard, len(data)-1, nil); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: expected ErrTooLarge for output past the limit, got %v", mode, err)
+		}
+		var out bytes.Buffer
+		if _, err := decompressStream(bytes.NewReader(blob), &out, len(data), nil); err != nil || !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("%s: expected the output within the limit, got %v", mode, err)
+		}
+	}
+}
+
+// neverEOF is a reader of endless zero bytes.
+type neverEOF struct{}
+
+func (neverEOF) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,197 @@
+package huffman
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// maxAutoTableBits caps the automatically chosen lookup width, keeping
+	// the table at 4096 entries however deep the tree is.
+	maxAutoTableBits = 12
+	// MaxTableBits is the widest lookup table a Decoder can be asked for.
+	MaxTableBits = 16
+	// tableBitsSlack is how far past the average code length the automatic
+	// width reaches, so most codes resolve in a single lookup.
+	tableBitsSlack = 4
+)
+
+// tableEntry is the result of looking up the next bits of a stream. For
+// codes no longer than the table width, node is the leaf and length its code
+// length; otherwise node is the internal node reached after width bits and
+// decoding continues bit by bit from there.
+type tableEntry struct {
+	node   *Node
+	length uint8
+}
+
+// decodeTable decodes a tree's codes width bits at a time.
+type decodeTable struct {
+	width   int
+	entries []tableEntry
+}
+
+// tableBitsFor picks a lookup width for root from its maximum and average
+// code lengths. Codes are weighted by freq, or equally if freq is nil.
+// Time Complexity: O(m), Space Complexity: O(m)
+func tableBitsFor(root *Node, freq map[byte]int) int {
+	maxLen, weighted, total := 0, 0, 0
+	var walk func(n *Node, depth int)
+	walk = func(n *Node, depth int) {
+		if n.Left == nil && n.Right == nil {
+			depth = max(depth, 1)
+			w := 1
+			if freq != nil {
+				w = freq[n.Char]
+			}
+			maxLen = max(maxLen, depth)
+			weighted += w * depth
+			total += w
+			return
+		}
+		walk(n.Left, depth+1)
+		walk(n.Right, depth+1)
+	}
+	walk(root, 0)
+	avg := int(math.Ceil(float64(weighted) / float64(max(total, 1))))
+	return max(1, min(maxLen, maxAutoTableBits, avg+tableBitsSlack))
+}
+
+// newDecodeTable builds a lookup table of the given width for root. A
+// single-leaf tree gets a one-bit table in which both bits decode the leaf.
+// Time Complexity: O(2^width + m), Space Complexity: O(2^width)
+func newDecodeTable(root *Node, width int) *decodeTable {
+	if root.Left == nil && root.Right == nil {
+		width = 1
+	}
+	t := &decodeTable{width: width, entries: make([]tableEntry, 1<<width)}
+	if width == 1 && root.Left == nil {
+		t.entries[0] = tableEntry{node: root, length: 1}
+		t.entries[1] = tableEntry{node: root, length: 1}
+		return t
+	}
+	t.fill(root, 0, 0)
+	return t
+}
+
+func (t *decodeTable) fill(n *Node, code, depth int) {
+	if n.Left == nil && n.Right == nil {
+		shift := t.width - depth
+		for s := 0; s < 1<<shift; s++ {
+			t.entries[code<<shift|s] = tableEntry{node: n, length: uint8(depth)}
+		}
+		return
+	}
+	if depth == t.width {
+		t.entries[code] = tableEntry{node: n, length: uint8(depth)}
+		return
+	}
+	t.fill(n.Left, code<<1, depth+1)
+	t.fill(n.Right, code<<1|1, depth+1)
+}
+
+// peek returns the width bits of bitData starting at bit pos, reading zeros
+// past the end of the data.
+func (t *decodeTable) peek(bitData []byte, pos uint64) int {
+	i := pos / 8
+	var window uint32
+	for j := uint64(0); j < 3; j++ {
+		window <<= 8
+		if i+j < uint64(len(bitData)) {
+			window |= uint32(bitData[i+j])
+		}
+	}
+	// The 24-bit window holds at least 17 bits after pos, enough for any
+	// table up to MaxTableBits wide.
+	return int(window<<(8+pos%8)) >> (32 - t.width) & (1<<t.width - 1)
+}
+
+// decode decodes the first totalBits bits of bitData and appends the output
+// to dst, failing once it would exceed maxSize bytes. The encoder only ever
+// writes whole codes, so a stream whose last code is cut off by totalBits is
+// damaged; strict rejects it, and otherwise the partial code is dropped.
+// Time Complexity: O(n), Space Complexity: O(n)
+func (t *decodeTable) decode(dst, bitData []byte, totalBits uint64, maxSize int, strict bool) ([]byte, error) {
+	// Checking the bit length against the payload up front fails fast on
+	// truncated streams, before any output is produced.
+	if uint64(len(bitData)) < (totalBits+7)/8 {
+		return nil, truncatedError(uint64(len(bitData))*8, totalBits)
+	}
+	out := dst
+	var pos uint64
+	for pos < totalBits {
+		start := pos
+		e := t.entries[t.peek(bitData, pos)]
+		pos += uint64(e.length)
+		if pos > totalBits {
+			return partialCode(out, start, totalBits, strict)
+		}
+		node := e.node
+		for node.Left != nil {
+			if pos == totalBits {
+				return partialCode(out, start, totalBits, strict)
+			}
+			if bitData[pos/8]&(0x80>>(pos%8)) == 0 {
+				node = node.Left
+			} else {
+				node = node.Right
+			}
+			pos++
+		}
+		if len(out)-len(dst) == maxSize {
+			return nil, sizeLimitError(maxSize)
+		}
+		out = append(out, node.Char)
+	}
+	return out, nil
+}
+
+// partialCode ends a decode whose final code, starting at bit start, runs
+// past totalBits.
+func partialCode(out []byte, start, totalBits uint64, strict bool) ([]byte, error) {
+	if strict {
+		return nil, corruptf("trailing bits do not form a complete code: %d bits left at bit %d", totalBits-start, start)
+	}
+	return out, nil
+}
+
+// checkPayloadLength reports a payload whose length is not exactly the
+// ceil(totalBits/8) bytes that totalBits needs. Short payloads are truncated;
+// long ones carry bytes no code accounts for, which usually means the bit
+// length or the payload has been damaged.
+func checkPayloadLength(payload []byte, totalBits uint64) error {
+	want := (totalBits + 7) / 8
+	if uint64(len(payload)) < want {
+		return truncatedError(uint64(len(payload))*8, totalBits)
+	}
+	if uint64(len(payload)) > want {
+		return corruptf("payload of %d bytes has %d trailing bytes past %d bits", len(payload), uint64(len(payload))-want, totalBits)
+	}
+	return nil
+}
+
+// checkPayload applies checkPayloadLength if strict, and otherwise only
+// rejects payloads too short for totalBits.
+func checkPayload(payload []byte, totalBits uint64, strict bool) error {
+	if strict || uint64(len(payload)) < (totalBits+7)/8 {
+		return checkPayloadLength(payload, totalBits)
+	}
+	return nil
+}
+
+// SetTableBits overrides the lookup width the Decoder chose from its header.
+// Wider tables resolve more codes per lookup at the cost of 2^bits entries.
+// It must not be called concurrently with Decode.
+// Time Complexity: O(2^bits + m), Space Complexity: O(2^bits)
+func (d *Decoder) SetTableBits(bits int) error {
+	if bits < 1 || bits > MaxTableBits {
+		return fmt.Errorf("table bits %d outside 1-%d", bits, MaxTableBits)
+	}
+	d.table = newDecodeTable(d.root, bits)
+	return nil
+}
+
+// TableBits reports the lookup width in use.
+func (d *Decoder) TableBits() int {
+	return d.table.width
+}
@@ -0,0 +1,87 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDecoderTableBits(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	shallow := make([]byte, 4096)
+	for i := range shallow {
+		shallow[i] = byte(rng.Intn(16))
+	}
+	deep := fibonacciData(26)
+	rng.Shuffle(len(deep), func(i, j int) { deep[i], deep[j] = deep[j], deep[i] })
+
+	tests := []struct {
+		name     string
+		content  []byte
+		wantBits int
+	}{
+		// Sixteen equally likely symbols all get 4-bit codes, so a 4-bit
+		// table resolves every code in one lookup.
+		{name: "Shallow tree", content: shallow, wantBits: 4},
+		// The rarest Fibonacci symbols sit 25 levels deep, but most of the
+		// input uses short codes, so the width stays well under the cap.
+		{name: "Deep tree", content: deep, wantBits: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := HuffmanCompressBytes(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			header, totalBits, payload := splitBlob(t, blob)
+			dec, err := NewDecoder(header)
+			if err != nil {
+				t.Fatalf("unexpected decoder error: %v", err)
+			}
+			if got := dec.TableBits(); got != tt.wantBits {
+				t.Errorf("expected %d table bits, got %d", tt.wantBits, got)
+			}
+			if dec.TableBits() > maxAutoTableBits {
+				t.Errorf("table bits %d exceed the cap of %d", dec.TableBits(), maxAutoTableBits)
+			}
+
+			for _, bits := range []int{0, 1, 3, 8, MaxTableBits} {
+				if bits > 0 {
+					if err := dec.SetTableBits(bits); err != nil {
+						t.Fatalf("unexpected error setting %d table bits: %v", bits, err)
+					}
+				}
+				decoded, err := dec.Decode(payload, totalBits)
+				if err != nil {
+					t.Fatalf("%d table bits: unexpected decode error: %v", dec.TableBits(), err)
+				}
+				if !bytes.Equal(decoded, tt.content) {
+					t.Errorf("%d table bits: decoded output does not match original", dec.TableBits())
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderSetTableBitsInvalid(t *testing.T) {
+	header, _, _ := splitBlob(t, mustCompress(t, []byte("abc")))
+	dec, err := NewDecoder(header)
+	if err != nil {
+		t.Fatalf("unexpected decoder error: %v", err)
+	}
+	for _, bits := range []int{0, MaxTableBits + 1} {
+		if err := dec.SetTableBits(bits); err == nil {
+			t.Errorf("expected error for %d table bits but got nil", bits)
+		}
+	}
+}
+
+func TestTableBitsForCapsDeepTrees(t *testing.T) {
+	// Equal weights over a deep tree push the average far down, so only the
+	// cap limits the width.
+	root := buildHuffmanTree(buildFrequencyTable(fibonacciData(26)))
+	if got := tableBitsFor(root, nil); got != maxAutoTableBits {
+		t.Errorf("expected width capped at %d, got %d", maxAutoTableBits, got)
+	}
+}
@@ -0,0 +1,80 @@
+package huffman
+
+import "fmt"
+
+// CompressOptions configures HuffmanCompressOpts. The zero value compresses
+// exactly like HuffmanCompressBytes.
+type CompressOptions struct {
+	// TieBreak orders nodes of equal frequency while building the tree, to
+	// reproduce the code lengths of a tool that breaks ties differently. Nil
+	// means TieBreakMinChar.
+	TieBreak TieBreaker
+	// Comment, if not empty, is attached to the blob with AddComment.
+	Comment string
+	// CompactHeader stores the frequency table and bit length as varints
+	// in a ModeCompact blob rather than at five and eight bytes, saving most
+	// of the header on small inputs. It has no effect with a TieBreak,
+	// whose ModeCanonical blob stores only code lengths.
+	CompactHeader bool
+}
+
+// HuffmanCompressOpts compresses data as configured by opts. Without a
+// TieBreak the result is the ModeHuffman blob of HuffmanCompressBytes, or
+// with CompactHeader the same codes in a ModeCompact blob. A
+// ModeHuffman decoder rebuilds the tree with the default tie-breaking, so
+// with a TieBreak the code lengths of the resulting tree are stored instead,
+// in a ModeCanonical blob that Decompress reads like any other.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressOpts(data []byte, opts CompressOptions) ([]byte, error) {
+	if opts.Comment != "" {
+		// Check the comment before spending time on the data.
+		if err := validateComment(opts.Comment); err != nil {
+			return nil, err
+		}
+		inner := opts
+		inner.Comment = ""
+		blob, err := HuffmanCompressOpts(data, inner)
+		if err != nil {
+			return nil, err
+		}
+		return AddComment(blob, opts.Comment)
+	}
+	if opts.TieBreak == nil {
+		if opts.CompactHeader {
+			return compressCompact(data)
+		}
+		return HuffmanCompressBytes(data)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	freq := buildFrequencyTable(data)
+	lengths, longest := tieBreakLengths(freq, opts.TieBreak)
+	if longest > MaxCodeLength {
+		return nil, fmt.Errorf("tree depth %d exceeds the %d-bit code length limit", longest, MaxCodeLength)
+	}
+	return writeCanonicalBlob(data, freq, lengths, longest)
+}
+
+// tieBreakLengths returns the code length of each symbol in the Huffman tree
+// of freq built with tieBreak, and the longest of them.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func tieBreakLengths(freq map[byte]int, tieBreak TieBreaker) ([]symbolLength, int) {
+	var counts [256]int
+	symbols := make([]byte, 0, len(freq))
+	for b, f := range freq {
+		symbols = append(symbols, b)
+		counts[b] = f
+	}
+	tb := treeBuilder{tieBreak: tieBreak}
+	depths := make(map[byte]int, len(freq))
+	codeLengths(tb.build(symbols, &counts), 0, depths)
+
+	lengths := make([]symbolLength, 0, len(depths))
+	longest := 0
+	for b, l := range depths {
+		longest = max(longest, l)
+		lengths = append(lengths, symbolLength{sym: b, length: uint8(l)})
+	}
+	return lengths, longest
+}
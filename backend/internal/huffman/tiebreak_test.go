@@ -0,0 +1,101 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// canonicalLengths returns the code length of each symbol in a ModeCanonical
+// blob.
+func canonicalLengths(t *testing.T, blob []byte) map[byte]int {
+	t.Helper()
+	mode, body, err := unwrap(blob)
+	if err != nil || mode != ModeCanonical {
+		t.Fatalf("expected a %s blob, got %s (%v)", ModeCanonical, mode, err)
+	}
+	root, _, _, err := readCanonicalBody(body)
+	if err != nil {
+		t.Fatalf("unexpected header error: %v", err)
+	}
+	lengths := make(map[byte]int)
+	codeLengths(root, 0, lengths)
+	return lengths
+}
+
+func TestHuffmanCompressOptsTieBreak(t *testing.T) {
+	// Equal frequencies leave every merge to the tie-breaker: by smallest
+	// symbol a and b are merged first, by largest b and c are.
+	data := []byte(strings.Repeat("abc", 20))
+	maxChar := func(a, b *Node) bool { return a.MinChar > b.MinChar }
+
+	tests := []struct {
+		name     string
+		tieBreak TieBreaker
+		want     map[byte]int
+	}{
+		{name: "MinChar", tieBreak: TieBreakMinChar, want: map[byte]int{'a': 2, 'b': 2, 'c': 1}},
+		{name: "MaxChar", tieBreak: maxChar, want: map[byte]int{'a': 1, 'b': 2, 'c': 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := HuffmanCompressOpts(data, CompressOptions{TieBreak: tt.tieBreak})
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			got := canonicalLengths(t, blob)
+			for b, l := range tt.want {
+				if got[b] != l {
+					t.Errorf("symbol %q: expected code length %d, got %d", b, l, got[b])
+				}
+			}
+			if err := HuffmanVerify(blob); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+			decompressed, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Error("decompressed output does not match original")
+			}
+		})
+	}
+}
+
+func TestHuffmanCompressOptsDefault(t *testing.T) {
+	data := []byte(strings.Repeat("hello world! ", 40))
+	want, err := HuffmanCompressBytes(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	got, err := HuffmanCompressOpts(data, CompressOptions{})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("expected the zero options to match HuffmanCompressBytes")
+	}
+
+	// The default tie-breaker stored as lengths gives the same codes.
+	explicit, err := HuffmanCompressOpts(data, CompressOptions{TieBreak: TieBreakMinChar})
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	lengths, err := CodeLengths(data)
+	if err != nil {
+		t.Fatalf("unexpected code length error: %v", err)
+	}
+	explicitLengths := canonicalLengths(t, explicit)
+	for b, l := range lengths {
+		if explicitLengths[b] != l {
+			t.Errorf("symbol %q: expected code length %d, got %d", b, l, explicitLengths[b])
+		}
+	}
+
+	if _, err := HuffmanCompressOpts(nil, CompressOptions{TieBreak: TieBreakMinChar}); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
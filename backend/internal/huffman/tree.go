@@ -0,0 +1,55 @@
+package huffman
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonNode is the JSON form of a Node. Leaves carry their symbol as a
+// "0x%02x" string; internal nodes omit it.
+type jsonNode struct {
+	Char  string    `json:"char,omitempty"`
+	Freq  int       `json:"freq"`
+	Left  *jsonNode `json:"left,omitempty"`
+	Right *jsonNode `json:"right,omitempty"`
+}
+
+type jsonTree struct {
+	Tree  *jsonNode         `json:"tree"`
+	Codes map[string]string `json:"codes"`
+}
+
+func symbolKey(b byte) string {
+	return fmt.Sprintf("0x%02x", b)
+}
+
+// toJSONNode converts the subtree rooted at n.
+// Time Complexity: O(m), Space Complexity: O(m)
+func toJSONNode(n *Node) *jsonNode {
+	if n == nil {
+		return nil
+	}
+	if n.Left == nil && n.Right == nil {
+		return &jsonNode{Char: symbolKey(n.Char), Freq: n.Freq}
+	}
+	return &jsonNode{Freq: n.Freq, Left: toJSONNode(n.Left), Right: toJSONNode(n.Right)}
+}
+
+// TreeJSON returns the Huffman tree built for data together with each
+// symbol's code, for visualization. A single-symbol input yields a tree that
+// is just one leaf with the code "0".
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func TreeJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	root := buildHuffmanTree(buildFrequencyTable(data))
+	codeMap := make(map[byte]string)
+	generateCodes(root, "", codeMap)
+
+	codes := make(map[string]string, len(codeMap))
+	for b, c := range codeMap {
+		codes[symbolKey(b)] = c
+	}
+	return json.Marshal(jsonTree{Tree: toJSONNode(root), Codes: codes})
+}
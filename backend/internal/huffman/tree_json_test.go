@@ -0,0 +1,62 @@
+package huffman
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTreeJSON(t *testing.T) {
+	// Frequencies a:3 b:1 c:1. b and c merge first (tie broken by symbol),
+	// then the pair (freq 2) is merged with a (freq 3).
+	out, err := TreeJSON([]byte("aaabc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got jsonTree
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	want := jsonTree{
+		Tree: &jsonNode{
+			Freq: 5,
+			Left: &jsonNode{
+				Freq:  2,
+				Left:  &jsonNode{Char: "0x62", Freq: 1},
+				Right: &jsonNode{Char: "0x63", Freq: 1},
+			},
+			Right: &jsonNode{Char: "0x61", Freq: 3},
+		},
+		Codes: map[string]string{"0x61": "1", "0x62": "00", "0x63": "01"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected tree JSON: %s", out)
+	}
+}
+
+func TestTreeJSONSingleSymbol(t *testing.T) {
+	out, err := TreeJSON([]byte("zzzz"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got jsonTree
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	want := jsonTree{
+		Tree:  &jsonNode{Char: "0x7a", Freq: 4},
+		Codes: map[string]string{"0x7a": "0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected tree JSON: %s", out)
+	}
+}
+
+func TestTreeJSONEmpty(t *testing.T) {
+	if _, err := TreeJSON(nil); err == nil {
+		t.Error("expected error for empty input but got nil")
+	}
+}
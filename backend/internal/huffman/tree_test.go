@@ -0,0 +1,131 @@
+package huffman
+
+import (
+	"container/heap"
+	"math/rand"
+	"testing"
+)
+
+// referenceQueue is the container/heap priority queue buildHuffmanTree used
+// before the two-queue construction; it is kept as an oracle.
+type referenceQueue []*Node
+
+func (pq referenceQueue) Len() int           { return len(pq) }
+func (pq referenceQueue) Less(i, j int) bool { return nodeLess(pq[i], pq[j]) }
+func (pq referenceQueue) Swap(i, j int)      { pq[i], pq[j] = pq[j], pq[i] }
+func (pq *referenceQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(*Node))
+}
+func (pq *referenceQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	*pq = old[:n-1]
+	return item
+}
+
+func buildReferenceTree(freq map[byte]int) *Node {
+	if len(freq) == 0 {
+		return nil
+	}
+	pq := &referenceQueue{}
+	for b, f := range freq {
+		heap.Push(pq, &Node{Char: b, Freq: f, MinChar: b})
+	}
+	for pq.Len() > 1 {
+		left := heap.Pop(pq).(*Node)
+		right := heap.Pop(pq).(*Node)
+		heap.Push(pq, &Node{
+			Freq:    left.Freq + right.Freq,
+			MinChar: min(left.MinChar, right.MinChar),
+			Left:    left,
+			Right:   right,
+		})
+	}
+	return heap.Pop(pq).(*Node)
+}
+
+func randomFrequencyTable(rng *rand.Rand) map[byte]int {
+	size := 1 + rng.Intn(256)
+	// Small frequency ranges force many ties between leaves and merged nodes.
+	maxFreq := 1 + rng.Intn(1<<uint(rng.Intn(16)))
+	freq := make(map[byte]int, size)
+	for _, b := range rng.Perm(256)[:size] {
+		freq[byte(b)] = 1 + rng.Intn(maxFreq)
+	}
+	return freq
+}
+
+func TestBuildHuffmanTreeMatchesReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 2000; i++ {
+		freq := randomFrequencyTable(rng)
+
+		got := make(map[byte]int)
+		codeLengths(buildHuffmanTree(freq), 0, got)
+		want := make(map[byte]int)
+		codeLengths(buildReferenceTree(freq), 0, want)
+
+		if len(got) != len(want) {
+			t.Fatalf("table %v: expected %d leaves, got %d", freq, len(want), len(got))
+		}
+		for b, l := range want {
+			if got[b] != l {
+				t.Fatalf("table %v: symbol 0x%02x has length %d, want %d", freq, b, got[b], l)
+			}
+		}
+	}
+}
+
+func BenchmarkBuildHuffmanTree(b *testing.B) {
+	rng := rand.New(rand.NewSource(4))
+	freq := make(map[byte]int, 256)
+	for i := 0; i < 256; i++ {
+		freq[byte(i)] = 1 + rng.Intn(1<<16)
+	}
+
+	b.Run("TwoQueue", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buildHuffmanTree(freq)
+		}
+	})
+	b.Run("Heap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buildReferenceTree(freq)
+		}
+	})
+}
+
+func TestBuildHuffmanTreeInsertionOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 200; i++ {
+		base := randomFrequencyTable(rng)
+		symbols := make([]byte, 0, len(base))
+		for b := range base {
+			symbols = append(symbols, b)
+		}
+
+		var want map[byte]string
+		for trial := 0; trial < 5; trial++ {
+			rng.Shuffle(len(symbols), func(i, j int) { symbols[i], symbols[j] = symbols[j], symbols[i] })
+			freq := make(map[byte]int, len(symbols))
+			for _, b := range symbols {
+				freq[b] = base[b]
+			}
+
+			codes := make(map[byte]string)
+			generateCodes(buildHuffmanTree(freq), "", codes)
+			if want == nil {
+				want = codes
+				continue
+			}
+			for b, code := range want {
+				if codes[b] != code {
+					t.Fatalf("table %v: symbol 0x%02x got code %q, want %q", base, b, codes[b], code)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ValidateRoundTrip compresses data with HuffmanCompressBytes, decompresses
+// the blob and checks the output equals data. ModeHuffman output depends on
+// nothing but the input, so it also compresses the decompressed output again
+// and checks the second blob is byte-identical to the first; a difference
+// means the format is not stable across a decompress and recompress. Either
+// failure wraps ErrRoundTrip.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func ValidateRoundTrip(data []byte) error {
+	return validateRoundTrip(data, HuffmanCompressBytes, Decompress)
+}
+
+// validateRoundTrip is ValidateRoundTrip with compress and decompress in
+// place of HuffmanCompressBytes and Decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func validateRoundTrip(data []byte, compress, decompress func([]byte) ([]byte, error)) error {
+	blob, err := compress(data)
+	if err != nil {
+		return err
+	}
+	out, err := decompress(blob)
+	if err != nil {
+		return fmt.Errorf("decompress failed: %w", err)
+	}
+	if i := firstDifference(out, data); i >= 0 {
+		return fmt.Errorf("%w: decompressed %d bytes differ from %d input bytes at offset %d", ErrRoundTrip, len(out), len(data), i)
+	}
+	again, err := compress(out)
+	if err != nil {
+		return fmt.Errorf("recompress failed: %w", err)
+	}
+	if i := firstDifference(again, blob); i >= 0 {
+		return fmt.Errorf("%w: recompressed blob of %d bytes differs from the %d byte original at offset %d", ErrRoundTrip, len(again), len(blob), i)
+	}
+	return nil
+}
+
+// firstDifference returns the offset of the first byte where a and b differ,
+// the shorter length if one is a prefix of the other, or -1 if they are
+// equal.
+// Time Complexity: O(n), Space Complexity: O(1)
+func firstDifference(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
@@ -0,0 +1,117 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidateRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(363))
+	random := make([]byte, 64<<10)
+	rng.Read(random)
+	allBytes := make([]byte, 256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Single byte", content: []byte("a")},
+		{name: "Single symbol", content: bytes.Repeat([]byte{0}, 1000)},
+		{name: "Skewed", content: []byte("aaaaabbbbcccdde")},
+		{name: "Text", content: []byte(strings.Repeat("hello world! ", 500))},
+		{name: "Binary", content: []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}},
+		{name: "All bytes", content: allBytes},
+		{name: "Format fixture", content: []byte("aaabc")},
+		{name: "Random", content: random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateRoundTrip(tt.content); err != nil {
+				t.Errorf("unexpected validate error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateRoundTripEmpty(t *testing.T) {
+	if err := ValidateRoundTrip(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
+
+func TestValidateRoundTripMismatch(t *testing.T) {
+	content := []byte("hello world! hello world!")
+	calls := 0
+	tests := []struct {
+		name       string
+		compress   func([]byte) ([]byte, error)
+		decompress func([]byte) ([]byte, error)
+	}{
+		{
+			name:     "Decoded output differs",
+			compress: HuffmanCompressBytes,
+			decompress: func(blob []byte) ([]byte, error) {
+				out, err := Decompress(blob)
+				if err == nil {
+					out[len(out)-1] ^= 1
+				}
+				return out, err
+			},
+		},
+		{
+			name:     "Decoded output short",
+			compress: HuffmanCompressBytes,
+			decompress: func(blob []byte) ([]byte, error) {
+				out, err := Decompress(blob)
+				return out[:len(out)-1], err
+			},
+		},
+		{
+			// A compressor whose output changes between calls is not stable
+			// across a recompress.
+			name: "Unstable blob",
+			compress: func(data []byte) ([]byte, error) {
+				calls++
+				return HuffmanCompressOpts(data, CompressOptions{Comment: strings.Repeat("x", calls)})
+			},
+			decompress: Decompress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRoundTrip(content, tt.compress, tt.decompress); !errors.Is(err, ErrRoundTrip) {
+				t.Errorf("expected ErrRoundTrip, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{name: "Equal", a: "abc", b: "abc", want: -1},
+		{name: "Both empty", a: "", b: "", want: -1},
+		{name: "Differ", a: "abc", b: "abd", want: 2},
+		{name: "Prefix", a: "ab", b: "abc", want: 2},
+		{name: "Longer", a: "abc", b: "a", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstDifference([]byte(tt.a), []byte(tt.b)); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
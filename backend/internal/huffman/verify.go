@@ -0,0 +1,220 @@
+package huffman
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// HuffmanVerify checks that blob would decompress cleanly without keeping the
+// output. It validates the container and header, walks the whole payload,
+// and checks that every code is complete and, where the header records
+// frequencies, that the decoded symbol counts match them. The format carries
+// no checksum, so payload corruption that still decodes to the recorded
+// counts goes unnoticed. Archive members and blocks must be ModeStore or
+// ModeHuffman blobs, as they are written, so verification never recurses
+// more than one level.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func HuffmanVerify(blob []byte) error {
+	mode, body, err := unwrap(blob)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case ModeStore:
+		return nil
+	case ModeHuffman:
+		return verifyHuffmanBody(body, nil)
+	case ModeRLE:
+		return verifyHuffmanBody(body, newRLEChecker())
+	case ModeDelta:
+		return verifyHuffmanBody(body, nil)
+	case ModeWords:
+		return verifyWordsBody(body)
+	case ModeFlate:
+		r := flate.NewReader(bytes.NewReader(body))
+		defer r.Close()
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return corruptf("flate decode failed: %w", err)
+		}
+		return nil
+	case ModeCanonical:
+		root, totalBits, bitData, err := readCanonicalBody(body)
+		if err != nil {
+			return err
+		}
+		if err := checkPayloadLength(bitData, totalBits); err != nil {
+			return err
+		}
+		complete, err := walkBits(root, bitData, totalBits, func(byte) error { return nil })
+		if err != nil {
+			return err
+		}
+		if !complete {
+			return corruptf("encoded data ends inside a code")
+		}
+		return nil
+	case ModeModel:
+		return fmt.Errorf("%s blob carries no frequency table to verify against", mode)
+	case ModeArchive:
+		entries, members, err := readArchiveIndex(body)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			member := members[e.offset : e.offset+e.length]
+			if err := checkLeafBlob(member); err != nil {
+				return fmt.Errorf("archive member %q: %w", e.name, err)
+			}
+			if err := HuffmanVerify(member); err != nil {
+				return fmt.Errorf("archive member %q: %w", e.name, err)
+			}
+		}
+		return nil
+	case ModeBlocks:
+		entries, blocks, _, err := readBlockIndex(body)
+		if err != nil {
+			return err
+		}
+		for i, e := range entries {
+			block := blocks[e.offset : e.offset+e.length]
+			if err := checkLeafBlob(block); err != nil {
+				return fmt.Errorf("block %d: %w", i, err)
+			}
+			if err := HuffmanVerify(block); err != nil {
+				return fmt.Errorf("block %d: %w", i, err)
+			}
+		}
+		return nil
+	case ModeComment:
+		_, inner, err := splitComment(body)
+		if err != nil {
+			return err
+		}
+		return HuffmanVerify(inner)
+	case ModeNamed:
+		_, inner, err := splitName(body)
+		if err != nil {
+			return err
+		}
+		return HuffmanVerify(inner)
+	case ModeEscape:
+		return verifyEscapeBody(body)
+	case ModeASCII:
+		freq, root, totalBits, bitData, err := readASCIIBody(body)
+		if err != nil {
+			return err
+		}
+		return verifyHuffmanPayload(freq, root, totalBits, bitData, nil)
+	case ModeCompact:
+		freq, root, totalBits, bitData, err := readCompactBody(body)
+		if err != nil {
+			return err
+		}
+		return verifyHuffmanPayload(freq, root, totalBits, bitData, nil)
+	default:
+		return corruptf("unknown mode %d", byte(mode))
+	}
+}
+
+// verifyHuffmanBody walks a ModeHuffman body, checking the decoded symbol
+// counts against the header. If check is non-nil it also sees every symbol.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func verifyHuffmanBody(body []byte, check *rleChecker) error {
+	freq, root, totalBits, bitData, err := readHuffmanBody(body)
+	if err != nil {
+		return err
+	}
+	return verifyHuffmanPayload(freq, root, totalBits, bitData, check)
+}
+
+// verifyHuffmanPayload is verifyHuffmanBody for a payload whose frequency
+// table has already been read.
+// Time Complexity: O(n), Space Complexity: O(1)
+func verifyHuffmanPayload(freq map[byte]int, root *Node, totalBits uint64, bitData []byte, check *rleChecker) error {
+	if err := checkPayloadLength(bitData, totalBits); err != nil {
+		return err
+	}
+	var counts [256]int
+	complete, err := walkBits(root, bitData, totalBits, func(b byte) error {
+		counts[b]++
+		if check != nil {
+			return check.next(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !complete {
+		return corruptf("encoded data ends inside a code")
+	}
+	for b, n := range counts {
+		if n != freq[byte(b)] {
+			return corruptf("symbol 0x%02x decoded %d times, header says %d", b, n, freq[byte(b)])
+		}
+	}
+	if check != nil {
+		return check.done()
+	}
+	return nil
+}
+
+// verifyWordsBody walks a ModeWords body, checking the decoded symbol counts
+// against the header.
+// Time Complexity: O(n + m log m), Space Complexity: O(m)
+func verifyWordsBody(body []byte) error {
+	wb, err := readWordsBody(body)
+	if err != nil {
+		return err
+	}
+	if err := checkPayloadLength(wb.payload, wb.totalBits); err != nil {
+		return err
+	}
+	if wb.root == nil {
+		return nil
+	}
+	counts := make(map[uint16]int, len(wb.freq))
+	complete, err := walkWords(wb.root, wb.payload, wb.totalBits, func(s uint16) error {
+		counts[s]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !complete {
+		return corruptf("encoded data ends inside a code")
+	}
+	for s, f := range wb.freq {
+		if counts[s] != f {
+			return corruptf("symbol 0x%04x decoded %d times, header says %d", s, counts[s], f)
+		}
+	}
+	return nil
+}
+
+// rleChecker validates a (byte, count) token stream one token at a time,
+// mirroring the checks in rleDecode.
+type rleChecker struct {
+	tokens int
+}
+
+func newRLEChecker() *rleChecker {
+	return &rleChecker{}
+}
+
+func (c *rleChecker) next(b byte) error {
+	if c.tokens%2 == 1 && b == 0 {
+		return corruptf("invalid RLE stream: zero-length run at token %d", c.tokens/2)
+	}
+	c.tokens++
+	return nil
+}
+
+func (c *rleChecker) done() error {
+	if c.tokens%2 != 0 {
+		return corruptf("invalid RLE stream: odd token count %d", c.tokens)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHuffmanVerify(t *testing.T) {
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50))
+	runs := bytes.Repeat([]byte("a"), 5000)
+
+	compressors := []struct {
+		name     string
+		compress func([]byte) ([]byte, error)
+		content  []byte
+	}{
+		{name: "Store", compress: CompressStore, content: text},
+		{name: "Huffman", compress: HuffmanCompressBytes, content: text},
+		{name: "Single symbol", compress: HuffmanCompressBytes, content: runs},
+		{name: "Words", compress: func(data []byte) ([]byte, error) { return HuffmanCompressWords(data, 2) }, content: append(bytes.Clone(text), '!')},
+		{name: "RLE", compress: HuffmanCompressRLE, content: runs},
+		{name: "Flate", compress: CompressBest, content: text},
+		{name: "Canonical", compress: func(data []byte) ([]byte, error) { return HuffmanCompressLimited(data, 10) }, content: text},
+	}
+
+	for _, tt := range compressors {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := tt.compress(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if err := HuffmanVerify(blob); err != nil {
+				t.Errorf("unexpected verify error: %v", err)
+			}
+			if tt.name != "Store" {
+				if err := HuffmanVerify(blob[:len(blob)-1]); !errors.Is(err, ErrCorruptStream) {
+					t.Errorf("expected truncated blob to fail with ErrCorruptStream, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestHuffmanVerifyCorrupt(t *testing.T) {
+	flipped := bytes.Clone(formatFixture)
+	flipped[len(flipped)-1] ^= 0x80 // 0110001 decodes as "cabc"
+
+	short := bytes.Clone(formatFixture)
+	short[len(short)-9] = 6 // 111000 stops inside the code for c
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		wantErr string
+	}{
+		{name: "Flipped payload bit", blob: flipped, wantErr: "decoded 1 times, header says 3"},
+		{name: "Partial final code", blob: short, wantErr: "ends inside a code"},
+		{name: "Bad magic", blob: append([]byte("JUNK"), formatFixture[len(magic):]...), wantErr: "bad magic"},
+		{name: "Zero-length run", blob: retag(mustCompress(t, []byte{'a', 0}), ModeRLE), wantErr: "zero-length run"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := HuffmanVerify(tt.blob)
+			if err == nil {
+				t.Fatal("expected verify error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHuffmanVerifyNested(t *testing.T) {
+	// nestArchive wraps inner as the only member of a ModeArchive blob.
+	nestArchive := func(inner []byte) []byte {
+		entries := []archiveEntry{{name: "inner", length: uint64(len(inner))}}
+		return wrap(ModeArchive, appendArchiveIndex(bytes.Clone(inner), entries))
+	}
+	leaf := mustCompress(t, []byte("hello world!"))
+	blocks, err := HuffmanCompressBlocks([]byte("hello world!"), 0)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{name: "Archive in an archive", blob: nestArchive(nestArchive(leaf))},
+		{name: "Blocks in an archive", blob: nestArchive(blocks)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := HuffmanVerify(tt.blob); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("HuffmanVerify: expected ErrCorruptStream, got %v", err)
+			}
+			if _, err := HuffmanArchiveExtract(tt.blob); !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("HuffmanArchiveExtract: expected ErrCorruptStream, got %v", err)
+			}
+		})
+	}
+	if err := HuffmanVerify(nestArchive(leaf)); err != nil {
+		t.Errorf("unexpected verify error for a Huffman member: %v", err)
+	}
+}
+
+func mustCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	blob, err := HuffmanCompressBytes(data)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	return blob
+}
@@ -0,0 +1,24 @@
+//go:build js && wasm
+
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCoreRoundTripWasm(t *testing.T) {
+	content := []byte("hello world! hello world! hello world! hello world!")
+
+	compressed, err := HuffmanCompressBytes(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	decompressed, err := HuffmanDecompress(compressed)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(decompressed, content) {
+		t.Errorf("decompressed output does not match original.\nGot: %v\nWant: %v", decompressed, content)
+	}
+}
@@ -0,0 +1,341 @@
+package huffman
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// wordNode is a Huffman tree node over symbols up to 16 bits wide.
+type wordNode struct {
+	Sym    uint16
+	Freq   int
+	MinSym uint16
+	Left   *wordNode
+	Right  *wordNode
+}
+
+type wordQueue []*wordNode
+
+func (pq wordQueue) Len() int { return len(pq) }
+func (pq wordQueue) Less(i, j int) bool {
+	if pq[i].Freq != pq[j].Freq {
+		return pq[i].Freq < pq[j].Freq
+	}
+	return pq[i].MinSym < pq[j].MinSym
+}
+func (pq wordQueue) Swap(i, j int) { pq[i], pq[j] = pq[j], pq[i] }
+func (pq *wordQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(*wordNode))
+}
+func (pq *wordQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	*pq = old[:n-1]
+	return item
+}
+
+// splitWords splits data into little-endian symbols of wordSize bytes,
+// returning any trailing bytes that do not fill a whole word.
+// Time Complexity: O(n), Space Complexity: O(n)
+func splitWords(data []byte, wordSize int) ([]uint16, []byte) {
+	n := len(data) / wordSize
+	words := make([]uint16, n)
+	for i := 0; i < n; i++ {
+		if wordSize == 1 {
+			words[i] = uint16(data[i])
+		} else {
+			words[i] = byteOrder.Uint16(data[i*2:])
+		}
+	}
+	return words, data[n*wordSize:]
+}
+
+// buildWordTree builds a Huffman tree from a word frequency table
+// deterministically, breaking ties by the smallest symbol in each subtree.
+// Time Complexity: O(m log m), Space Complexity: O(m) where m is unique word count (<= 65536)
+func buildWordTree(freq map[uint16]int) *wordNode {
+	if len(freq) == 0 {
+		return nil
+	}
+	pq := &wordQueue{}
+	heap.Init(pq)
+	for s, f := range freq {
+		heap.Push(pq, &wordNode{Sym: s, Freq: f, MinSym: s})
+	}
+	for pq.Len() > 1 {
+		left := heap.Pop(pq).(*wordNode)
+		right := heap.Pop(pq).(*wordNode)
+		heap.Push(pq, &wordNode{
+			Freq:   left.Freq + right.Freq,
+			MinSym: min(left.MinSym, right.MinSym),
+			Left:   left,
+			Right:  right,
+		})
+	}
+	return heap.Pop(pq).(*wordNode)
+}
+
+// generateWordCodes populates codeMap with packed codes for each leaf.
+// A tree consisting of a single leaf gets the one-bit code 0.
+// Time Complexity: O(m), Space Complexity: O(m)
+func generateWordCodes(root *wordNode, bits uint64, depth uint8, codeMap map[uint16]code) {
+	if root == nil {
+		return
+	}
+	if root.Left == nil && root.Right == nil {
+		codeMap[root.Sym] = code{bits: bits, length: max(depth, 1)}
+		return
+	}
+	generateWordCodes(root.Left, bits<<1, depth+1, codeMap)
+	generateWordCodes(root.Right, bits<<1|1, depth+1, codeMap)
+}
+
+// writeWordSymbol writes s using wordSize little-endian bytes.
+func writeWordSymbol(buf *bytes.Buffer, s uint16, wordSize int) error {
+	if wordSize == 1 {
+		return buf.WriteByte(byte(s))
+	}
+	return binary.Write(buf, byteOrder, s)
+}
+
+// appendWord appends s to out using wordSize little-endian bytes.
+func appendWord(out []byte, s uint16, wordSize int) []byte {
+	if wordSize == 1 {
+		return append(out, byte(s))
+	}
+	return byteOrder.AppendUint16(out, s)
+}
+
+// writeWordTable writes the entry count and the (symbol, u32 frequency)
+// entries of freq to buf, in ascending symbol order so the same input always
+// gives the same blob. A frequency too large for its u32 is an error, as it
+// would wrap and leave a blob that does not decode.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func writeWordTable(buf *bytes.Buffer, freq map[uint16]int, wordSize int) error {
+	symbols := make([]uint16, 0, len(freq))
+	for s, f := range freq {
+		if uint64(f) > math.MaxUint32 {
+			return fmt.Errorf("invalid frequency table: symbol 0x%04x frequency %d overflows 32 bits", s, f)
+		}
+		symbols = append(symbols, s)
+	}
+	slices.Sort(symbols)
+	if err := binary.Write(buf, byteOrder, uint32(len(freq))); err != nil {
+		return err
+	}
+	for _, s := range symbols {
+		if err := writeWordSymbol(buf, s, wordSize); err != nil {
+			return err
+		}
+		if err := binary.Write(buf, byteOrder, uint32(freq[s])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HuffmanCompressWords builds a ModeWords blob over symbols of wordSize
+// bytes (1 or 2). The body header records the width, the trailing bytes
+// that do not fill a whole word, and a frequency table of up to 65536
+// symbols.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func HuffmanCompressWords(data []byte, wordSize int) ([]byte, error) {
+	if wordSize != 1 && wordSize != 2 {
+		return nil, fmt.Errorf("unsupported word size %d", wordSize)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	words, tail := splitWords(data, wordSize)
+	freq := make(map[uint16]int)
+	for _, w := range words {
+		freq[w]++
+	}
+	root := buildWordTree(freq)
+	codeMap := make(map[uint16]code)
+	generateWordCodes(root, 0, 0, codeMap)
+
+	var out bytes.Buffer
+	writeContainerHeader(&out, ModeWords)
+	out.WriteByte(byte(wordSize))
+	out.WriteByte(byte(len(tail)))
+	out.Write(tail)
+	if err := writeWordTable(&out, freq, wordSize); err != nil {
+		return nil, err
+	}
+
+	var payload bytes.Buffer
+	bw := newBitWriter(&payload)
+	for _, w := range words {
+		if err := bw.writeCode(codeMap[w]); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&out, byteOrder, uint64(bw.Written())); err != nil {
+		return nil, err
+	}
+	out.Write(payload.Bytes())
+	return out.Bytes(), nil
+}
+
+// wordsBody is a parsed ModeWords body.
+type wordsBody struct {
+	wordSize  int
+	tail      []byte
+	freq      map[uint16]int
+	root      *wordNode // nil when the input was shorter than one word
+	totalBits uint64
+	payload   []byte
+}
+
+// readWordsBody parses the header and bit length of a ModeWords body.
+// Time Complexity: O(m log m), Space Complexity: O(m)
+func readWordsBody(body []byte) (wordsBody, error) {
+	r := bytes.NewReader(body)
+	width, err := r.ReadByte()
+	if err != nil {
+		return wordsBody{}, corruptf("read word size failed: %w", err)
+	}
+	wordSize := int(width)
+	if wordSize != 1 && wordSize != 2 {
+		return wordsBody{}, corruptf("unsupported word size %d", wordSize)
+	}
+	tailLen, err := r.ReadByte()
+	if err != nil {
+		return wordsBody{}, corruptf("read tail length failed: %w", err)
+	}
+	if int(tailLen) >= wordSize {
+		return wordsBody{}, corruptf("invalid header: tail of %d bytes for word size %d", tailLen, wordSize)
+	}
+	tail := make([]byte, tailLen)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return wordsBody{}, corruptf("read tail failed: %w", err)
+	}
+	var numEntries uint32
+	if err := binary.Read(r, byteOrder, &numEntries); err != nil {
+		return wordsBody{}, corruptf("read header entries failed: %w", err)
+	}
+	if numEntries > 1<<(8*wordSize) {
+		return wordsBody{}, corruptf("invalid header: %d entries exceeds %d symbols", numEntries, 1<<(8*wordSize))
+	}
+	freq := make(map[uint16]int)
+	for i := 0; i < int(numEntries); i++ {
+		var s uint16
+		if wordSize == 1 {
+			b, err := r.ReadByte()
+			if err != nil {
+				return wordsBody{}, corruptf("read header symbol failed: %w", err)
+			}
+			s = uint16(b)
+		} else if err := binary.Read(r, byteOrder, &s); err != nil {
+			return wordsBody{}, corruptf("read header symbol failed: %w", err)
+		}
+		if _, dup := freq[s]; dup {
+			return wordsBody{}, corruptf("invalid header: duplicate symbol 0x%04x", s)
+		}
+		var count uint32
+		if err := binary.Read(r, byteOrder, &count); err != nil {
+			return wordsBody{}, corruptf("read header freq failed: %w", err)
+		}
+		freq[s] = int(count)
+	}
+	var totalBits uint64
+	if err := binary.Read(r, byteOrder, &totalBits); err != nil {
+		return wordsBody{}, corruptf("read bit length failed: %w", err)
+	}
+	root := buildWordTree(freq)
+	if root == nil && totalBits > 0 {
+		return wordsBody{}, corruptf("invalid tree")
+	}
+	return wordsBody{
+		wordSize:  wordSize,
+		tail:      tail,
+		freq:      freq,
+		root:      root,
+		totalBits: totalBits,
+		payload:   body[len(body)-r.Len():],
+	}, nil
+}
+
+// decodeWordsBody reverses HuffmanCompressWords for a ModeWords body,
+// reading the word size from the header and rejecting blobs whose header
+// frequencies add up to more than maxSize bytes. strict is as for decompress.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func decodeWordsBody(body []byte, maxSize int, strict bool) ([]byte, error) {
+	wb, err := readWordsBody(body)
+	if err != nil {
+		return nil, err
+	}
+	size := len(wb.tail)
+	for _, f := range wb.freq {
+		size += f * wb.wordSize
+	}
+	if size > maxSize {
+		return nil, sizeLimitError(maxSize)
+	}
+	if err := checkPayload(wb.payload, wb.totalBits, strict); err != nil {
+		return nil, err
+	}
+	if wb.root == nil {
+		return wb.tail, nil
+	}
+	var out []byte
+	complete, err := walkWords(wb.root, wb.payload, wb.totalBits, func(s uint16) error {
+		if len(out)+wb.wordSize+len(wb.tail) > maxSize {
+			return sizeLimitError(maxSize)
+		}
+		out = appendWord(out, s, wb.wordSize)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if strict && !complete {
+		return nil, corruptf("trailing bits do not form a complete code")
+	}
+	return append(out, wb.tail...), nil
+}
+
+// walkWords walks root for each of the first totalBits bits of bitData,
+// passing every decoded symbol to emit and stopping at the first error it
+// returns. It reports whether the walk ended on a symbol boundary.
+// Time Complexity: O(n), Space Complexity: O(1)
+func walkWords(root *wordNode, bitData []byte, totalBits uint64, emit func(s uint16) error) (bool, error) {
+	br := newBitReader(bitData, totalBits)
+	node := root
+	for {
+		bit, err := br.ReadBit()
+		if err == io.EOF {
+			return node == root, nil
+		}
+		if err != nil {
+			return false, truncatedError(br.BitsRead(), totalBits)
+		}
+		if root.Left == nil && root.Right == nil {
+			if err := emit(root.Sym); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if bit == 0 {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+		if node.Left == nil && node.Right == nil {
+			if err := emit(node.Sym); err != nil {
+				return false, err
+			}
+			node = root
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestHuffmanCompressWords(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	wide := make([]byte, 1<<12)
+	for i := 0; i < len(wide); i += 2 {
+		// Draw from a few hundred 16-bit symbols so the alphabet exceeds a byte.
+		sym := uint16(rng.Intn(600)) * 97
+		wide[i] = byte(sym)
+		wide[i+1] = byte(sym >> 8)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Simple ASCII", content: []byte("aaaaabbbbcccdde")},
+		{name: "Even length text", content: []byte("the cat sat on the mat, the cat sat on the mat")},
+		{name: "Binary data", content: []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}},
+		{name: "Single symbol", content: []byte("zzzzzzzz")},
+		{name: "Single byte", content: []byte("q")},
+		{name: "Wide alphabet", content: wide},
+	}
+
+	for _, wordSize := range []int{1, 2} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/width=%d", tt.name, wordSize), func(t *testing.T) {
+				compressed, err := HuffmanCompressWords(tt.content, wordSize)
+				if err != nil {
+					t.Fatalf("word size %d: unexpected compress error: %v", wordSize, err)
+				}
+				if width := compressed[containerHeaderSize]; width != byte(wordSize) {
+					t.Errorf("word size %d: header records width %d", wordSize, width)
+				}
+				// Map iteration order varies between runs, so repeat to catch
+				// a header that depends on it.
+				for i := 0; i < 5; i++ {
+					if again, err := HuffmanCompressWords(tt.content, wordSize); err != nil || !bytes.Equal(again, compressed) {
+						t.Fatalf("word size %d: compressing again gave a different blob (err %v)", wordSize, err)
+					}
+				}
+
+				decompressed, err := Decompress(compressed)
+				if err != nil {
+					t.Fatalf("word size %d: unexpected decompress error: %v", wordSize, err)
+				}
+				if !bytes.Equal(decompressed, tt.content) {
+					t.Errorf("word size %d: decompressed output does not match original.\nGot: %v\nWant: %v", wordSize, decompressed, tt.content)
+				}
+			})
+		}
+	}
+}
+
+func TestHuffmanCompressWordsInvalid(t *testing.T) {
+	if _, err := HuffmanCompressWords([]byte("abc"), 3); err == nil {
+		t.Error("expected error for unsupported word size but got nil")
+	}
+	if _, err := HuffmanCompressWords(nil, 2); err == nil {
+		t.Error("expected error for empty input but got nil")
+	}
+	if _, err := Decompress(wrap(ModeWords, []byte{4, 0})); err == nil {
+		t.Error("expected error for unsupported header width but got nil")
+	}
+}
+
+func TestWriteWordTableWidth(t *testing.T) {
+	wide := uint64(math.MaxUint32)
+	var buf bytes.Buffer
+	if err := writeWordTable(&buf, map[uint16]int{0x0102: int(wide)}, 2); err != nil {
+		t.Errorf("unexpected error for the largest frequency: %v", err)
+	}
+	buf.Reset()
+	if err := writeWordTable(&buf, map[uint16]int{0x0102: 1, 0x0304: int(wide + 1)}, 2); err == nil {
+		t.Error("expected error for a frequency past 32 bits but got nil")
+	}
+}
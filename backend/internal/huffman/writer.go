@@ -0,0 +1,181 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Writer is an io.WriteCloser that compresses everything written to it into
+// one ModeHuffman blob. Huffman coding needs the full frequency table before
+// the first code can be emitted, so the input is buffered and the blob is
+// written to the underlying writer on Close.
+type Writer struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	scratch *encodeScratch
+	closed  bool
+}
+
+// NewWriter returns a Writer that writes its blob to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, scratch: newEncodeScratch()}
+}
+
+// Write buffers p for compression on Close.
+// Time Complexity: O(len(p)), Space Complexity: O(len(p))
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errors.New("write to closed Writer")
+	}
+	return z.buf.Write(p)
+}
+
+// Close compresses the buffered input and writes the blob to the underlying
+// writer. It does not close the underlying writer. Like HuffmanCompressBytes
+// it rejects an empty stream.
+// Time Complexity: O(n + m log m), Space Complexity: O(n + m)
+func (z *Writer) Close() error {
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+	defer z.scratch.reset()
+	blob, err := z.scratch.compress(nil, z.buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	if _, err := z.w.Write(blob); err != nil {
+		return fmt.Errorf("write output failed: %w", err)
+	}
+	return nil
+}
+
+// Reset discards any buffered input and makes z write its next blob to w,
+// keeping the input buffer and encoder state allocated for reuse.
+func (z *Writer) Reset(w io.Writer) {
+	z.w = w
+	z.buf.Reset()
+	z.scratch.reset()
+	z.closed = false
+}
+
+// BlockWriter is an io.WriteCloser that compresses everything written to it
+// into one ModeBlocks blob, as HuffmanCompressBlocks does. Unlike Writer it
+// does not hold the whole input: each block is coded and written to the
+// underlying writer as soon as it fills, and the index follows on Close.
+type BlockWriter struct {
+	w         io.Writer
+	blockSize int
+	buf       []byte
+	entries   []blockEntry
+	written   uint64
+	started   bool
+	closed    bool
+	err       error
+}
+
+// NewBlockWriter returns a BlockWriter that writes its blob to w in blocks
+// of blockSize bytes, or DefaultBlockSize if blockSize is zero.
+func NewBlockWriter(w io.Writer, blockSize int) (*BlockWriter, error) {
+	if blockSize < 0 {
+		return nil, fmt.Errorf("invalid block size %d", blockSize)
+	}
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+	return &BlockWriter{w: w, blockSize: blockSize}, nil
+}
+
+// Write buffers p, coding and writing out each block it completes.
+// Time Complexity: O(len(p) + k·m log m) for k completed blocks,
+// Space Complexity: O(block size)
+func (z *BlockWriter) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errors.New("write to closed BlockWriter")
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		take := min(len(p), z.blockSize-len(z.buf))
+		z.buf = append(z.buf, p[:take]...)
+		p = p[take:]
+		if len(z.buf) == z.blockSize {
+			if err := z.Flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush ends the current block early, coding the data buffered so far and
+// writing it to the underlying writer, so a reader of the finished blob can
+// decode everything written before the Flush without what follows. Like
+// flate.Writer.Flush it does not close the stream; the blob is only complete
+// after Close. Flushing with nothing buffered does nothing.
+// Time Complexity: O(b + m log m) for b buffered bytes, Space Complexity: O(b)
+func (z *BlockWriter) Flush() error {
+	if z.err != nil {
+		return z.err
+	}
+	if len(z.buf) == 0 {
+		return nil
+	}
+	block, err := encodeBlock(z.buf)
+	if err != nil {
+		z.err = err
+		return err
+	}
+	if !z.started {
+		var head bytes.Buffer
+		writeContainerHeader(&head, ModeBlocks)
+		if err := z.write(head.Bytes()); err != nil {
+			return err
+		}
+		z.started = true
+	}
+	if err := z.write(block); err != nil {
+		return err
+	}
+	z.entries = append(z.entries, blockEntry{
+		rawLength: uint64(len(z.buf)),
+		offset:    z.written,
+		length:    uint64(len(block)),
+	})
+	z.written += uint64(len(block))
+	z.buf = z.buf[:0]
+	return nil
+}
+
+// Close flushes any buffered data and writes the block index, completing
+// the blob. It does not close the underlying writer. Like
+// HuffmanCompressBlocks it rejects an empty stream.
+// Time Complexity: O(b + m log m + k) for b buffered bytes and k blocks,
+// Space Complexity: O(b + k)
+func (z *BlockWriter) Close() error {
+	if z.closed {
+		return nil
+	}
+	if err := z.Flush(); err != nil {
+		return err
+	}
+	z.closed = true
+	if len(z.entries) == 0 {
+		return ErrEmptyInput
+	}
+	return z.write(appendBlockIndex(nil, z.entries, z.written))
+}
+
+// write writes p to the underlying writer, remembering any failure so later
+// calls report it rather than emit a damaged blob.
+func (z *BlockWriter) write(p []byte) error {
+	if _, err := z.w.Write(p); err != nil {
+		z.err = fmt.Errorf("write output failed: %w", err)
+		return z.err
+	}
+	return nil
+}
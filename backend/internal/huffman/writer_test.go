@@ -0,0 +1,188 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriterReset(t *testing.T) {
+	payloads := [][]byte{
+		[]byte(strings.Repeat("hello world! ", 200)),
+		{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03},
+	}
+
+	var outs [2]bytes.Buffer
+	zw := NewWriter(&outs[0])
+	// The first stream is written in two parts, and a stray write that is
+	// never closed must not leak into the second.
+	zw.Write(payloads[0][:100])
+	zw.Write(payloads[0][100:])
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if _, err := zw.Write([]byte("x")); err == nil {
+		t.Error("expected error writing to a closed Writer but got nil")
+	}
+
+	zw.Reset(&outs[1])
+	zw.Write(payloads[1])
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected close error after reset: %v", err)
+	}
+
+	for i, want := range payloads {
+		got, err := Decompress(outs[i].Bytes())
+		if err != nil {
+			t.Fatalf("stream %d: unexpected decompress error: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("stream %d: decompressed output does not match original", i)
+		}
+		direct, err := HuffmanCompressBytes(want)
+		if err != nil {
+			t.Fatalf("stream %d: unexpected compress error: %v", i, err)
+		}
+		if !bytes.Equal(outs[i].Bytes(), direct) {
+			t.Errorf("stream %d: Writer output differs from HuffmanCompressBytes", i)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	zw := NewWriter(&out)
+	if err := zw.Close(); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %d bytes", out.Len())
+	}
+}
+
+func TestBlockWriterFlush(t *testing.T) {
+	parts := [][]byte{
+		[]byte(strings.Repeat("first part, ", 20)),
+		[]byte("second"),
+		[]byte(strings.Repeat("the third part spans more than one block. ", 10)),
+	}
+	const blockSize = 100
+
+	var out bytes.Buffer
+	zw, err := NewBlockWriter(&out, blockSize)
+	if err != nil {
+		t.Fatalf("unexpected writer error: %v", err)
+	}
+	var want []byte
+	var wantLengths []uint64
+	for _, p := range parts {
+		if _, err := zw.Write(p); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+		if err := zw.Flush(); err != nil {
+			t.Fatalf("unexpected flush error: %v", err)
+		}
+		// A second flush has nothing to write.
+		size := out.Len()
+		if err := zw.Flush(); err != nil {
+			t.Fatalf("unexpected flush error: %v", err)
+		}
+		if out.Len() != size {
+			t.Errorf("empty flush wrote %d bytes", out.Len()-size)
+		}
+		want = append(want, p...)
+		for n := len(p); n > 0; n -= blockSize {
+			wantLengths = append(wantLengths, uint64(min(n, blockSize)))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if _, err := zw.Write([]byte("x")); err == nil {
+		t.Error("expected error writing to a closed BlockWriter but got nil")
+	}
+
+	blob := out.Bytes()
+	got, err := Decompress(blob)
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("decompressed output does not match the written data")
+	}
+	_, body, err := unwrap(blob)
+	if err != nil {
+		t.Fatalf("unexpected container error: %v", err)
+	}
+	entries, _, _, err := readBlockIndex(body)
+	if err != nil {
+		t.Fatalf("unexpected index error: %v", err)
+	}
+	if len(entries) != len(wantLengths) {
+		t.Fatalf("expected %d blocks, got %d", len(wantLengths), len(entries))
+	}
+	for i, e := range entries {
+		if e.rawLength != wantLengths[i] {
+			t.Errorf("block %d: expected %d bytes, got %d", i, wantLengths[i], e.rawLength)
+		}
+	}
+
+	// Each flushed part can be read back on its own.
+	var start int64
+	for i, p := range parts {
+		got, err := HuffmanDecompressRange(blob, start, int64(len(p)))
+		if err != nil {
+			t.Fatalf("part %d: unexpected range error: %v", i, err)
+		}
+		if !bytes.Equal(got, p) {
+			t.Errorf("part %d: range does not match the written data", i)
+		}
+		start += int64(len(p))
+	}
+}
+
+func TestBlockWriterMatchesCompressBlocks(t *testing.T) {
+	data := []byte(strings.Repeat("hello world! the quick brown fox. ", 100))
+	want, err := HuffmanCompressBlocks(data, 256)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	var out bytes.Buffer
+	zw, err := NewBlockWriter(&out, 256)
+	if err != nil {
+		t.Fatalf("unexpected writer error: %v", err)
+	}
+	// Writes that straddle block boundaries still cut blocks at blockSize.
+	for i := 0; i < len(data); i += 77 {
+		if _, err := zw.Write(data[i:min(i+77, len(data))]); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Error("BlockWriter output differs from HuffmanCompressBlocks")
+	}
+}
+
+func TestBlockWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	zw, err := NewBlockWriter(&out, 0)
+	if err != nil {
+		t.Fatalf("unexpected writer error: %v", err)
+	}
+	if err := zw.Flush(); err != nil {
+		t.Errorf("unexpected flush error: %v", err)
+	}
+	if err := zw.Close(); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %d bytes", out.Len())
+	}
+	if _, err := NewBlockWriter(&out, -1); err == nil {
+		t.Error("expected error for a negative block size but got nil")
+	}
+}
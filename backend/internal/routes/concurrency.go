@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ConcurrencyLimit returns middleware that lets at most limit requests run at
+// once. Excess requests wait up to wait for a slot, then are rejected with
+// 503 Service Unavailable; a wait of 0 rejects them immediately. Routes
+// sharing the returned middleware share its slots.
+func ConcurrencyLimit(limit int, wait time.Duration) echo.MiddlewareFunc {
+	slots := make(chan struct{}, limit)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if !acquire(c, slots, wait) {
+					return echo.NewHTTPError(http.StatusServiceUnavailable, "server busy, try again later")
+				}
+			}
+			defer func() { <-slots }()
+			return next(c)
+		}
+	}
+}
+
+// acquire waits up to wait for a slot, giving up early if the client goes
+// away. It reports whether a slot was taken.
+func acquire(c echo.Context, slots chan struct{}, wait time.Duration) bool {
+	if wait <= 0 {
+		return false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request().Context().Done():
+		return false
+	}
+}
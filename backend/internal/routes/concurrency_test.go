@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		wait     time.Duration
+		wantCode int
+	}{
+		{name: "Reject", wait: 0, wantCode: http.StatusServiceUnavailable},
+		{name: "Queue timeout", wait: 20 * time.Millisecond, wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const limit = 2
+			started := make(chan struct{})
+			release := make(chan struct{})
+			e := echo.New()
+			e.POST("/compress", func(c echo.Context) error {
+				started <- struct{}{}
+				<-release
+				return c.NoContent(http.StatusOK)
+			}, ConcurrencyLimit(limit, tt.wait))
+
+			var wg sync.WaitGroup
+			codes := make([]int, limit)
+			for i := 0; i < limit; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					rec := httptest.NewRecorder()
+					e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/compress", nil))
+					codes[i] = rec.Code
+				}(i)
+				<-started
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/compress", nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d past the limit, got %d", tt.wantCode, rec.Code)
+			}
+
+			close(release)
+			wg.Wait()
+			for i, code := range codes {
+				if code != http.StatusOK {
+					t.Errorf("request %d: expected status 200 within the limit, got %d", i, code)
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitQueues(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	e := echo.New()
+	e.POST("/compress", func(c echo.Context) error {
+		started <- struct{}{}
+		<-release
+		return c.NoContent(http.StatusOK)
+	}, ConcurrencyLimit(1, time.Minute))
+
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/compress", nil))
+			codes <- rec.Code
+		}()
+	}
+
+	// Only one request runs; the other waits for its slot.
+	<-started
+	select {
+	case <-started:
+		t.Fatal("expected the second request to queue behind the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	<-started
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("expected queued requests to succeed, got status %d", code)
+		}
+	}
+}
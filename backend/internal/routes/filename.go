@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultExtension is the extension /compress gives its downloads unless
+// the Extension middleware sets another.
+const DefaultExtension = ".huff"
+
+// compressedPrefix is prepended to the name of every /compress download.
+const compressedPrefix = "compressed_"
+
+// extensionKey is the context key Extension stores its value under.
+const extensionKey = "huffmin.extension"
+
+// Extension returns middleware that makes the compression handlers name
+// their downloads with ext instead of DefaultExtension, and makes
+// /decompress and /decompress/info strip it from uploads.
+func Extension(ext string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(extensionKey, ext)
+			return next(c)
+		}
+	}
+}
+
+// extension returns the download extension in effect for c.
+func extension(c echo.Context) string {
+	if ext, ok := c.Get(extensionKey).(string); ok {
+		return ext
+	}
+	return DefaultExtension
+}
+
+// compressedName names the compressed download of an upload called name,
+// adding ext unless name already ends in it, as a blob uploaded with
+// compressed=skip may.
+func compressedName(name, ext string) string {
+	if !strings.HasSuffix(name, ext) {
+		name += ext
+	}
+	return compressedPrefix + name
+}
+
+// originalName recovers an upload's name from the name of its compressed
+// download, reporting whether name is exactly what compressedName gives.
+// Otherwise it only strips ext, if present.
+func originalName(name, ext string) (string, bool) {
+	trimmed, hadExt := strings.CutSuffix(name, ext)
+	if original, ok := strings.CutPrefix(trimmed, compressedPrefix); ok && hadExt && original != "" {
+		return original, true
+	}
+	return trimmed, false
+}
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kelbwah/huffmin/backend/internal/huffman"
+	"github.com/labstack/echo/v4"
+)
+
+func TestOriginalName(t *testing.T) {
+	tests := []struct {
+		name         string
+		upload       string
+		want         string
+		wantRestored bool
+	}{
+		{name: "Compressed download", upload: "compressed_notes.txt.huff", want: "notes.txt", wantRestored: true},
+		{name: "Extension only", upload: "notes.txt.huff", want: "notes.txt"},
+		{name: "Prefix only", upload: "compressed_notes.txt", want: "compressed_notes.txt"},
+		{name: "Nothing left", upload: "compressed_.huff", want: "compressed_"},
+		{name: "Plain", upload: "blob", want: "blob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, restored := originalName(tt.upload, DefaultExtension)
+			if got != tt.want || restored != tt.wantRestored {
+				t.Errorf("originalName(%q) = %q, %v, want %q, %v", tt.upload, got, restored, tt.want, tt.wantRestored)
+			}
+		})
+	}
+}
+
+// dispositionName returns the filename of rec's Content-Disposition header.
+func dispositionName(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	_, params, err := mime.ParseMediaType(rec.Header().Get(echo.HeaderContentDisposition))
+	if err != nil {
+		t.Fatalf("invalid Content-Disposition %q: %v", rec.Header().Get(echo.HeaderContentDisposition), err)
+	}
+	return params["filename"]
+}
+
+func TestCompressedNameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		wantExt string
+	}{
+		{name: "Default extension", wantExt: ".huff"},
+		{name: "Custom extension", ext: ".hm", wantExt: ".hm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			if tt.ext != "" {
+				e.Use(Extension(tt.ext))
+			}
+			e.POST("/compress", CompressFile)
+			e.POST("/decompress", DecompressFile)
+
+			content := []byte(strings.Repeat("round trip the name ", 20))
+			req := newMultipartRequest(t, "/compress", "file", []formFile{{name: "notes.txt", content: content}})
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("compress: expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			compressedName := dispositionName(t, rec)
+			if want := "compressed_notes.txt" + tt.wantExt; compressedName != want {
+				t.Errorf("expected compressed filename %q, got %q", want, compressedName)
+			}
+
+			req = newMultipartRequest(t, "/decompress", "file", []formFile{{name: compressedName, content: rec.Body.Bytes()}})
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("decompress: expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := dispositionName(t, rec); got != "notes.txt" {
+				t.Errorf("expected the original filename back, got %q", got)
+			}
+			if rec.Body.String() != string(content) {
+				t.Error("decompressed output does not match original")
+			}
+		})
+	}
+}
+
+func TestCompressedNameSkipped(t *testing.T) {
+	e := echo.New()
+	e.POST("/compress", CompressFile)
+	blob, err := huffman.HuffmanCompressBytes([]byte("already a blob"))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	req := newMultipartRequest(t, "/compress?compressed=skip", "file", []formFile{{name: "compressed_notes.txt.huff", content: blob}})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got, want := dispositionName(t, rec), "compressed_compressed_notes.txt.huff"; got != want {
+		t.Errorf("expected filename %q without a second extension, got %q", want, got)
+	}
+}
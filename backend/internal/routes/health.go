@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/kelbwah/huffmin/backend/internal/huffman"
+	"github.com/labstack/echo/v4"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+type VersionResponse struct {
+	FormatVersion int    `json:"formatVersion"`
+	Version       string `json:"version"`
+	Revision      string `json:"revision"`
+	GoVersion     string `json:"goVersion"`
+}
+
+func Health(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+func Version(c echo.Context) error {
+	resp := VersionResponse{
+		FormatVersion: huffman.FormatVersion,
+		Version:       "(devel)",
+		Revision:      "unknown",
+		GoVersion:     runtime.Version(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Version != "" {
+			resp.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				resp.Revision = setting.Value
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
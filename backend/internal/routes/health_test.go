@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kelbwah/huffmin/backend/internal/huffman"
+	"github.com/labstack/echo/v4"
+)
+
+func TestHealth(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Health(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if body["status"] != "ok" {
+		t.Errorf("expected status \"ok\", got %v", body["status"])
+	}
+}
+
+func TestVersion(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Version(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	for _, key := range []string{"formatVersion", "version", "revision", "goVersion"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("missing key %q in response: %v", key, body)
+		}
+	}
+	if got, ok := body["formatVersion"].(float64); !ok || int(got) != huffman.FormatVersion {
+		t.Errorf("expected formatVersion %d, got %v", huffman.FormatVersion, body["formatVersion"])
+	}
+	for _, key := range []string{"version", "revision", "goVersion"} {
+		if s, ok := body[key].(string); !ok || s == "" {
+			t.Errorf("expected non-empty string for %q, got %v", key, body[key])
+		}
+	}
+}
@@ -1,60 +1,182 @@
 package routes
 
 import (
+	"errors"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"net/textproto"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kelbwah/huffmin/backend/internal/huffman"
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	// HeaderHuffminError marks a batch response part whose file could not be
+	// compressed; its value describes the failure.
+	HeaderHuffminError = "X-Huffmin-Error"
+	// HeaderHuffminMode reports the codec /compress chose, such as
+	// "huffman" or "store". A blob given a name=store name is a ModeNamed
+	// container around a blob of this mode.
+	HeaderHuffminMode = "X-Huffmin-Mode"
+	// HeaderHuffminOriginalSize reports the size in bytes of the uploaded
+	// file /compress encoded.
+	HeaderHuffminOriginalSize = "X-Huffmin-Original-Size"
+	// HeaderHuffminSkipped is set to "already-compressed" when /compress
+	// returns a huffmin blob upload unchanged.
+	HeaderHuffminSkipped = "X-Huffmin-Skipped"
+	// HeaderHuffminWarning carries a diagnostic about the upload, such as
+	// a byte distribution too skewed for Huffman coding to pay off.
+	HeaderHuffminWarning = "X-Huffmin-Warning"
+)
+
 func CompressFile(c echo.Context) error {
+	start := time.Now()
+	auto := false
+	switch c.QueryParam("mode") {
+	case "", huffman.ModeHuffman.String():
+	case "auto":
+		auto = true
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "mode must be huffman or auto")
+	}
+	// Uploads that are already huffmin blobs are rejected unless the client
+	// asks for them back as they are; compressing them again only grows them.
+	skipCompressed := false
+	switch c.QueryParam("compressed") {
+	case "", "reject":
+	case "skip":
+		skipCompressed = true
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "compressed must be reject or skip")
+	}
+	// The upload's name is recorded in the blob only on request, so
+	// clients that expect a plain blob of the reported mode still get one.
+	storeName := false
+	switch c.QueryParam("name") {
+	case "", "omit":
+	case "store":
+		storeName = true
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "name must be omit or store")
+	}
 	file, err := c.FormFile("file")
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "file required")
 	}
+	var nameHeader []byte
+	if storeName {
+		if nameHeader, err = huffman.NameHeader(file.Filename); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "file name cannot be stored: "+err.Error())
+		}
+	}
+	// There is nothing to compress in an empty upload.
+	if file.Size == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is empty")
+	}
+	// The upload is either held in memory or spilled by mime/multipart to a
+	// temp file that net/http removes when the request ends. Both are
+	// io.ReaderAt, so it is compressed in place without another copy.
 	src, err := file.Open()
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "cannot open uploaded file")
 	}
 	defer src.Close()
 
-	tempInputPath := filepath.Join(os.TempDir(), file.Filename)
-	outFile, err := os.Create(tempInputPath)
+	compressed, err := huffman.HasMagic(src)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create temp file")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+	if compressed && !skipCompressed {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is already huffmin-compressed; decompress it first or pass compressed=skip to get it back unchanged")
 	}
-	defer outFile.Close()
 
-	_, err = io.Copy(outFile, src)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to copy file data")
+	// The download headers go out only once there is a blob to send, so a
+	// failure before then is a plain error response, not an attachment.
+	header := c.Response().Header()
+	setDownload := func() {
+		header.Set(echo.HeaderContentType, "application/octet-stream")
+		header.Set(
+			echo.HeaderContentDisposition,
+			"attachment; filename=\""+compressedName(file.Filename, extension(c))+"\"",
+		)
+		header.Set(HeaderHuffminOriginalSize, strconv.FormatInt(file.Size, 10))
 	}
 
-	// Compress File
-	compressedBytes, err := huffman.HuffmanCompress(tempInputPath)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "compression failed")
+	if compressed {
+		setDownload()
+		header.Set(HeaderHuffminSkipped, "already-compressed")
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(file.Size, 10))
+		written, err := io.Copy(c.Response(), io.NewSectionReader(src, 0, file.Size))
+		if err != nil {
+			if c.Response().Committed {
+				return err
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to write response")
+		}
+		logOperation(c, "compress", "skipped", int(file.Size), int(written), start)
+		return nil
 	}
 
-	c.Response().Header().Set(echo.HeaderContentType, "application/octet-stream")
-	c.Response().Header().Set(
-		echo.HeaderContentDisposition,
-		"attachment; filename=\"compressed_"+file.Filename+"\"",
-	)
+	if auto {
+		// Choosing a codec means trying several, so auto mode works on the
+		// whole upload in memory.
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+		}
+		blob, stats, err := huffman.CompressAutoStats(data)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "compression failed")
+		}
+		blob = append(nameHeader, blob...)
+		setDownload()
+		header.Set(HeaderHuffminMode, stats.Mode.String())
+		if warning, depth, err := huffman.DeepCodeWarning(data); err == nil && warning != "" {
+			header.Set(HeaderHuffminWarning, warning)
+			logDeepCode(c, depth)
+		}
+		header.Set(echo.HeaderContentLength, strconv.Itoa(len(blob)))
+		if _, err := c.Response().Write(blob); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to write response")
+		}
+		logOperation(c, "compress", stats.Mode.String(), len(data), len(blob), start)
+		return nil
+	}
 
-	_, err = c.Response().Write(compressedBytes)
+	// Nothing is written until the frequency pass has finished, so input
+	// errors still produce a proper error response. That pass also fixes the
+	// blob's size, which is sent as Content-Length with the download headers
+	// before the blob is streamed, each piece the encoder emits flushed to
+	// the client straight away.
+	w := &prefixWriter{prefix: nameHeader, w: flushWriter{c.Response()}}
+	written, err := huffman.HuffmanCompressReaderAtSized(src, file.Size, w, func(info huffman.StreamInfo) {
+		setDownload()
+		header.Set(HeaderHuffminMode, huffman.ModeHuffman.String())
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(int64(len(nameHeader))+info.BlobSize, 10))
+		if warning := info.DeepCodeWarning(); warning != "" {
+			header.Set(HeaderHuffminWarning, warning)
+			logDeepCode(c, info.LongestCode)
+		}
+	})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to write response")
+		if c.Response().Committed {
+			return err
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "compression failed")
 	}
 
+	logOperation(c, "compress", huffman.ModeHuffman.String(), int(file.Size), len(nameHeader)+int(written), start)
 	return nil
 }
 
 func DecompressFile(c echo.Context) error {
+	start := time.Now()
 	file, err := c.FormFile("file")
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "file required")
@@ -70,22 +192,319 @@ func DecompressFile(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
 	}
+	// Uploads written before the container header carry no magic; those
+	// that decode as version 1 blobs are upgraded rather than turned away.
+	if !huffman.IsHuffmin(compressedBytes) {
+		if upgraded, err := huffman.UpgradeV1(compressedBytes); err == nil {
+			compressedBytes = upgraded
+		}
+	}
+	// A header check is enough to turn away uploads that were never
+	// huffmin blobs, with a clearer message than a failed decode.
+	if err := huffman.Validate(compressedBytes); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "not a huffmin file: "+err.Error())
+	}
 
 	decompressedBytes, err := huffman.HuffmanDecompress(compressedBytes)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "decompression failed")
+		return echo.NewHTTPError(decompressStatus(err), "decompression failed")
 	}
 
-	c.Response().Header().Set(echo.HeaderContentType, "application/octet-stream")
+	// A blob that records its name gets it back, as does a download named
+	// by /compress; any other upload is named after itself.
+	name, restored := originalName(file.Filename, extension(c))
+	if stored, _ := huffman.ReadName(compressedBytes); stored != "" {
+		name, restored = stored, true
+	}
+	if !restored {
+		name = "decompressed_" + name
+	}
+	contentType, disposition := "application/octet-stream", "attachment"
+	if c.QueryParam("disposition") == "inline" {
+		if t, ok := inlineContentType(name); ok {
+			contentType, disposition = t, "inline"
+			// The browser renders the upload itself, so it must trust the
+			// declared type rather than sniff one from the bytes.
+			c.Response().Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+		}
+	}
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
 	c.Response().Header().Set(
 		echo.HeaderContentDisposition,
-		"attachment; filename=\"decompressed_"+strings.TrimSuffix(file.Filename, ".huff")+"\"",
+		disposition+"; filename=\""+name+"\"",
 	)
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.Itoa(len(decompressedBytes)))
 
 	_, err = c.Response().Write(decompressedBytes)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to write response")
 	}
 
+	mode, _ := huffman.ModeOf(compressedBytes)
+	logOperation(c, "decompress", mode.String(), len(compressedBytes), len(decompressedBytes), start)
 	return nil
 }
+
+// flushWriter flushes the response after every write, so each chunk a
+// streaming encoder produces reaches the client without waiting for
+// net/http's buffer to fill.
+type flushWriter struct {
+	res *echo.Response
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.res.Write(p)
+	if err == nil {
+		w.res.Flush()
+	}
+	return n, err
+}
+
+// prefixWriter writes prefix to w ahead of the first write, so a header
+// known up front goes out only once the streamed body does. The count it
+// returns covers only p.
+type prefixWriter struct {
+	prefix []byte
+	w      io.Writer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if len(w.prefix) > 0 {
+		if _, err := w.w.Write(w.prefix); err != nil {
+			return 0, err
+		}
+		w.prefix = nil
+	}
+	return w.w.Write(p)
+}
+
+// decompressStatus maps a decompression error to an HTTP status: uploads that
+// are not valid blobs are the client's fault, anything else is the server's.
+func decompressStatus(err error) int {
+	switch {
+	case errors.Is(err, huffman.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, huffman.ErrCorruptStream),
+		errors.Is(err, huffman.ErrBadMagic),
+		errors.Is(err, huffman.ErrUnsupportedVersion):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// inlineContentType returns the media type for name's extension if browsers
+// can safely render it in place. Markup types such as HTML and SVG are
+// excluded, since rendering them inline would run scripts from the upload.
+func inlineContentType(name string) (string, bool) {
+	t := mime.TypeByExtension(filepath.Ext(name))
+	mediaType, _, err := mime.ParseMediaType(t)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case mediaType == "image/svg+xml":
+		return "", false
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		mediaType == "text/plain",
+		mediaType == "application/pdf":
+		return t, true
+	default:
+		return "", false
+	}
+}
+
+func CompressBatch(c echo.Context) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "multipart form required")
+	}
+	files := form.File["files[]"]
+	if len(files) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one file required")
+	}
+
+	mw := multipart.NewWriter(c.Response())
+	c.Response().Header().Set(echo.HeaderContentType, "multipart/mixed; boundary="+mw.Boundary())
+	c.Response().WriteHeader(http.StatusOK)
+
+	// Each file is written as its own part and flushed as soon as it is
+	// compressed, so clients can start consuming results before the batch ends.
+	for _, file := range files {
+		header := textproto.MIMEHeader{}
+		header.Set(echo.HeaderContentDisposition, "attachment; filename=\""+compressedName(file.Filename, extension(c))+"\"")
+
+		body, err := compressFormFile(file)
+		if err != nil {
+			msg := "compression failed"
+			if errors.Is(err, huffman.ErrEmptyInput) {
+				msg = "file is empty"
+			}
+			header.Set(echo.HeaderContentType, "text/plain; charset=utf-8")
+			header.Set(HeaderHuffminError, msg)
+			body = []byte(msg)
+		} else {
+			header.Set(echo.HeaderContentType, "application/octet-stream")
+		}
+
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(body); err != nil {
+			return err
+		}
+		c.Response().Flush()
+	}
+
+	return mw.Close()
+}
+
+type DecompressInfoResponse struct {
+	huffman.BlobInfo
+	// NameFromUpload is set when the blob records no name, as blobs
+	// compressed without name=store do. OriginalName is then the name
+	// /decompress would give the output, the upload's filename with the
+	// download extension stripped, and only as right as that name.
+	NameFromUpload bool `json:"nameFromUpload"`
+}
+
+func DecompressInfo(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file required")
+	}
+
+	compressedBytes, err := readFormFile(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+
+	info, err := huffman.Inspect(compressedBytes)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid compressed file")
+	}
+
+	res := DecompressInfoResponse{BlobInfo: info}
+	if res.OriginalName == "" {
+		res.OriginalName, _ = originalName(file.Filename, extension(c))
+		res.NameFromUpload = true
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func Estimate(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file required")
+	}
+
+	data, err := readFormFile(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+
+	estimate, err := huffman.EstimateCompressedSize(data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "estimation failed")
+	}
+
+	return c.JSON(http.StatusOK, estimate)
+}
+
+func Analyze(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file required")
+	}
+
+	data, err := readFormFile(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+
+	analysis, err := huffman.Analyze(data)
+	if errors.Is(err, huffman.ErrEmptyInput) {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is empty")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "analysis failed")
+	}
+
+	return c.JSON(http.StatusOK, analysis)
+}
+
+func Compare(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file required")
+	}
+
+	data, err := readFormFile(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+
+	comparison, err := huffman.CompareCodecs(data)
+	if errors.Is(err, huffman.ErrEmptyInput) {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is empty")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "comparison failed")
+	}
+
+	return c.JSON(http.StatusOK, comparison)
+}
+
+func Frequencies(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file required")
+	}
+
+	data, err := readFormFile(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+
+	return c.JSON(http.StatusOK, huffman.CountFrequencies(data))
+}
+
+func Tree(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file required")
+	}
+
+	data, err := readFormFile(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read uploaded file")
+	}
+
+	tree, err := huffman.TreeJSON(data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "tree construction failed")
+	}
+
+	return c.JSONBlob(http.StatusOK, tree)
+}
+
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return io.ReadAll(src)
+}
+
+func compressFormFile(file *multipart.FileHeader) ([]byte, error) {
+	data, err := readFormFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return huffman.HuffmanCompressBytes(data)
+}
@@ -0,0 +1,932 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelbwah/huffmin/backend/internal/huffman"
+	"github.com/labstack/echo/v4"
+)
+
+type formFile struct {
+	name    string
+	content []byte
+}
+
+func newMultipartRequest(t *testing.T, target, field string, files []formFile) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, f := range files {
+		w, err := mw.CreateFormFile(field, f.name)
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		if _, err := w.Write(f.content); err != nil {
+			t.Fatalf("failed to write form file: %v", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	return req
+}
+
+func TestCompressBatch(t *testing.T) {
+	files := []formFile{
+		{name: "a.txt", content: []byte("aaaaabbbbcccdde")},
+		{name: "b.bin", content: []byte{0x00, 0xFF, 0xAB, 0xAB, 0xAB, 0x01, 0x02, 0x03}},
+		{name: "c.txt", content: []byte("hello world! hello world! hello world!")},
+	}
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/compress/batch", "files[]", files)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := CompressBatch(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get(echo.HeaderContentType))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected multipart/mixed response, got %q (%v)", rec.Header().Get(echo.HeaderContentType), err)
+	}
+
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for i, want := range files {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if got := part.FileName(); got != "compressed_"+want.name+".huff" {
+			t.Errorf("part %d: expected filename %q, got %q", i, "compressed_"+want.name+".huff", got)
+		}
+		if msg := part.Header.Get(HeaderHuffminError); msg != "" {
+			t.Fatalf("part %d: unexpected error part: %s", i, msg)
+		}
+		compressed, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("part %d: read failed: %v", i, err)
+		}
+		decompressed, err := huffman.HuffmanDecompress(compressed)
+		if err != nil {
+			t.Fatalf("part %d: decompress failed: %v", i, err)
+		}
+		if !bytes.Equal(decompressed, want.content) {
+			t.Errorf("part %d: round trip mismatch.\nGot: %v\nWant: %v", i, decompressed, want.content)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly %d parts, got more (err=%v)", len(files), err)
+	}
+}
+
+func TestCompressEmptyFile(t *testing.T) {
+	for _, mode := range []string{"", "auto"} {
+		t.Run("mode="+mode, func(t *testing.T) {
+			e := echo.New()
+			e.POST("/compress", CompressFile)
+			req := newMultipartRequest(t, "/compress?mode="+mode, "file", []formFile{{name: "empty.txt"}})
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+			var body struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+			if body.Message != "file is empty" {
+				t.Errorf("expected message %q, got %q", "file is empty", body.Message)
+			}
+			// The error must not be offered to the browser as a download.
+			if cd := rec.Header().Get(echo.HeaderContentDisposition); cd != "" {
+				t.Errorf("expected no Content-Disposition on the error, got %q", cd)
+			}
+		})
+	}
+}
+
+func TestCompressBatchEmptyFile(t *testing.T) {
+	files := []formFile{{name: "empty.txt"}, {name: "full.txt", content: []byte("not empty")}}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(newMultipartRequest(t, "/compress/batch", "files[]", files), rec)
+	if err := CompressBatch(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, params, err := mime.ParseMediaType(rec.Header().Get(echo.HeaderContentType))
+	if err != nil {
+		t.Fatalf("unexpected media type error: %v", err)
+	}
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("unexpected part error: %v", err)
+	}
+	if msg := part.Header.Get(HeaderHuffminError); msg != "file is empty" {
+		t.Errorf("expected the empty part to report %q, got %q", "file is empty", msg)
+	}
+	part, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("unexpected part error: %v", err)
+	}
+	if msg := part.Header.Get(HeaderHuffminError); msg != "" {
+		t.Errorf("expected the other part to compress, got error %q", msg)
+	}
+}
+
+func TestCompressBatchNoFiles(t *testing.T) {
+	e := echo.New()
+	req := newMultipartRequest(t, "/compress/batch", "files[]", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := CompressBatch(c)
+	he, ok := err.(*echo.HTTPError)
+	if !ok || he.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 HTTP error, got %v", err)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	content := []byte("hello world! hello world! hello world! hello world!")
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/estimate", "file", []formFile{{name: "a.txt", content: content}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Estimate(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body huffman.EstimateResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	want, err := huffman.EstimateCompressedSize(content)
+	if err != nil {
+		t.Fatalf("unexpected estimate error: %v", err)
+	}
+	if body != want {
+		t.Errorf("expected %+v, got %+v", want, body)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	content := bytes.Repeat([]byte("hello world! the quick brown fox. "), 40)
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/compare", "file", []formFile{{name: "a.txt", content: content}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Compare(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body huffman.Comparison
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if body.OriginalSize != len(content) {
+		t.Errorf("expected original size %d, got %d", len(content), body.OriginalSize)
+	}
+	for name, r := range map[string]huffman.CodecResult{"huffman": body.Huffman, "gzip": body.Gzip} {
+		if r.Size <= 0 || r.Duration <= 0 {
+			t.Errorf("%s: expected size and duration to be set, got %+v", name, r)
+		}
+		// Text compresses with either codec, and neither expands it.
+		if r.Ratio <= 0 || r.Ratio >= 1 {
+			t.Errorf("%s: ratio %f outside (0, 1)", name, r.Ratio)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(newMultipartRequest(t, "/compare", "file", []formFile{{name: "empty.txt"}}), rec)
+	err := Compare(c)
+	if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty file, got %v", err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	content := bytes.Repeat([]byte("aaaabbcd"), 64)
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/analyze", "file", []formFile{{name: "a.txt", content: content}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Analyze(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body huffman.AnalyzeResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	want, err := huffman.Analyze(content)
+	if err != nil {
+		t.Fatalf("unexpected analyze error: %v", err)
+	}
+	if body != want {
+		t.Errorf("expected %+v, got %+v", want, body)
+	}
+	// Dyadic frequencies let Huffman coding meet the entropy bound.
+	if body.Entropy != 1.75 || body.Efficiency != 1 {
+		t.Errorf("expected entropy 1.75 at efficiency 1, got %v at %v", body.Entropy, body.Efficiency)
+	}
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(newMultipartRequest(t, "/analyze", "file", []formFile{{name: "empty.txt"}}), rec)
+	err = Analyze(c)
+	if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty file, got %v", err)
+	}
+}
+
+func TestFrequencies(t *testing.T) {
+	content := []byte("abracadabra")
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/frequencies", "file", []formFile{{name: "a.txt", content: content}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Frequencies(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	want := `{"0x61":5,"0x62":2,"0x63":1,"0x64":1,"0x72":2}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestTree(t *testing.T) {
+	e := echo.New()
+	req := newMultipartRequest(t, "/tree", "file", []formFile{{name: "a.txt", content: []byte("aaabc")}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := Tree(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Tree struct {
+			Freq int `json:"freq"`
+		} `json:"tree"`
+		Codes map[string]string `json:"codes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if body.Tree.Freq != 5 {
+		t.Errorf("expected root frequency 5, got %d", body.Tree.Freq)
+	}
+	if len(body.Codes) != 3 || body.Codes["0x61"] != "1" {
+		t.Errorf("unexpected codes: %v", body.Codes)
+	}
+}
+
+func TestDecompressInfo(t *testing.T) {
+	content := []byte("aaaaabbbbcccdde")
+	compressed, err := huffman.HuffmanCompressBytes(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/decompress/info", "file", []formFile{{name: "notes.txt.huff", content: compressed}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := DecompressInfo(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	want := map[string]any{
+		"originalName":   "notes.txt",
+		"nameFromUpload": true,
+		"mode":           "huffman",
+		"originalSize":   float64(len(content)),
+		"compressedSize": float64(len(compressed)),
+		"symbolCount":    float64(5),
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("expected %s = %v, got %v", key, value, body[key])
+		}
+	}
+	if _, ok := body["payloadBits"]; !ok {
+		t.Errorf("missing payloadBits in response: %v", body)
+	}
+}
+
+func TestDecompressInfoAutoModes(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(36)).Read(random)
+
+	tests := []struct {
+		name     string
+		content  []byte
+		wantMode string
+	}{
+		{name: "Store", content: random, wantMode: "store"},
+		{name: "Flate", content: bytes.Repeat([]byte("hello world! "), 100), wantMode: "flate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/compress?mode=auto", "file", []formFile{{name: "in.bin", content: tt.content}})
+			rec := httptest.NewRecorder()
+			if err := CompressFile(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			if got := rec.Header().Get(HeaderHuffminMode); got != tt.wantMode {
+				t.Fatalf("expected /compress to pick %q, got %q", tt.wantMode, got)
+			}
+			blob := rec.Body.Bytes()
+
+			req = newMultipartRequest(t, "/decompress/info", "file", []formFile{{name: "in.bin.huff", content: blob}})
+			rec = httptest.NewRecorder()
+			if err := DecompressInfo(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("unexpected info error: %v", err)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+			want := map[string]any{
+				"mode":           tt.wantMode,
+				"originalSize":   float64(len(tt.content)),
+				"compressedSize": float64(len(blob)),
+			}
+			for key, value := range want {
+				if body[key] != value {
+					t.Errorf("expected %s = %v, got %v", key, value, body[key])
+				}
+			}
+		})
+	}
+}
+
+func TestCompressFileStoreName(t *testing.T) {
+	content := bytes.Repeat([]byte("hello world! "), 100)
+
+	for _, query := range []string{"?name=store", "?name=store&mode=auto"} {
+		t.Run(query, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/compress"+query, "file", []formFile{{name: "report.txt", content: content}})
+			rec := httptest.NewRecorder()
+			if err := CompressFile(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("unexpected compress error: %v", err)
+			}
+			blob := rec.Body.Bytes()
+			if got, want := rec.Header().Get(echo.HeaderContentLength), strconv.Itoa(len(blob)); got != want {
+				t.Errorf("expected Content-Length %s, got %s", want, got)
+			}
+			if name, err := huffman.ReadName(blob); err != nil || name != "report.txt" {
+				t.Fatalf("expected the blob to record %q, got %q (%v)", "report.txt", name, err)
+			}
+
+			// The stored name wins over whatever the blob was renamed to.
+			req = newMultipartRequest(t, "/decompress/info", "file", []formFile{{name: "renamed.bin", content: blob}})
+			rec = httptest.NewRecorder()
+			if err := DecompressInfo(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("unexpected info error: %v", err)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+			if body["originalName"] != "report.txt" || body["nameFromUpload"] != false {
+				t.Errorf("expected the stored name, got originalName %v, nameFromUpload %v", body["originalName"], body["nameFromUpload"])
+			}
+			if body["originalSize"] != float64(len(content)) {
+				t.Errorf("expected originalSize %d, got %v", len(content), body["originalSize"])
+			}
+
+			req = newMultipartRequest(t, "/decompress", "file", []formFile{{name: "renamed.bin", content: blob}})
+			rec = httptest.NewRecorder()
+			if err := DecompressFile(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if got, want := rec.Header().Get(echo.HeaderContentDisposition), `attachment; filename="report.txt"`; got != want {
+				t.Errorf("expected Content-Disposition %q, got %q", want, got)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), content) {
+				t.Error("decompressed output does not match original")
+			}
+		})
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		fileName string
+	}{
+		{name: "Unknown value", query: "?name=keep", fileName: "report.txt"},
+		{name: "Unstorable name", query: "?name=store", fileName: `say "hi".txt`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/compress"+tt.query, "file", []formFile{{name: tt.fileName, content: content}})
+			rec := httptest.NewRecorder()
+			err := CompressFile(e.NewContext(req, rec))
+			if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %v", err)
+			}
+			if got := rec.Header().Get(echo.HeaderContentDisposition); got != "" {
+				t.Errorf("expected no Content-Disposition on an error, got %q", got)
+			}
+		})
+	}
+}
+
+func TestDecompressInfoInvalid(t *testing.T) {
+	e := echo.New()
+	req := newMultipartRequest(t, "/decompress/info", "file", []formFile{{name: "bad.huff", content: []byte{0x01}}})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := DecompressInfo(c)
+	he, ok := err.(*echo.HTTPError)
+	if !ok || he.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 HTTP error, got %v", err)
+	}
+}
+
+func TestDecompressFileStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "Not a blob", content: []byte("plain text"), wantCode: http.StatusBadRequest, wantMessage: "not a huffmin file: bad magic"},
+		{name: "Too short", content: []byte("HU"), wantCode: http.StatusBadRequest, wantMessage: "not a huffmin file"},
+		{name: "Unknown mode", content: []byte("HUFM\x02\xff"), wantCode: http.StatusBadRequest, wantMessage: "not a huffmin file"},
+		{name: "Truncated", content: []byte("HUFM\x02\x01\x03"), wantCode: http.StatusBadRequest, wantMessage: "decompression failed"},
+		{name: "Future version", content: []byte("HUFM\x09\x01"), wantCode: http.StatusBadRequest, wantMessage: "not a huffmin file: unsupported format version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/decompress", "file", []formFile{{name: "x.huff", content: tt.content}})
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			err := DecompressFile(c)
+			he, ok := err.(*echo.HTTPError)
+			if !ok || he.Code != tt.wantCode {
+				t.Fatalf("expected %d HTTP error, got %v", tt.wantCode, err)
+			}
+			if msg, _ := he.Message.(string); !strings.HasPrefix(msg, tt.wantMessage) {
+				t.Errorf("expected message starting %q, got %q", tt.wantMessage, he.Message)
+			}
+		})
+	}
+}
+
+func TestDecompressFileV1(t *testing.T) {
+	content := []byte("written before the container header")
+	blob, err := huffman.HuffmanCompressBytes(content)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	// A version 1 blob is the ModeHuffman body without the six-byte
+	// container header.
+	legacy := blob[6:]
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/decompress", "file", []formFile{{name: "old.txt.huff", content: legacy}})
+	rec := httptest.NewRecorder()
+	if err := DecompressFile(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content) {
+		t.Errorf("expected %q, got %q", content, rec.Body.Bytes())
+	}
+}
+
+func TestDecompressFileDisposition(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nnot really an image")
+	compressed, err := huffman.HuffmanCompressBytes(png)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		filename        string
+		query           string
+		wantType        string
+		wantDisposition string
+	}{
+		{name: "Inline image", filename: "photo.png.huff", query: "?disposition=inline", wantType: "image/png", wantDisposition: `inline; filename="decompressed_photo.png"`},
+		{name: "Default attachment", filename: "photo.png.huff", query: "", wantType: "application/octet-stream", wantDisposition: `attachment; filename="decompressed_photo.png"`},
+		{name: "Unknown extension", filename: "blob.zzz.huff", query: "?disposition=inline", wantType: "application/octet-stream", wantDisposition: `attachment; filename="decompressed_blob.zzz"`},
+		{name: "Unsafe markup", filename: "page.html.huff", query: "?disposition=inline", wantType: "application/octet-stream", wantDisposition: `attachment; filename="decompressed_page.html"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/decompress"+tt.query, "file", []formFile{{name: tt.filename, content: compressed}})
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := DecompressFile(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); got != tt.wantType {
+				t.Errorf("expected content type %q, got %q", tt.wantType, got)
+			}
+			if got := rec.Header().Get(echo.HeaderContentDisposition); got != tt.wantDisposition {
+				t.Errorf("expected disposition %q, got %q", tt.wantDisposition, got)
+			}
+			inline := strings.HasPrefix(tt.wantDisposition, "inline")
+			if got := rec.Header().Get(echo.HeaderXContentTypeOptions); inline && got != "nosniff" {
+				t.Errorf("expected %s nosniff on an inline response, got %q", echo.HeaderXContentTypeOptions, got)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), png) {
+				t.Error("decompressed output does not match original")
+			}
+		})
+	}
+}
+
+func TestCompressFileNoTempFiles(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	e := echo.New()
+	e.POST("/compress", CompressFile)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		content  []byte
+		wantCode int
+	}{
+		{name: "Small upload", content: bytes.Repeat([]byte("stream me without a temp file. "), 1000), wantCode: http.StatusOK},
+		// Past the 32 MiB in-memory limit, mime/multipart spills the upload
+		// to the temp dir; it must be gone once the request ends.
+		{name: "Spilled upload", content: bytes.Repeat([]byte("abcdefgh"), 33<<20/8), wantCode: http.StatusOK},
+		{name: "Empty upload", content: nil, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newMultipartRequest(t, srv.URL+"/compress", "file", []formFile{{name: "upload.txt", content: tt.content}})
+			req.RequestURI = ""
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("failed to read response: %v", err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			if tt.wantCode == http.StatusOK {
+				decompressed, err := huffman.Decompress(body)
+				if err != nil {
+					t.Fatalf("unexpected decompress error: %v", err)
+				}
+				if !bytes.Equal(decompressed, tt.content) {
+					t.Error("decompressed output does not match original")
+				}
+			}
+
+			// The server removes spilled parts after the handler returns,
+			// which can land just after the client has read the response.
+			var entries []os.DirEntry
+			for i := 0; i < 50; i++ {
+				if entries, err = os.ReadDir(tmp); err != nil {
+					t.Fatalf("failed to read temp dir: %v", err)
+				}
+				if len(entries) == 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if len(entries) != 0 {
+				t.Errorf("expected no files left in the temp dir, found %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestCompressFileModeHeaders(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(36)).Read(random)
+	text := bytes.Repeat([]byte("hello world! "), 100)
+
+	tests := []struct {
+		name     string
+		query    string
+		content  []byte
+		wantMode string
+	}{
+		{name: "Auto stores random input", query: "?mode=auto", content: random, wantMode: "store"},
+		{name: "Default is huffman", query: "", content: text, wantMode: "huffman"},
+		{name: "Explicit huffman", query: "?mode=huffman", content: random, wantMode: "huffman"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/compress"+tt.query, "file", []formFile{{name: "in.bin", content: tt.content}})
+			rec := httptest.NewRecorder()
+			if err := CompressFile(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := rec.Header().Get(HeaderHuffminMode); got != tt.wantMode {
+				t.Errorf("expected %s %q, got %q", HeaderHuffminMode, tt.wantMode, got)
+			}
+			if got, want := rec.Header().Get(HeaderHuffminOriginalSize), strconv.Itoa(len(tt.content)); got != want {
+				t.Errorf("expected %s %q, got %q", HeaderHuffminOriginalSize, want, got)
+			}
+			mode, err := huffman.ModeOf(rec.Body.Bytes())
+			if err != nil || mode.String() != tt.wantMode {
+				t.Errorf("expected a %s blob, got %v (%v)", tt.wantMode, mode, err)
+			}
+			decompressed, err := huffman.Decompress(rec.Body.Bytes())
+			if err != nil {
+				t.Fatalf("unexpected decompress error: %v", err)
+			}
+			if !bytes.Equal(decompressed, tt.content) {
+				t.Error("decompressed output does not match original")
+			}
+		})
+	}
+
+	e := echo.New()
+	req := newMultipartRequest(t, "/compress?mode=zip", "file", []formFile{{name: "in.bin", content: text}})
+	err := CompressFile(e.NewContext(req, httptest.NewRecorder()))
+	if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown mode, got %v", err)
+	}
+}
+
+func TestCompressFileAlreadyCompressed(t *testing.T) {
+	blob, err := huffman.HuffmanCompressBytes(bytes.Repeat([]byte("already compressed. "), 50))
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{name: "Rejected by default", query: "", wantCode: http.StatusBadRequest},
+		{name: "Explicit reject", query: "?compressed=reject", wantCode: http.StatusBadRequest},
+		{name: "Skip returns upload", query: "?compressed=skip", wantCode: http.StatusOK},
+		{name: "Skip with auto mode", query: "?compressed=skip&mode=auto", wantCode: http.StatusOK},
+		{name: "Unknown value", query: "?compressed=again", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := newMultipartRequest(t, "/compress"+tt.query, "file", []formFile{{name: "in.txt.huff", content: blob}})
+			rec := httptest.NewRecorder()
+			err := CompressFile(e.NewContext(req, rec))
+			if tt.wantCode != http.StatusOK {
+				if he, ok := err.(*echo.HTTPError); !ok || he.Code != tt.wantCode {
+					t.Fatalf("expected %d, got %v", tt.wantCode, err)
+				}
+				if cd := rec.Header().Get(echo.HeaderContentDisposition); cd != "" {
+					t.Errorf("expected no Content-Disposition on the error, got %q", cd)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := rec.Header().Get(HeaderHuffminSkipped); got != "already-compressed" {
+				t.Errorf("expected %s %q, got %q", HeaderHuffminSkipped, "already-compressed", got)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), blob) {
+				t.Error("expected the upload back unchanged")
+			}
+		})
+	}
+
+	// Plain uploads are compressed whatever the parameter says, including
+	// ones that merely start with the magic.
+	for _, query := range []string{"", "?compressed=skip"} {
+		for _, text := range []string{"not a blob", "HUFM meeting notes"} {
+			e := echo.New()
+			req := newMultipartRequest(t, "/compress"+query, "file", []formFile{{name: "in.txt", content: []byte(text)}})
+			rec := httptest.NewRecorder()
+			if err := CompressFile(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("%q%s: unexpected error: %v", text, query, err)
+			}
+			if rec.Header().Get(HeaderHuffminSkipped) != "" {
+				t.Errorf("%q%s: expected a plain upload not to be skipped", text, query)
+			}
+			if got, err := huffman.Decompress(rec.Body.Bytes()); err != nil || string(got) != text {
+				t.Errorf("%q%s: expected a blob of the plain upload, got %v", text, query, err)
+			}
+		}
+	}
+}
+
+func TestCompressFileStreamed(t *testing.T) {
+	e := echo.New()
+	e.POST("/compress", CompressFile)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	rng := rand.New(rand.NewSource(353))
+	content := make([]byte, 1<<20)
+	for i := range content {
+		content[i] = "abcdefghij"[rng.Intn(10)]
+	}
+	req := newMultipartRequest(t, srv.URL+"/compress", "file", []formFile{{name: "big.txt", content: content}})
+	req.RequestURI = ""
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.ContentLength <= 0 || len(resp.TransferEncoding) != 0 {
+		t.Errorf("expected a response with Content-Length, got length %d, encoding %v", resp.ContentLength, resp.TransferEncoding)
+	}
+
+	// Read the body a small piece at a time, as a client consuming the
+	// stream would.
+	var body bytes.Buffer
+	buf := make([]byte, 1024)
+	reads := 0
+	for {
+		n, err := resp.Body.Read(buf)
+		body.Write(buf[:n])
+		if n > 0 {
+			reads++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+	}
+	if reads < 2 {
+		t.Errorf("expected the body to arrive in several reads, got %d", reads)
+	}
+	if int64(body.Len()) != resp.ContentLength {
+		t.Errorf("expected %d body bytes, got %d", resp.ContentLength, body.Len())
+	}
+	decompressed, err := huffman.Decompress(body.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected decompress error: %v", err)
+	}
+	if !bytes.Equal(decompressed, content) {
+		t.Error("decompressed output does not match original")
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	e := echo.New()
+	e.POST("/compress", CompressFile)
+	e.POST("/decompress", DecompressFile)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	text := []byte(strings.Repeat("content length ", 200))
+	blob, err := huffman.HuffmanCompressBytes(text)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		content []byte
+	}{
+		{name: "Compress", path: "/compress", content: text},
+		{name: "Compress auto", path: "/compress?mode=auto", content: text},
+		{name: "Compress skipped", path: "/compress?compressed=skip", content: blob},
+		{name: "Decompress", path: "/decompress", content: blob},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newMultipartRequest(t, srv.URL+tt.path, "file", []formFile{{name: "in.txt", content: tt.content}})
+			req.RequestURI = ""
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+			header := resp.Header.Get(echo.HeaderContentLength)
+			if header == "" {
+				t.Fatal("expected a Content-Length header")
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response: %v", err)
+			}
+			if header != strconv.Itoa(len(body)) {
+				t.Errorf("expected Content-Length %d, got %s", len(body), header)
+			}
+		})
+	}
+}
+
+func TestCompressFileDeepTreeWarning(t *testing.T) {
+	// Fibonacci frequencies over 26 symbols give a tree 25 codes deep.
+	var skewed []byte
+	a, b := 1, 1
+	for s := 0; s < 26; s++ {
+		skewed = append(skewed, bytes.Repeat([]byte{'A' + byte(s)}, a)...)
+		a, b = b, a+b
+	}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		wantWarning bool
+	}{
+		{name: "Skewed", content: skewed, wantWarning: true},
+		{name: "Text", content: bytes.Repeat([]byte("hello world! "), 100), wantWarning: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The streamed default mode warns from the tree it encodes with.
+			for _, path := range []string{"/compress?mode=auto", "/compress"} {
+				e := echo.New()
+				req := newMultipartRequest(t, path, "file", []formFile{{name: "in.txt", content: tt.content}})
+				rec := httptest.NewRecorder()
+				if err := CompressFile(e.NewContext(req, rec)); err != nil {
+					t.Fatalf("%s: unexpected error: %v", path, err)
+				}
+				warning := rec.Header().Get(HeaderHuffminWarning)
+				if (warning != "") != tt.wantWarning {
+					t.Errorf("%s: expected warning %v, got %q", path, tt.wantWarning, warning)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/kelbwah/huffmin/backend/internal/huffman"
+	"github.com/labstack/echo/v4"
+	echoware "github.com/labstack/echo/v4/middleware"
+)
+
+// RequestID returns middleware that keeps the client's X-Request-ID header,
+// or generates one if it is missing, and echoes it on the response so log
+// lines can be matched to requests.
+func RequestID() echo.MiddlewareFunc {
+	return echoware.RequestIDWithConfig(echoware.RequestIDConfig{
+		TargetHeader: echo.HeaderXRequestID,
+	})
+}
+
+// logOperation writes one structured log line for a completed compress or
+// decompress operation, tagged with the request ID set by RequestID.
+func logOperation(c echo.Context, op string, mode string, bytesIn, bytesOut int, start time.Time) {
+	slog.InfoContext(c.Request().Context(), op,
+		slog.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID)),
+		slog.String("mode", mode),
+		slog.Int("bytes_in", bytesIn),
+		slog.Int("bytes_out", bytesOut),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// logDeepCode warns that an upload's Huffman tree is deeper than
+// huffman.DeepCodeThreshold, a sign that compressing it gains little.
+func logDeepCode(c echo.Context, depth int) {
+	slog.WarnContext(c.Request().Context(), "deep huffman tree",
+		slog.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID)),
+		slog.Int("max_code_length", depth),
+		slog.Int("threshold", huffman.DeepCodeThreshold),
+	)
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	e := echo.New()
+	e.Use(RequestID())
+	e.POST("/compress", CompressFile)
+
+	content := []byte("hello world! hello world!")
+	tests := []struct {
+		name     string
+		clientID string
+	}{
+		{name: "Generated", clientID: ""},
+		{name: "Propagated", clientID: "client-supplied-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := newMultipartRequest(t, "/compress", "file", []formFile{{name: "id.txt", content: content}})
+			if tt.clientID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.clientID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			if id == "" {
+				t.Fatal("expected a request ID in the response header")
+			}
+			if tt.clientID != "" && id != tt.clientID {
+				t.Errorf("expected request ID %q, got %q", tt.clientID, id)
+			}
+			for _, want := range []string{`"request_id":"` + id + `"`, `"mode":"huffman"`, `"bytes_in":25`} {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("log output missing %s.\nGot: %s", want, logs.String())
+				}
+			}
+		})
+	}
+}
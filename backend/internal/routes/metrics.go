@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Recorder receives one observation per instrumented request. Embedders can
+// implement it to forward metrics to their own backend.
+type Recorder interface {
+	// Observe records a request for op that read bytesIn upload bytes and
+	// wrote bytesOut response bytes. err is the handler's error, if any.
+	Observe(op string, bytesIn, bytesOut int64, err error)
+}
+
+// Instrument returns middleware reporting each request to rec under op. The
+// input size is that of the uploaded "file" field, when present.
+func Instrument(rec Recorder, op string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			var bytesIn int64
+			if file, ferr := c.FormFile("file"); ferr == nil {
+				bytesIn = file.Size
+			}
+			rec.Observe(op, bytesIn, c.Response().Size, err)
+			return err
+		}
+	}
+}
+
+// ratioBuckets are the upper bounds of the compression ratio histogram. The
+// ratio is always compressed size over original size.
+var ratioBuckets = []float64{0.25, 0.5, 0.75, 1, 1.5}
+
+type opMetrics struct {
+	requests   int64
+	errors     int64
+	bytesIn    int64
+	bytesOut   int64
+	buckets    []int64 // per bucket, plus one for +Inf; not cumulative
+	ratioSum   float64
+	ratioCount int64
+}
+
+// Metrics is an in-memory Recorder that serves its counters in the
+// Prometheus text exposition format.
+type Metrics struct {
+	mu  sync.Mutex
+	ops map[string]*opMetrics
+}
+
+var _ Recorder = (*Metrics)(nil)
+
+func NewMetrics() *Metrics {
+	return &Metrics{ops: make(map[string]*opMetrics)}
+}
+
+func (m *Metrics) Observe(op string, bytesIn, bytesOut int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	om, ok := m.ops[op]
+	if !ok {
+		om = &opMetrics{buckets: make([]int64, len(ratioBuckets)+1)}
+		m.ops[op] = om
+	}
+	om.requests++
+	om.bytesIn += bytesIn
+	om.bytesOut += bytesOut
+	if err != nil {
+		om.errors++
+		return
+	}
+	original, compressed := bytesIn, bytesOut
+	if op == "decompress" {
+		original, compressed = bytesOut, bytesIn
+	}
+	if original == 0 {
+		return
+	}
+	ratio := float64(compressed) / float64(original)
+	i, _ := slices.BinarySearch(ratioBuckets, ratio)
+	om.buckets[i]++
+	om.ratioSum += ratio
+	om.ratioCount++
+}
+
+// Serve writes every counter and histogram in the Prometheus text format.
+func (m *Metrics) Serve(c echo.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ops := make([]string, 0, len(m.ops))
+	for op := range m.ops {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+
+	var b strings.Builder
+	counter := func(name, help string, value func(*opMetrics) int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, op := range ops {
+			fmt.Fprintf(&b, "%s{op=%q} %d\n", name, op, value(m.ops[op]))
+		}
+	}
+	counter("huffmin_requests_total", "Requests handled.", func(om *opMetrics) int64 { return om.requests })
+	counter("huffmin_errors_total", "Requests that failed.", func(om *opMetrics) int64 { return om.errors })
+	counter("huffmin_bytes_in_total", "Uploaded bytes read.", func(om *opMetrics) int64 { return om.bytesIn })
+	counter("huffmin_bytes_out_total", "Response bytes written.", func(om *opMetrics) int64 { return om.bytesOut })
+
+	const ratio = "huffmin_compression_ratio"
+	fmt.Fprintf(&b, "# HELP %s Compressed size over original size.\n# TYPE %s histogram\n", ratio, ratio)
+	for _, op := range ops {
+		om := m.ops[op]
+		var cumulative int64
+		for i, le := range ratioBuckets {
+			cumulative += om.buckets[i]
+			fmt.Fprintf(&b, "%s_bucket{op=%q,le=\"%g\"} %d\n", ratio, op, le, cumulative)
+		}
+		cumulative += om.buckets[len(ratioBuckets)]
+		fmt.Fprintf(&b, "%s_bucket{op=%q,le=\"+Inf\"} %d\n", ratio, op, cumulative)
+		fmt.Fprintf(&b, "%s_sum{op=%q} %g\n", ratio, op, om.ratioSum)
+		fmt.Fprintf(&b, "%s_count{op=%q} %d\n", ratio, op, om.ratioCount)
+	}
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	e := echo.New()
+	e.POST("/compress", CompressFile, Instrument(metrics, "compress"))
+	e.POST("/decompress", DecompressFile, Instrument(metrics, "decompress"))
+	e.GET("/metrics", metrics.Serve)
+
+	content := []byte(strings.Repeat("hello world! ", 20))
+	var compressed []byte
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, newMultipartRequest(t, "/compress", "file", []formFile{{name: "metrics.txt", content: content}}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("compress: expected status 200, got %d", rec.Code)
+		}
+		compressed = rec.Body.Bytes()
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newMultipartRequest(t, "/decompress", "file", []formFile{{name: "metrics.txt.huff", content: compressed}}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("decompress: expected status 200, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, newMultipartRequest(t, "/decompress", "file", []formFile{{name: "junk.huff", content: []byte("junk")}}))
+	if rec.Code == http.StatusOK {
+		t.Fatal("decompress: expected junk input to fail")
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("metrics: expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`huffmin_requests_total{op="compress"} 2`,
+		`huffmin_requests_total{op="decompress"} 2`,
+		`huffmin_errors_total{op="compress"} 0`,
+		`huffmin_errors_total{op="decompress"} 1`,
+		`huffmin_bytes_in_total{op="compress"} 520`,
+		`huffmin_compression_ratio_count{op="compress"} 2`,
+		`huffmin_compression_ratio_bucket{op="decompress",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q.\nGot:\n%s", want, body)
+		}
+	}
+}
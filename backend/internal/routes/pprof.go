@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterPprof mounts the net/http/pprof handlers on e under /debug/pprof.
+// Profiles reveal the server's internals and a CPU profile keeps a request
+// busy for its whole duration, so only call it when operators ask for it.
+func RegisterPprof(e *echo.Echo) {
+	g := e.Group("/debug/pprof")
+	g.GET("/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	g.GET("/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	g.GET("/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.POST("/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.GET("/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	// Index serves the listing at /debug/pprof/ and every named profile,
+	// such as heap and goroutine, below it.
+	g.GET("/*", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+}
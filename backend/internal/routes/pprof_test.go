@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	paths := []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/goroutine?debug=1", "/debug/pprof/cmdline"}
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		wantCode int
+	}{
+		{name: "Absent unless registered", enabled: false, wantCode: http.StatusNotFound},
+		{name: "Present when enabled", enabled: true, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			if tt.enabled {
+				RegisterPprof(e)
+			}
+			for _, path := range paths {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != tt.wantCode {
+					t.Errorf("GET %s: expected status %d, got %d", path, tt.wantCode, rec.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"net"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echoware "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitExpiry is how long a client's bucket is kept after its last
+// request before it is dropped.
+const rateLimitExpiry = 3 * time.Minute
+
+// RateLimit returns middleware that allows each client IP perSecond
+// requests per second with bursts of up to burst, rejecting the rest with
+// 429 Too Many Requests. Routes sharing the returned middleware share each
+// client's budget. Clients are told apart by c.RealIP, so the server's
+// IPExtractor must be one such as ClientIP that ignores forwarding headers a
+// client can set itself; echo's default trusts them.
+func RateLimit(perSecond float64, burst int) echo.MiddlewareFunc {
+	return echoware.RateLimiterWithConfig(echoware.RateLimiterConfig{
+		Store: echoware.NewRateLimiterMemoryStoreWithConfig(echoware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(perSecond),
+			Burst:     burst,
+			ExpiresIn: rateLimitExpiry,
+		}),
+	})
+}
+
+// ClientIP returns the IPExtractor that identifies clients for RateLimit.
+// With no trusted proxies it is the address of the connection itself, and
+// X-Forwarded-For and X-Real-IP are ignored. Behind proxies it takes the
+// client from X-Forwarded-For, honouring only hops whose address lies in
+// one of trusted.
+func ClientIP(trusted []*net.IPNet) echo.IPExtractor {
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, n := range trusted {
+		opts = append(opts, echo.TrustIPRange(n))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRateLimit(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = ClientIP(nil)
+	// A negligible refill rate leaves only the burst to spend.
+	limit := RateLimit(0.001, 2)
+	e.POST("/compress", CompressFile, limit)
+	e.POST("/decompress", DecompressFile, limit)
+
+	content := []byte("hello world! hello world!")
+	send := func(ip string) int {
+		req := newMultipartRequest(t, "/compress", "file", []formFile{{name: "limit.txt", content: content}})
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := send("192.0.2.1"); code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200 within the burst, got %d", i, code)
+		}
+	}
+	if code := send("192.0.2.1"); code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 past the burst, got %d", code)
+	}
+
+	// The budget is shared with /decompress, so it is refused before the
+	// handler sees the body.
+	req := newMultipartRequest(t, "/decompress", "file", []formFile{{name: "limit.txt.huff", content: []byte("junk")}})
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("decompress: expected status 429 on a spent budget, got %d", rec.Code)
+	}
+
+	// Other clients have their own budget.
+	if code := send("192.0.2.2"); code != http.StatusOK {
+		t.Errorf("expected status 200 for another client, got %d", code)
+	}
+}
+
+func TestRateLimitSpoofedHeaders(t *testing.T) {
+	_, proxy, err := net.ParseCIDR("198.51.100.0/24")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		trusted []*net.IPNet
+		remote  string
+	}{
+		{name: "Direct", remote: "192.0.2.1"},
+		// Forwarding headers count only on connections from a trusted
+		// proxy, which a client dialling in directly is not.
+		{name: "Untrusted hop", trusted: []*net.IPNet{proxy}, remote: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.IPExtractor = ClientIP(tt.trusted)
+			e.POST("/compress", CompressFile, RateLimit(0.001, 1))
+
+			for i, spoof := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+				req := newMultipartRequest(t, "/compress", "file", []formFile{{name: "limit.txt", content: []byte("hello world!")}})
+				req.RemoteAddr = tt.remote + ":1234"
+				req.Header.Set(echo.HeaderXForwardedFor, spoof)
+				req.Header.Set(echo.HeaderXRealIP, spoof)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				want := http.StatusOK
+				if i > 0 {
+					want = http.StatusTooManyRequests
+				}
+				if rec.Code != want {
+					t.Errorf("request %d spoofing %s: expected status %d, got %d", i, spoof, want, rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestClientIPTrustedProxy(t *testing.T) {
+	_, proxy, err := net.ParseCIDR("198.51.100.0/24")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	extract := ClientIP([]*net.IPNet{proxy})
+	req := httptest.NewRequest(http.MethodPost, "/compress", nil)
+	req.RemoteAddr = "198.51.100.7:1234"
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9, 192.0.2.1")
+	if got := extract(req); got != "192.0.2.1" {
+		t.Errorf("ClientIP behind a trusted proxy = %q, want %q", got, "192.0.2.1")
+	}
+}
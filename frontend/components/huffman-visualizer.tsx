@@ -25,9 +25,17 @@ function buildFrequencyTableBytes(data: Uint8Array): Record<string, number> {
   return freq;
 }
 
+// Container header: "HUFM" magic, format version, mode.
+const CONTAINER_HEADER_SIZE = 6;
+const MODE_HUFFMAN = 1;
+
 function parseCompressedHeader(buffer: ArrayBuffer): Record<string, number> {
   const view = new DataView(buffer);
-  let offset = 0;
+  const magic = String.fromCharCode(...new Uint8Array(buffer, 0, 4));
+  if (magic !== "HUFM" || view.getUint8(5) !== MODE_HUFFMAN) {
+    throw new Error("not a Huffman-coded huffmin blob");
+  }
+  let offset = CONTAINER_HEADER_SIZE;
   const numEntries = view.getUint16(offset, true);
   offset += 2;
   const freq: Record<string, number> = {};